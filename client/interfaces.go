@@ -69,6 +69,7 @@ type InstanceServer interface {
 	// Server functions
 	GetServer() (server *api.Server, ETag string, err error)
 	GetServerResources() (resources *api.Resources, err error)
+	RunServerFirewallTest() (result *api.FirewallTest, err error)
 	UpdateServer(server api.ServerPut, ETag string) (err error)
 	HasExtension(extension string) (exists bool)
 	RequireAuthenticated(authenticated bool)
@@ -225,7 +226,11 @@ type InstanceServer interface {
 	GetNetworks() (networks []api.Network, err error)
 	GetNetwork(name string) (network *api.Network, ETag string, err error)
 	GetNetworkLeases(name string) (leases []api.NetworkLease, err error)
+	GetNetworkExternalSubnets(name string) (externalSubnets []api.NetworkExternalSubnet, err error)
+	GetNetworkACLLog(name string, limit int) (entries []string, err error)
+	EvaluateNetworkACLs(name string, packet api.NetworkACLEvaluatePost) (evaluation *api.NetworkACLEvaluation, err error)
 	GetNetworkState(name string) (state *api.NetworkState, err error)
+	UpdateNetworkState(name string, state api.NetworkStatePost) (err error)
 	CreateNetwork(network api.NetworksPost) (err error)
 	UpdateNetwork(name string, network api.NetworkPut, ETag string) (err error)
 	RenameNetwork(name string, network api.NetworkPost) (err error)
@@ -25,6 +25,10 @@ type ProtocolLXD struct {
 	server      *api.Server
 	chConnected chan struct{}
 
+	// serverExtensions indexes the API extensions of the cached server information. It is built on first use.
+	serverExtensions     map[string]struct{}
+	serverExtensionsLock sync.Mutex
+
 	// eventConns contains event listener connections associated to a project name (or empty for all projects).
 	eventConns map[string]*websocket.Conn
 
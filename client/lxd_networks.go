@@ -76,6 +76,62 @@ func (r *ProtocolLXD) GetNetworkLeases(name string) ([]api.NetworkLease, error)
 	return leases, nil
 }
 
+// GetNetworkExternalSubnets returns the external subnets in use on the server the network is on
+func (r *ProtocolLXD) GetNetworkExternalSubnets(name string) ([]api.NetworkExternalSubnet, error) {
+	if !r.HasExtension("network_external_subnets") {
+		return nil, fmt.Errorf("The server is missing the required \"network_external_subnets\" API extension")
+	}
+
+	externalSubnets := []api.NetworkExternalSubnet{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/external-subnets", url.PathEscape(name)), nil, "", &externalSubnets)
+	if err != nil {
+		return nil, err
+	}
+
+	return externalSubnets, nil
+}
+
+// GetNetworkACLLog returns the most recent ACL log entries of the network (all entries if limit is 0)
+func (r *ProtocolLXD) GetNetworkACLLog(name string, limit int) ([]string, error) {
+	if !r.HasExtension("network_acl_log_bridge") {
+		return nil, fmt.Errorf("The server is missing the required \"network_acl_log_bridge\" API extension")
+	}
+
+	entries := []string{}
+
+	path := fmt.Sprintf("/networks/%s/acl-log", url.PathEscape(name))
+	if limit > 0 {
+		path = fmt.Sprintf("%s?limit=%d", path, limit)
+	}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", path, nil, "", &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// EvaluateNetworkACLs evaluates a packet against the ACLs of the network
+func (r *ProtocolLXD) EvaluateNetworkACLs(name string, packet api.NetworkACLEvaluatePost) (*api.NetworkACLEvaluation, error) {
+	if !r.HasExtension("network_acl_evaluate") {
+		return nil, fmt.Errorf("The server is missing the required \"network_acl_evaluate\" API extension")
+	}
+
+	evaluation := api.NetworkACLEvaluation{}
+
+	// Send the request
+	_, err := r.queryStruct("POST", fmt.Sprintf("/networks/%s/acl-evaluate", url.PathEscape(name)), packet, "", &evaluation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &evaluation, nil
+}
+
 // GetNetworkState returns metrics and information on the running network
 func (r *ProtocolLXD) GetNetworkState(name string) (*api.NetworkState, error) {
 	if !r.HasExtension("network_state") {
@@ -93,6 +149,21 @@ func (r *ProtocolLXD) GetNetworkState(name string) (*api.NetworkState, error) {
 	return &state, nil
 }
 
+// UpdateNetworkState drains or undrains the network on the targeted member
+func (r *ProtocolLXD) UpdateNetworkState(name string, state api.NetworkStatePost) error {
+	if !r.HasExtension("network_state_drain") {
+		return fmt.Errorf("The server is missing the required \"network_state_drain\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("POST", fmt.Sprintf("/networks/%s/state", url.PathEscape(name)), state, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // CreateNetwork defines a new network using the provided Network struct
 func (r *ProtocolLXD) CreateNetwork(network api.NetworksPost) error {
 	if !r.HasExtension("network") {
@@ -38,6 +38,10 @@ func (r *ProtocolLXD) GetServer() (*api.Server, string, error) {
 	// Add the value to the cache
 	r.server = &server
 
+	r.serverExtensionsLock.Lock()
+	r.serverExtensions = nil
+	r.serverExtensionsLock.Unlock()
+
 	return &server, etag, nil
 }
 
@@ -60,13 +64,18 @@ func (r *ProtocolLXD) HasExtension(extension string) bool {
 		return true
 	}
 
-	for _, entry := range r.server.APIExtensions {
-		if entry == extension {
-			return true
+	r.serverExtensionsLock.Lock()
+	defer r.serverExtensionsLock.Unlock()
+
+	if r.serverExtensions == nil {
+		r.serverExtensions = make(map[string]struct{}, len(r.server.APIExtensions))
+		for _, entry := range r.server.APIExtensions {
+			r.serverExtensions[entry] = struct{}{}
 		}
 	}
 
-	return false
+	_, ok := r.serverExtensions[extension]
+	return ok
 }
 
 // IsClustered returns true if the server is part of a LXD cluster.
@@ -91,6 +100,23 @@ func (r *ProtocolLXD) GetServerResources() (*api.Resources, error) {
 	return &resources, nil
 }
 
+// RunServerFirewallTest runs a self-test of the firewall driver of a given LXD server
+func (r *ProtocolLXD) RunServerFirewallTest() (*api.FirewallTest, error) {
+	if !r.HasExtension("firewall_self_test") {
+		return nil, fmt.Errorf("The server is missing the required \"firewall_self_test\" API extension")
+	}
+
+	result := api.FirewallTest{}
+
+	// Send the request
+	_, err := r.queryStruct("POST", "/firewall-test", nil, "", &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 // UseProject returns a client that will use a specific project.
 func (r *ProtocolLXD) UseProject(name string) InstanceServer {
 	return &ProtocolLXD{
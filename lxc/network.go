@@ -61,6 +61,10 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	networkEditCmd := cmdNetworkEdit{global: c.global, network: c}
 	cmd.AddCommand(networkEditCmd.Command())
 
+	// Firewall test
+	networkFirewallTestCmd := cmdNetworkFirewallTest{global: c.global, network: c}
+	cmd.AddCommand(networkFirewallTestCmd.Command())
+
 	// Get
 	networkGetCmd := cmdNetworkGet{global: c.global, network: c}
 	cmd.AddCommand(networkGetCmd.Command())
@@ -690,6 +694,79 @@ func (c *cmdNetworkEdit) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Firewall test
+type cmdNetworkFirewallTest struct {
+	global  *cmdGlobal
+	network *cmdNetwork
+}
+
+func (c *cmdNetworkFirewallTest) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("firewall-test", i18n.G("[<remote>:]"))
+	cmd.Short = i18n.G("Run a self-test of the firewall driver")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Run a self-test of the firewall driver
+
+This installs and immediately removes a representative set of firewall rules
+(SNAT, an address forward and an ACL drop rule) on a throwaway network,
+reporting which operations succeeded.`))
+
+	cmd.Flags().StringVar(&c.network.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkFirewallTest) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	client := resources[0].server
+
+	// Targeting
+	if c.network.flagTarget != "" {
+		if !client.IsClustered() {
+			return fmt.Errorf(i18n.G("To use --target, the destination remote must be a cluster"))
+		}
+
+		client = client.UseTarget(c.network.flagTarget)
+	}
+
+	result, err := client.RunServerFirewallTest()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(i18n.G("Driver: %s")+"\n", result.Driver)
+	for _, op := range result.Operations {
+		if op.Success {
+			fmt.Printf("  %s: %s\n", op.Name, i18n.G("OK"))
+		} else {
+			fmt.Printf("  %s: %s (%s)\n", op.Name, i18n.G("FAILED"), op.Error)
+		}
+	}
+
+	if !result.Success {
+		return fmt.Errorf(i18n.G("Firewall self-test failed"))
+	}
+
+	return nil
+}
+
 // Get
 type cmdNetworkGet struct {
 	global  *cmdGlobal
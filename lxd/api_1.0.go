@@ -14,6 +14,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	instanceDrivers "github.com/lxc/lxd/lxd/instance/drivers"
 	"github.com/lxc/lxd/lxd/lifecycle"
+	"github.com/lxc/lxd/lxd/network"
 	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/rbac"
@@ -62,6 +63,7 @@ var api10 = []APIEndpoint{
 	instanceSnapshotsCmd,
 	instanceStateCmd,
 	eventsCmd,
+	firewallTestCmd,
 	imageAliasCmd,
 	imageAliasesCmd,
 	imageCmd,
@@ -70,6 +72,9 @@ var api10 = []APIEndpoint{
 	imagesCmd,
 	imageSecretCmd,
 	networkCmd,
+	networkACLEvaluateCmd,
+	networkACLLogCmd,
+	networkExternalSubnetsCmd,
 	networkLeasesCmd,
 	networksCmd,
 	networkStateCmd,
@@ -363,6 +368,26 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 
 	env.StorageSupportedDrivers = supportedStorageDrivers
 
+	for _, driverName := range network.SupportedDrivers() {
+		netType, err := network.LoadByType(driverName)
+		if err != nil {
+			return response.InternalError(err)
+		}
+
+		info := netType.Info()
+		env.NetworkSupportedDrivers = append(env.NetworkSupportedDrivers, api.ServerNetworkDriverInfo{
+			Name:            driverName,
+			Projects:        info.Projects,
+			AddressForwards: info.AddressForwards,
+			Peering:         info.Peering,
+			ACLs:            info.ACLs,
+			BGP:             info.BGP,
+			Fan:             info.Fan,
+			Tunnels:         info.Tunnels,
+			ConfigKeys:      info.ConfigKeys,
+		})
+	}
+
 	fullSrv := api.Server{ServerUntrusted: srv}
 	fullSrv.Environment = env
 
@@ -751,6 +776,8 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		case "core.bgp_routerid":
 			bgpChanged = true
 		case "core.dns_address":
+			fallthrough
+		case "core.dns_upstreams":
 			dnsChanged = true
 		}
 	}
@@ -861,6 +888,8 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 	if dnsChanged {
 		address := nodeConfig.DNSAddress()
 
+		s.DNS.SetUpstreams(nodeConfig.DNSUpstreams())
+
 		err := s.DNS.Reconfigure(address)
 		if err != nil {
 			return err
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/lxc/lxd/lxd/dnsmasq"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
 )
@@ -30,8 +31,12 @@ profile "{{ .name }}" flags=(attach_disconnected,mediate_deleted) {
   network inet6 raw,
 
   # Network-specific paths
+  {{ .varPath }}/networks/{{ .networkName }}/dnsmasq.drained r,
   {{ .varPath }}/networks/{{ .networkName }}/dnsmasq.hosts/{,*} r,
   {{ .varPath }}/networks/{{ .networkName }}/dnsmasq.leases rw,
+{{- if .leaseFile }}
+  {{ .leaseFile }} rw,
+{{- end }}
   {{ .varPath }}/networks/{{ .networkName }}/dnsmasq.raw r,
 
   # Additional system files
@@ -73,11 +78,18 @@ func dnsmasqProfile(state *state.State, n network) (string, error) {
 		rootPath = "/var/lib/snapd/hostfs"
 	}
 
+	// Only allow access to a relocated leases file inside the directory reserved for them.
+	leaseFile := ""
+	if dnsmasq.IsLeasesPath(n.Config()["dns.leasefile"]) {
+		leaseFile = n.Config()["dns.leasefile"]
+	}
+
 	// Render the profile.
 	var sb *strings.Builder = &strings.Builder{}
 	err := dnsmasqProfileTpl.Execute(sb, map[string]interface{}{
 		"name":        DnsmasqProfileName(n),
 		"networkName": n.Name(),
+		"leaseFile":   leaseFile,
 		"varPath":     shared.VarPath(""),
 		"rootPath":    rootPath,
 		"snap":        shared.InSnap(),
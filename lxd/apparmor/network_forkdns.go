@@ -6,6 +6,7 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/lxc/lxd/lxd/dnsmasq"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
@@ -24,6 +25,9 @@ profile "{{ .name }}" flags=(attach_disconnected,mediate_deleted) {
 
   # Network-specific paths
   {{ .varPath }}/networks/{{ .networkName }}/dnsmasq.leases r,
+{{- if .leaseFile }}
+  {{ .leaseFile }} r,
+{{- end }}
   {{ .varPath }}/networks/{{ .networkName }}/forkdns.servers/servers.conf r,
 
   # Needed for lxd fork commands
@@ -61,11 +65,18 @@ func forkdnsProfile(state *state.State, n network) (string, error) {
 		rootPath = "/var/lib/snapd/hostfs"
 	}
 
+	// Only allow access to a relocated leases file inside the directory reserved for them.
+	leaseFile := ""
+	if dnsmasq.IsLeasesPath(n.Config()["dns.leasefile"]) {
+		leaseFile = n.Config()["dns.leasefile"]
+	}
+
 	// Render the profile.
 	var sb *strings.Builder = &strings.Builder{}
 	err := forkdnsProfileTpl.Execute(sb, map[string]interface{}{
 		"name":        ForkdnsProfileName(n),
 		"networkName": n.Name(),
+		"leaseFile":   leaseFile,
 		"varPath":     shared.VarPath(""),
 		"rootPath":    rootPath,
 		"snap":        shared.InSnap(),
@@ -206,6 +206,12 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 		return errors.Wrap(err, "Error writing tarball")
 	}
 
+	// Store the digest of the tarball so that it can be sent along with the backup when exported.
+	err = backup.WriteDigest(target)
+	if err != nil {
+		return errors.Wrap(err, "Error computing tarball digest")
+	}
+
 	revert.Success()
 	s.Events.SendLifecycle(sourceInst.Project(), lifecycle.InstanceBackupCreated.Event(args.Name, b.Instance(), nil))
 
@@ -468,6 +474,12 @@ func volumeBackupCreate(s *state.State, args db.StoragePoolVolumeBackup, project
 		return errors.Wrap(err, "Error writing tarball")
 	}
 
+	// Store the digest of the tarball so that it can be sent along with the backup when exported.
+	err = backup.WriteDigest(target)
+	if err != nil {
+		return errors.Wrap(err, "Error computing tarball digest")
+	}
+
 	revert.Success()
 	return nil
 }
@@ -1,6 +1,12 @@
 package backup
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/lxc/lxd/lxd/state"
@@ -9,6 +15,9 @@ import (
 // WorkingDirPrefix is used when temporary working directories are needed.
 const WorkingDirPrefix = "lxd_backup"
 
+// digestSuffix is appended to the path of a backup tarball to get the path of the file holding its digest.
+const digestSuffix = ".sha256"
+
 // CommonBackup represents a common backup.
 type CommonBackup struct {
 	state                *state.State
@@ -40,3 +49,52 @@ func (b *CommonBackup) SetCompressionAlgorithm(compression string) {
 func (b *CommonBackup) OptimizedStorage() bool {
 	return b.optimizedStorage
 }
+
+// WriteDigest computes the SHA-256 digest of the backup tarball at backupPath and stores it alongside the tarball,
+// so that it doesn't need to be computed each time the backup is exported.
+func WriteDigest(backupPath string) error {
+	f, err := os.Open(backupPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(backupPath+digestSuffix, []byte(hex.EncodeToString(hash.Sum(nil))), 0600)
+}
+
+// ReadDigest returns the hex encoded SHA-256 digest stored for the backup tarball at backupPath, or an empty
+// string if there is none (such as for backups created by older versions).
+func ReadDigest(backupPath string) string {
+	content, err := ioutil.ReadFile(backupPath + digestSuffix)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(content))
+}
+
+// renameDigest moves the digest stored for the backup tarball at oldBackupPath (if any) along with the tarball.
+func renameDigest(oldBackupPath string, newBackupPath string) error {
+	err := os.Rename(oldBackupPath+digestSuffix, newBackupPath+digestSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// deleteDigest removes the digest stored for the backup tarball at backupPath (if any).
+func deleteDigest(backupPath string) error {
+	err := os.Remove(backupPath + digestSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -82,6 +82,11 @@ func (b *InstanceBackup) Rename(newName string) error {
 		return err
 	}
 
+	err = renameDigest(oldBackupPath, newBackupPath)
+	if err != nil {
+		return err
+	}
+
 	// Check if we can remove the old parent directory.
 	empty, _ := shared.PathIsEmpty(oldParentBackupsPath)
 	if empty {
@@ -115,6 +120,11 @@ func (b *InstanceBackup) Delete() error {
 		}
 	}
 
+	err := deleteDigest(backupPath)
+	if err != nil {
+		return err
+	}
+
 	// Check if we can remove the instance directory.
 	backupsPath := shared.VarPath("backups", "instances", project.Instance(b.instance.Project(), b.instance.Name()))
 	empty, _ := shared.PathIsEmpty(backupsPath)
@@ -126,7 +136,7 @@ func (b *InstanceBackup) Delete() error {
 	}
 
 	// Remove the database record.
-	err := b.state.Cluster.DeleteInstanceBackup(b.name)
+	err = b.state.Cluster.DeleteInstanceBackup(b.name)
 	if err != nil {
 		return err
 	}
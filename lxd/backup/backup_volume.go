@@ -81,6 +81,12 @@ func (b *VolumeBackup) Rename(newName string) error {
 	}
 	revert.Add(func() { os.Rename(newBackupPath, oldBackupPath) })
 
+	err = renameDigest(oldBackupPath, newBackupPath)
+	if err != nil {
+		return err
+	}
+	revert.Add(func() { renameDigest(newBackupPath, oldBackupPath) })
+
 	// Check if we can remove the old parent directory.
 	empty, _ := shared.PathIsEmpty(oldParentBackupsPath)
 	if empty {
@@ -111,6 +117,11 @@ func (b *VolumeBackup) Delete() error {
 		}
 	}
 
+	err := deleteDigest(backupPath)
+	if err != nil {
+		return err
+	}
+
 	// Check if we can remove the volume directory.
 	backupsPath := shared.VarPath("backups", "custom", b.poolName, project.StorageVolume(b.projectName, b.volumeName))
 	empty, _ := shared.PathIsEmpty(backupsPath)
@@ -122,7 +133,7 @@ func (b *VolumeBackup) Delete() error {
 	}
 
 	// Remove the database record.
-	err := b.state.Cluster.DeleteStoragePoolVolumeBackup(b.name)
+	err = b.state.Cluster.DeleteStoragePoolVolumeBackup(b.name)
 	if err != nil {
 		return err
 	}
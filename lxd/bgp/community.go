@@ -0,0 +1,65 @@
+package bgp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CommunityGracefulShutdown is the GRACEFUL_SHUTDOWN well-known community (RFC 8326).
+const CommunityGracefulShutdown uint32 = 0xFFFF0000
+
+// wellKnownCommunities maps the names accepted in place of "ASN:VALUE" to their well-known community.
+var wellKnownCommunities = map[string]uint32{
+	"graceful-shutdown":   CommunityGracefulShutdown, // RFC 8326
+	"blackhole":           0xFFFF029A,                // RFC 7999
+	"no-export":           0xFFFFFF01,                // RFC 1997
+	"no-advertise":        0xFFFFFF02,                // RFC 1997
+	"no-export-subconfed": 0xFFFFFF03,                // RFC 1997
+	"no-peer":             0xFFFFFF04,                // RFC 3765
+}
+
+// ParseCommunities parses a comma separated list of standard BGP communities in the "ASN:VALUE" format or
+// given by their well-known name (such as "no-export").
+func ParseCommunities(value string) ([]uint32, error) {
+	communities := []uint32{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+
+		community, ok := wellKnownCommunities[entry]
+		if ok {
+			communities = append(communities, community)
+			continue
+		}
+
+		fields := strings.Split(entry, ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Invalid community %q (must be in the ASN:VALUE format)", entry)
+		}
+
+		asn, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ASN in community %q (must be between 0 and 65535)", entry)
+		}
+
+		val, err := strconv.ParseUint(fields[1], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value in community %q (must be between 0 and 65535)", entry)
+		}
+
+		communities = append(communities, uint32(asn<<16|val))
+	}
+
+	return communities, nil
+}
+
+// hasCommunity returns whether the community is in the list of communities.
+func hasCommunity(communities []uint32, community uint32) bool {
+	for _, entry := range communities {
+		if entry == community {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package bgp_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/bgp"
+)
+
+func TestParseCommunities(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		communities []uint32
+		err         bool
+	}{
+		{name: "single", value: "65000:100", communities: []uint32{65000<<16 | 100}},
+		{name: "bounds", value: "0:0,65535:65535", communities: []uint32{0, 0xFFFFFFFF}},
+		{name: "spaces", value: " 1:2 , 3:4 ", communities: []uint32{1<<16 | 2, 3<<16 | 4}},
+		{name: "well-known", value: "no-export,no-advertise,no-export-subconfed,no-peer", communities: []uint32{0xFFFFFF01, 0xFFFFFF02, 0xFFFFFF03, 0xFFFFFF04}},
+		{name: "graceful-shutdown", value: "graceful-shutdown", communities: []uint32{bgp.CommunityGracefulShutdown}},
+		{name: "mixed", value: "blackhole,65000:1", communities: []uint32{0xFFFF029A, 65000<<16 | 1}},
+		{name: "asn out of range", value: "65536:1", err: true},
+		{name: "value out of range", value: "1:65536", err: true},
+		{name: "negative", value: "-1:1", err: true},
+		{name: "empty", value: "", err: true},
+		{name: "empty entry", value: "1:2,", err: true},
+		{name: "missing value", value: "1", err: true},
+		{name: "missing asn", value: ":1", err: true},
+		{name: "too many fields", value: "1:2:3", err: true},
+		{name: "not numeric", value: "a:b", err: true},
+		{name: "unknown name", value: "no-such-community", err: true},
+		{name: "case sensitive name", value: "NO-EXPORT", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			communities, err := bgp.ParseCommunities(test.value)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.communities, communities)
+		})
+	}
+}
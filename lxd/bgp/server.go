@@ -34,6 +34,13 @@ type path struct {
 	owner   string
 	prefix  net.IPNet
 	nexthop net.IP
+	attrs   PrefixAttributes
+}
+
+// PrefixAttributes represents the optional BGP path attributes advertised with a prefix.
+type PrefixAttributes struct {
+	Communities []uint32 // Standard communities (ASN in the high 16 bits and value in the low 16 bits).
+	LocalPref   uint32   // Local preference (not advertised if 0).
 }
 
 type peer struct {
@@ -69,7 +76,7 @@ func (s *Server) setup() {
 		s.paths = map[string]path{}
 
 		for _, path := range paths {
-			s.addPrefix(path.prefix, path.nexthop, path.owner)
+			s.addPrefix(path.prefix, path.nexthop, path.owner, path.attrs)
 		}
 	}
 }
@@ -234,10 +241,19 @@ func (s *Server) AddPrefix(subnet net.IPNet, nexthop net.IP, owner string) error
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.addPrefix(subnet, nexthop, owner)
+	return s.addPrefix(subnet, nexthop, owner, PrefixAttributes{})
 }
 
-func (s *Server) addPrefix(subnet net.IPNet, nexthop net.IP, owner string) error {
+// AddPrefixWithAttributes adds a new prefix to the BGP server, advertised with the provided path attributes.
+func (s *Server) AddPrefixWithAttributes(subnet net.IPNet, nexthop net.IP, owner string, attrs PrefixAttributes) error {
+	// Locking.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addPrefix(subnet, nexthop, owner, attrs)
+}
+
+func (s *Server) addPrefix(subnet net.IPNet, nexthop net.IP, owner string, attrs PrefixAttributes) error {
 	// Prepare the prefix.
 	prefixLen, _ := subnet.Mask.Size()
 	prefix := subnet.IP.String()
@@ -251,6 +267,24 @@ func (s *Server) addPrefix(subnet net.IPNet, nexthop net.IP, owner string) error
 		Origin: 0,
 	})
 
+	// Prepare the optional attributes.
+	aOptional := []*anypb.Any{}
+	if len(attrs.Communities) > 0 {
+		aCommunities, _ := anypb.New(&bgpAPI.CommunitiesAttribute{
+			Communities: attrs.Communities,
+		})
+
+		aOptional = append(aOptional, aCommunities)
+	}
+
+	if attrs.LocalPref > 0 {
+		aLocalPref, _ := anypb.New(&bgpAPI.LocalPrefAttribute{
+			LocalPref: attrs.LocalPref,
+		})
+
+		aOptional = append(aOptional, aLocalPref)
+	}
+
 	// Add the prefix to the server.
 	var pathUUID string
 	if s.bgp != nil {
@@ -264,7 +298,7 @@ func (s *Server) addPrefix(subnet net.IPNet, nexthop net.IP, owner string) error
 				Path: &bgpAPI.Path{
 					Family: &bgpAPI.Family{Afi: bgpAPI.Family_AFI_IP, Safi: bgpAPI.Family_SAFI_UNICAST},
 					Nlri:   nlri,
-					Pattrs: []*anypb.Any{aOrigin, aNextHop},
+					Pattrs: append([]*anypb.Any{aOrigin, aNextHop}, aOptional...),
 				},
 			})
 			if err != nil {
@@ -289,7 +323,7 @@ func (s *Server) addPrefix(subnet net.IPNet, nexthop net.IP, owner string) error
 				Path: &bgpAPI.Path{
 					Family: family,
 					Nlri:   nlri,
-					Pattrs: []*anypb.Any{aOrigin, v6Attrs},
+					Pattrs: append([]*anypb.Any{aOrigin, v6Attrs}, aOptional...),
 				},
 			})
 			if err != nil {
@@ -308,11 +342,49 @@ func (s *Server) addPrefix(subnet net.IPNet, nexthop net.IP, owner string) error
 		prefix:  subnet,
 		nexthop: nexthop,
 		owner:   owner,
+		attrs:   attrs,
 	}
 
 	return nil
 }
 
+// GracefulShutdownByOwner re-advertises all prefixes for the provided owner with the GRACEFUL_SHUTDOWN community
+// added, so that peers can move traffic away before the prefixes are withdrawn.
+// Returns the number of prefixes that were re-advertised to a running BGP server.
+func (s *Server) GracefulShutdownByOwner(owner string) (int, error) {
+	// Locking.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Collect the paths first, as re-advertising them changes their UUIDs.
+	pathUUIDs := []string{}
+	for pathUUID, path := range s.paths {
+		if path.owner == owner && !hasCommunity(path.attrs.Communities, CommunityGracefulShutdown) {
+			pathUUIDs = append(pathUUIDs, pathUUID)
+		}
+	}
+
+	for _, pathUUID := range pathUUIDs {
+		path := s.paths[pathUUID]
+
+		attrs := path.attrs
+		attrs.Communities = append(append([]uint32{}, path.attrs.Communities...), CommunityGracefulShutdown)
+
+		// Advertising the same prefix again replaces the existing path in the BGP server.
+		delete(s.paths, pathUUID)
+		err := s.addPrefix(path.prefix, path.nexthop, path.owner, attrs)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if s.bgp == nil {
+		return 0, nil
+	}
+
+	return len(pathUUIDs), nil
+}
+
 // RemovePrefixByOwner removes all prefixes for the provided owner.
 func (s *Server) RemovePrefixByOwner(owner string) error {
 	// Locking.
@@ -1228,6 +1228,7 @@ func (d *Daemon) init() error {
 	candidExpiry := int64(0)
 
 	dnsAddress := ""
+	dnsUpstreams := []string{}
 
 	rbacAPIURL := ""
 	rbacAPIKey := ""
@@ -1252,6 +1253,7 @@ func (d *Daemon) init() error {
 		bgpAddress = config.BGPAddress()
 		bgpRouterID = config.BGPRouterID()
 		dnsAddress = config.DNSAddress()
+		dnsUpstreams = config.DNSUpstreams()
 		return nil
 	})
 	if err != nil {
@@ -1331,6 +1333,8 @@ func (d *Daemon) init() error {
 
 		return resp, nil
 	})
+	d.dns.SetUpstreams(dnsUpstreams)
+
 	if dnsAddress != "" {
 		err := d.dns.Start(dnsAddress)
 		if err != nil {
@@ -1509,6 +1513,12 @@ func (d *Daemon) Ready() error {
 
 		// Remove resolved warnings (daily)
 		d.tasks.Add(pruneResolvedWarningsTask(d))
+
+		// Apply scheduled network ACL rules (minutely)
+		d.tasks.Add(networkACLScheduleTask(d))
+
+		// Re-apply network address forwards on target reachability changes (minutely)
+		d.tasks.Add(networkForwardTargetsTask(d))
 	}
 
 	// Start all background tasks
@@ -1599,6 +1609,16 @@ func (d *Daemon) Stop(ctx context.Context, sig os.Signal) error {
 		}
 	}
 
+	// Announce the BGP graceful shutdown of the networks before anything is stopped, so that peers can reroute
+	// traffic in the meantime. The prefixes are then held until bgpHoldUntil.
+	var bgpHoldUntil time.Time
+	if d.cluster != nil {
+		bgpHoldUntil, err = networkBGPGracefulShutdown(s)
+		if err != nil {
+			logger.Warn("Failed announcing BGP graceful shutdown", log.Ctx{"err": err})
+		}
+	}
+
 	// Handle shutdown (unix.SIGPWR) and reload (unix.SIGTERM) signals.
 	if sig == unix.SIGPWR || sig == unix.SIGTERM {
 		if d.cluster != nil {
@@ -1634,7 +1654,7 @@ func (d *Daemon) Stop(ctx context.Context, sig os.Signal) error {
 			instancesShutdown(s, instances)
 
 			logger.Info("Stopping networks")
-			networkShutdown(s)
+			networkShutdown(s, bgpHoldUntil)
 
 			// Unmount storage pools after instances stopped.
 			logger.Info("Stopping storage pools")
@@ -1659,6 +1679,9 @@ func (d *Daemon) Stop(ctx context.Context, sig os.Signal) error {
 		}
 	}
 
+	// Keep the BGP prefixes advertised until the end of the graceful shutdown hold, they're withdrawn on exit.
+	time.Sleep(time.Until(bgpHoldUntil))
+
 	if d.gateway != nil {
 		d.gateway.Kill()
 	}
@@ -43,12 +43,39 @@ func (c *Cluster) GetNetworkZones(project string) ([]string, error) {
 	return zoneNames, nil
 }
 
+// GetAllNetworkZones returns the names of the Network zones of all projects.
+func (c *Cluster) GetAllNetworkZones() ([]string, error) {
+	q := `SELECT name FROM networks_zones ORDER BY id`
+
+	var zoneNames []string
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		return tx.QueryScan(q, func(scan func(dest ...interface{}) error) error {
+			var zoneName string
+
+			err := scan(&zoneName)
+			if err != nil {
+				return err
+			}
+
+			zoneNames = append(zoneNames, zoneName)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return zoneNames, nil
+}
+
 // GetNetworkZoneKeys returns a map of key names to keys.
 func (c *Cluster) GetNetworkZoneKeys() (map[string]string, error) {
 	q := `SELECT networks_zones.name, networks_zones_config.key, networks_zones_config.value
 		FROM networks_zones
 		JOIN networks_zones_config ON networks_zones_config.network_zone_id=networks_zones.id
-		WHERE networks_zones_config.key LIKE 'peers.%.key';
+		WHERE networks_zones_config.key LIKE 'peers.%.key' OR networks_zones_config.key LIKE 'peers.%.keys';
 	`
 
 	secrets := map[string]string{}
@@ -70,7 +97,22 @@ func (c *Cluster) GetNetworkZoneKeys() (map[string]string, error) {
 			}
 
 			// Format as a valid TSIG secret (encode domain name, key name and make valid FQDN).
-			secrets[fmt.Sprintf("%s_%s.", name, fields[1])] = secret
+			if fields[2] == "key" {
+				secrets[fmt.Sprintf("%s_%s.", name, fields[1])] = secret
+
+				return nil
+			}
+
+			// Multiple keys are listed as comma separated "NAME:SECRET" entries.
+			for _, entry := range strings.Split(secret, ",") {
+				keyFields := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+				if len(keyFields) != 2 {
+					// Skip invalid values.
+					continue
+				}
+
+				secrets[fmt.Sprintf("%s_%s_%s.", name, fields[1], keyFields[0])] = keyFields[1]
+			}
 
 			return nil
 		})
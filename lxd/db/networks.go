@@ -938,5 +938,7 @@ var NodeSpecificNetworkConfig = []string{
 	"bgp.ipv4.nexthop",
 	"bgp.ipv6.nexthop",
 	"bridge.external_interfaces",
+	"dns.forkdns.exclude",
+	"fan.underlay_interface",
 	"parent",
 }
@@ -55,6 +55,12 @@ const (
 	WarningInstanceAutostartFailure
 	//WarningInstanceTypeNotOperational represents the lack of support for an instance driver
 	WarningInstanceTypeNotOperational
+	// WarningNetworkDHCPLeaseLimitExceeded represents a MAC address holding more DHCP leases than allowed
+	WarningNetworkDHCPLeaseLimitExceeded
+	// WarningNetworkDNSMasqStartupFailure represents the dnsmasq process of a network failing to start
+	WarningNetworkDNSMasqStartupFailure
+	// WarningNetworkVXLANDefaultPort represents a bridge VXLAN tunnel using the kernel default port
+	WarningNetworkVXLANDefaultPort
 )
 
 // WarningTypeNames associates a warning code to its name.
@@ -83,6 +89,9 @@ var WarningTypeNames = map[WarningType]string{
 	WarningOfflineClusterMember:                   "Offline cluster member",
 	WarningInstanceAutostartFailure:               "Failed to autostart instance",
 	WarningInstanceTypeNotOperational:             "Instance type not operational",
+	WarningNetworkDHCPLeaseLimitExceeded:          "DHCP lease limit per MAC address exceeded",
+	WarningNetworkDNSMasqStartupFailure:           "Failed to start dnsmasq",
+	WarningNetworkVXLANDefaultPort:                "VXLAN tunnel using the kernel default port",
 }
 
 // WarningTypes associates a warning type to its type code.
@@ -145,6 +154,12 @@ func (t WarningType) Severity() WarningSeverity {
 		return WarningSeverityLow
 	case WarningInstanceTypeNotOperational:
 		return WarningSeverityLow
+	case WarningNetworkDHCPLeaseLimitExceeded:
+		return WarningSeverityModerate
+	case WarningNetworkDNSMasqStartupFailure:
+		return WarningSeverityHigh
+	case WarningNetworkVXLANDefaultPort:
+		return WarningSeverityLow
 	}
 
 	return WarningSeverityLow
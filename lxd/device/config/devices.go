@@ -1,7 +1,6 @@
 package config
 
 import (
-	"fmt"
 	"sort"
 	"strings"
 )
@@ -28,7 +27,7 @@ func (device Device) Validate(rules map[string]func(value string) error) error {
 		checkedFields[k] = struct{}{} //Mark field as checked.
 		err := validator(device[k])
 		if err != nil {
-			return fmt.Errorf("Invalid value for device option %q: %v", k, err)
+			return ValidationError{Key: k, Reason: err.Error()}
 		}
 	}
 
@@ -57,7 +56,7 @@ func (device Device) Validate(rules map[string]func(value string) error) error {
 			continue
 		}
 
-		return fmt.Errorf("Invalid device option %q", k)
+		return ValidationError{Key: k, Reason: "Unknown option"}
 	}
 
 	return nil
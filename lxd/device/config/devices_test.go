@@ -1,8 +1,13 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"reflect"
 	"testing"
+
+	"github.com/lxc/lxd/shared/api"
 )
 
 func TestSortableDevices(t *testing.T) {
@@ -37,3 +42,43 @@ func TestSortableDevices(t *testing.T) {
 		t.Error("devices reverse sorted incorrectly")
 	}
 }
+
+func TestDeviceValidate_ValidationError(t *testing.T) {
+	rules := map[string]func(value string) error{
+		"mtu": func(value string) error {
+			if value == "bad" {
+				return fmt.Errorf("Invalid MTU")
+			}
+
+			return nil
+		},
+	}
+
+	tests := map[string]Device{
+		"mtu": {"type": "nic", "mtu": "bad"},
+		"foo": {"type": "nic", "foo": "bar"},
+	}
+
+	for key, device := range tests {
+		var validationErr ValidationError
+		err := device.Validate(rules)
+		if !errors.As(err, &validationErr) {
+			t.Errorf("expected ValidationError for %q, got %v", key, err)
+			continue
+		}
+
+		if validationErr.Key != key {
+			t.Errorf("expected key %q, got %q", key, validationErr.Key)
+		}
+
+		status, found := api.StatusErrorMatch(err)
+		if !found || status != http.StatusBadRequest {
+			t.Errorf("expected bad request status for %q, got %d", key, status)
+		}
+	}
+
+	err := Device{"type": "nic", "mtu": "1500"}.Validate(rules)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// ValidationError is returned when a device config key fails validation.
+// It carries the offending key so that callers can report which option needs fixing.
+type ValidationError struct {
+	Key    string
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("Invalid device option %q: %s", e.Key, e.Reason)
+}
+
+// Unwrap returns a bad request StatusError, as validation errors are caused by the request's config.
+// This lets response.SmartError report them as such.
+func (e ValidationError) Unwrap() error {
+	return api.StatusErrorf(http.StatusBadRequest, e.Error())
+}
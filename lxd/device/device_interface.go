@@ -80,3 +80,10 @@ type device interface {
 type NICState interface {
 	State() (*api.InstanceStateNetwork, error)
 }
+
+// VFAvailability provides the ability to check how many SR-IOV virtual functions are free on a device's parent.
+type VFAvailability interface {
+	// AvailableVFs returns the number of virtual functions on the parent that could be used by the device.
+	// It doesn't modify the state of the parent or its virtual functions.
+	AvailableVFs() (int, error)
+}
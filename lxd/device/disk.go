@@ -1968,12 +1968,20 @@ func (d *disk) generateVMConfigDrive() (string, error) {
 		return "", err
 	}
 
-	// Include a network-config file if the user configured it.
+	// Include a network-config file if the user configured it, otherwise configure any routed NICs as these
+	// can't use DHCP.
 	networkConfig, ok := instanceConfig["cloud-init.network-config"]
 	if !ok {
 		networkConfig = instanceConfig["user.network-config"]
 	}
 
+	if networkConfig == "" && d.inst.Type() == instancetype.VM {
+		networkConfig, err = nicRoutedNetworkConfig(d.inst)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	if networkConfig != "" {
 		err = ioutil.WriteFile(filepath.Join(scratchDir, "network-config"), []byte(networkConfig), 0400)
 		if err != nil {
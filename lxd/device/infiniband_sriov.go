@@ -62,15 +62,8 @@ func (d *infinibandSRIOV) validateEnvironment() error {
 	return nil
 }
 
-// Start is run when the device is added to a running instance or instance is starting up.
-func (d *infinibandSRIOV) Start() (*deviceConfig.RunConfig, error) {
-	err := d.validateEnvironment()
-	if err != nil {
-		return nil, err
-	}
-
-	saveData := make(map[string]string)
-
+// freeVFs returns the virtual functions of the parent device that are not in use by other devices.
+func (d *infinibandSRIOV) freeVFs() (map[string]*api.ResourcesNetworkCardPort, error) {
 	// Load network interface info.
 	nics, err := resources.GetNetwork()
 	if err != nil {
@@ -94,6 +87,33 @@ func (d *infinibandSRIOV) Start() (*deviceConfig.RunConfig, error) {
 		delete(ibDevs, k)
 	}
 
+	return ibDevs, nil
+}
+
+// AvailableVFs returns the number of free virtual functions on the parent device.
+func (d *infinibandSRIOV) AvailableVFs() (int, error) {
+	ibDevs, err := d.freeVFs()
+	if err != nil {
+		return -1, err
+	}
+
+	return len(ibDevs), nil
+}
+
+// Start is run when the device is added to a running instance or instance is starting up.
+func (d *infinibandSRIOV) Start() (*deviceConfig.RunConfig, error) {
+	err := d.validateEnvironment()
+	if err != nil {
+		return nil, err
+	}
+
+	saveData := make(map[string]string)
+
+	ibDevs, err := d.freeVFs()
+	if err != nil {
+		return nil, err
+	}
+
 	if len(ibDevs) < 1 {
 		return nil, fmt.Errorf("All virtual functions on parent device are already in use")
 	}
@@ -36,6 +36,7 @@ func nicValidationRules(requiredFields []string, optionalFields []string, instCo
 		"ipv4.routes":                          validate.Optional(validate.IsNetworkV4List),
 		"ipv6.routes":                          validate.Optional(validate.IsNetworkV6List),
 		"boot.priority":                        validate.Optional(validate.IsUint32),
+		"dns.name":                             validate.Optional(shared.ValidHostname),
 		"ipv4.gateway":                         networkValidGateway,
 		"ipv6.gateway":                         networkValidGateway,
 		"ipv4.host_address":                    validate.Optional(validate.IsNetworkAddressV4),
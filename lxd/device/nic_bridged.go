@@ -89,6 +89,7 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		"maas.subnet.ipv6",
 		"boot.priority",
 		"vlan",
+		"dns.name",
 	}
 
 	// checkWithManagedNetwork validates the device's settings against the managed network.
@@ -118,23 +119,22 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 				return fmt.Errorf("Device IP address %q not within network %q subnet", d.config["ipv4.address"], n.Name())
 			}
 
+			// Skip the remaining checks (but not those of the other IP family) if the network has no address.
 			parentAddress := netConfig["ipv4.address"]
-			if shared.StringInSlice(parentAddress, []string{"", "none"}) {
-				return nil
-			}
-
-			ip, _, err := net.ParseCIDR(parentAddress)
-			if err != nil {
-				return errors.Wrapf(err, "Invalid network ipv4.address")
-			}
+			if !shared.StringInSlice(parentAddress, []string{"", "none"}) {
+				ip, _, err := net.ParseCIDR(parentAddress)
+				if err != nil {
+					return errors.Wrapf(err, "Invalid network ipv4.address")
+				}
 
-			if d.config["ipv4.address"] == "none" && !shared.IsTrue(d.config["security.ipv4_filtering"]) {
-				return fmt.Errorf("Cannot have ipv4.address as none unless using security.ipv4_filtering")
-			}
+				if d.config["ipv4.address"] == "none" && !shared.IsTrue(d.config["security.ipv4_filtering"]) {
+					return fmt.Errorf("Cannot have ipv4.address as none unless using security.ipv4_filtering")
+				}
 
-			// IP should not be the same as the parent managed network address.
-			if ip.Equal(net.ParseIP(d.config["ipv4.address"])) {
-				return fmt.Errorf("IP address %q is assigned to parent managed network device %q", d.config["ipv4.address"], d.config["parent"])
+				// IP should not be the same as the parent managed network address.
+				if ip.Equal(net.ParseIP(d.config["ipv4.address"])) {
+					return fmt.Errorf("IP address %q is assigned to parent managed network device %q", d.config["ipv4.address"], d.config["parent"])
+				}
 			}
 		}
 
@@ -153,23 +153,22 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 				return fmt.Errorf("Device IP address %q not within network %q subnet", d.config["ipv6.address"], n.Name())
 			}
 
+			// Skip the remaining checks (but not those of the other IP family) if the network has no address.
 			parentAddress := netConfig["ipv6.address"]
-			if shared.StringInSlice(parentAddress, []string{"", "none"}) {
-				return nil
-			}
-
-			ip, _, err := net.ParseCIDR(parentAddress)
-			if err != nil {
-				return errors.Wrapf(err, "Invalid network ipv6.address")
-			}
+			if !shared.StringInSlice(parentAddress, []string{"", "none"}) {
+				ip, _, err := net.ParseCIDR(parentAddress)
+				if err != nil {
+					return errors.Wrapf(err, "Invalid network ipv6.address")
+				}
 
-			if d.config["ipv6.address"] == "none" && !shared.IsTrue(d.config["security.ipv6_filtering"]) {
-				return fmt.Errorf("Cannot have ipv6.address as none unless using security.ipv6_filtering")
-			}
+				if d.config["ipv6.address"] == "none" && !shared.IsTrue(d.config["security.ipv6_filtering"]) {
+					return fmt.Errorf("Cannot have ipv6.address as none unless using security.ipv6_filtering")
+				}
 
-			// IP should not be the same as the parent managed network address.
-			if ip.Equal(net.ParseIP(d.config["ipv6.address"])) {
-				return fmt.Errorf("IP address %q is assigned to parent managed network device %q", d.config["ipv6.address"], d.config["parent"])
+				// IP should not be the same as the parent managed network address.
+				if ip.Equal(net.ParseIP(d.config["ipv6.address"])) {
+					return fmt.Errorf("IP address %q is assigned to parent managed network device %q", d.config["ipv6.address"], d.config["parent"])
+				}
 			}
 		}
 
@@ -475,6 +474,32 @@ func (d *nicBridged) Add() error {
 	return nil
 }
 
+// warnStaticIPsInDynamicRanges logs a warning for each static IP that is part of the network's configured dynamic
+// DHCP ranges, as it may already be leased out to another host.
+func (d *nicBridged) warnStaticIPsInDynamicRanges() {
+	for _, keyPrefix := range []string{"ipv4", "ipv6"} {
+		ip := net.ParseIP(d.config[fmt.Sprintf("%s.address", keyPrefix)])
+		if ip == nil || d.network.Config()[fmt.Sprintf("%s.dhcp.ranges", keyPrefix)] == "" {
+			continue
+		}
+
+		var dhcpRanges []shared.IPRange
+		if keyPrefix == "ipv4" {
+			ip = ip.To4()
+			dhcpRanges = d.network.DHCPv4Ranges()
+		} else {
+			dhcpRanges = d.network.DHCPv6Ranges()
+		}
+
+		for _, dhcpRange := range dhcpRanges {
+			if dhcpRange.ContainsIP(ip) {
+				d.logger.Warn("Static IP address is within the network's dynamic DHCP range and may collide with an existing lease", log.Ctx{"address": ip.String(), "network": d.network.Name(), "range": dhcpRange.String()})
+				break
+			}
+		}
+	}
+}
+
 // Start is run when the device is added to a running instance or instance is starting up.
 func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 	err := d.validateEnvironment()
@@ -482,6 +507,10 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	if d.network != nil {
+		d.warnStaticIPsInDynamicRanges()
+	}
+
 	revert := revert.New()
 	defer revert.Fail()
 
@@ -557,8 +586,8 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
-	// Attempt to enable port isolation.
-	if shared.IsTrue(d.config["security.port_isolation"]) {
+	// Attempt to enable port isolation, either requested by the NIC or by the parent network for all its ports.
+	if shared.IsTrue(d.config["security.port_isolation"]) || (d.network != nil && shared.IsTrue(d.network.Config()["bridge.port_isolation"])) {
 		link := &ip.Link{Name: saveData["host_name"]}
 		err = link.BridgeLinkSetIsolated(true)
 		if err != nil {
@@ -876,7 +905,7 @@ func (d *nicBridged) rebuildDnsmasqEntry() error {
 		}
 	}
 
-	err = dnsmasq.UpdateStaticEntry(d.config["parent"], d.inst.Project(), d.inst.Name(), netConfig, d.config["hwaddr"], ipv4Address, ipv6Address)
+	err = dnsmasq.UpdateStaticEntry(d.config["parent"], d.inst.Project(), d.inst.Name(), d.config["dns.name"], netConfig, d.config["hwaddr"], ipv4Address, ipv6Address)
 	if err != nil {
 		return err
 	}
@@ -1017,6 +1046,7 @@ func (d *nicBridged) setFilters() (err error) {
 		opts := &dhcpalloc.Options{
 			ProjectName: d.inst.Project(),
 			HostName:    d.inst.Name(),
+			DNSName:     d.config["dns.name"],
 			HostMAC:     mac,
 			Network:     d.network,
 		}
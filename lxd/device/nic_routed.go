@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
 
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance"
@@ -75,12 +76,14 @@ func (d *nicRouted) validateConfig(instConf instance.ConfigReader) error {
 		"ipv4.host_table",
 		"ipv6.host_table",
 		"gvrp",
+		"security.rp_filter",
 	}
 
 	rules := nicValidationRules(requiredFields, optionalFields, instConf)
 	rules["ipv4.address"] = validate.Optional(validate.IsNetworkAddressV4List)
 	rules["ipv6.address"] = validate.Optional(validate.IsNetworkAddressV6List)
 	rules["gvrp"] = validate.Optional(validate.IsBool)
+	rules["security.rp_filter"] = validate.Optional(validate.IsBool)
 
 	err = d.config.Validate(rules)
 	if err != nil {
@@ -95,7 +98,7 @@ func (d *nicRouted) validateConfig(instConf instance.ConfigReader) error {
 			for _, addr := range strings.Split(d.config[key], ",") {
 				addr = strings.TrimSpace(addr)
 				if _, dupe := ips[addr]; dupe {
-					return fmt.Errorf("Duplicate address %q in %q", addr, key)
+					return deviceConfig.ValidationError{Key: key, Reason: fmt.Sprintf("Duplicate address %q", addr)}
 				}
 
 				ips[addr] = struct{}{}
@@ -106,7 +109,7 @@ func (d *nicRouted) validateConfig(instConf instance.ConfigReader) error {
 	// Ensure that address is set if routes is set
 	for _, keyPrefix := range []string{"ipv4", "ipv6"} {
 		if d.config[fmt.Sprintf("%s.routes", keyPrefix)] != "" && d.config[fmt.Sprintf("%s.address", keyPrefix)] == "" {
-			return fmt.Errorf("%s.routes requires %s.address to be set", keyPrefix, keyPrefix)
+			return deviceConfig.ValidationError{Key: fmt.Sprintf("%s.routes", keyPrefix), Reason: fmt.Sprintf("Requires %s.address to be set", keyPrefix)}
 		}
 	}
 
@@ -129,32 +132,7 @@ func (d *nicRouted) validateEnvironment() error {
 	}
 
 	if d.config["parent"] == "" && d.config["vlan"] != "" {
-		return fmt.Errorf("The vlan setting can only be used when combined with a parent interface")
-	}
-
-	// Check necessary "all" sysctls are configured for use with l2proxy parent for routed mode.
-	if d.config["parent"] != "" && d.config["ipv6.address"] != "" {
-		// net.ipv6.conf.all.forwarding=1 is required to enable general packet forwarding for IPv6.
-		ipv6FwdPath := fmt.Sprintf("net/ipv6/conf/%s/forwarding", "all")
-		sysctlVal, err := util.SysctlGet(ipv6FwdPath)
-		if err != nil {
-			return fmt.Errorf("Error reading net sysctl %s: %v", ipv6FwdPath, err)
-		}
-		if sysctlVal != "1\n" {
-			return fmt.Errorf("Routed mode requires sysctl net.ipv6.conf.%s.forwarding=1", "all")
-		}
-
-		// net.ipv6.conf.all.proxy_ndp=1 is needed otherwise unicast neighbour solicitations are rejected.
-		// This causes periodic latency spikes every 15-20s as the neighbour has to resort to using
-		// multicast NDP resolution and expires the previous neighbour entry.
-		ipv6ProxyNdpPath := fmt.Sprintf("net/ipv6/conf/%s/proxy_ndp", "all")
-		sysctlVal, err = util.SysctlGet(ipv6ProxyNdpPath)
-		if err != nil {
-			return fmt.Errorf("Error reading net sysctl %s: %v", ipv6ProxyNdpPath, err)
-		}
-		if sysctlVal != "1\n" {
-			return fmt.Errorf("Routed mode requires sysctl net.ipv6.conf.%s.proxy_ndp=1", "all")
-		}
+		return deviceConfig.ValidationError{Key: "vlan", Reason: "Can only be used when combined with a parent interface"}
 	}
 
 	// Generate effective parent name, including the VLAN part if option used.
@@ -162,44 +140,93 @@ func (d *nicRouted) validateEnvironment() error {
 
 	// If the effective parent doesn't exist and the vlan option is specified, it means we are going to create
 	// the VLAN parent at start, and we will configure the needed sysctls so don't need to check them yet.
-	if d.config["vlan"] != "" && network.InterfaceExists(effectiveParentName) {
-		return nil
+	checkParent := effectiveParentName != ""
+	if d.config["vlan"] != "" && !network.InterfaceExists(effectiveParentName) {
+		checkParent = false
 	}
 
-	// Check necessary sysctls are configured for use with l2proxy parent for routed mode.
-	if effectiveParentName != "" && d.config["ipv4.address"] != "" {
-		ipv4FwdPath := fmt.Sprintf("net/ipv4/conf/%s/forwarding", effectiveParentName)
-		sysctlVal, err := util.SysctlGet(ipv4FwdPath)
+	// The checks for each IP family are independent, so that a single protocol NIC doesn't require the
+	// host to be configured for routing of the other protocol.
+	if d.config["ipv4.address"] != "" && checkParent {
+		err := d.checkParentSysctlsIPv4(effectiveParentName)
 		if err != nil {
-			return fmt.Errorf("Error reading net sysctl %s: %v", ipv4FwdPath, err)
-		}
-		if sysctlVal != "1\n" {
-			// Replace . in parent name with / for sysctl formatting.
-			return fmt.Errorf("Routed mode requires sysctl net.ipv4.conf.%s.forwarding=1", strings.Replace(effectiveParentName, ".", "/", -1))
+			return err
 		}
 	}
 
-	// Check necessary devic specific sysctls are configured for use with l2proxy parent for routed mode.
-	if effectiveParentName != "" && d.config["ipv6.address"] != "" {
-		ipv6FwdPath := fmt.Sprintf("net/ipv6/conf/%s/forwarding", effectiveParentName)
-		sysctlVal, err := util.SysctlGet(ipv6FwdPath)
+	if d.config["ipv6.address"] != "" && d.config["parent"] != "" {
+		err := d.checkParentSysctlsIPv6(effectiveParentName, checkParent)
 		if err != nil {
-			return fmt.Errorf("Error reading net sysctl %s: %v", ipv6FwdPath, err)
-		}
-		if sysctlVal != "1\n" {
-			// Replace . in parent name with / for sysctl formatting.
-			return fmt.Errorf("Routed mode requires sysctl net.ipv6.conf.%s.forwarding=1", strings.Replace(effectiveParentName, ".", "/", -1))
+			return err
 		}
+	}
 
-		ipv6ProxyNdpPath := fmt.Sprintf("net/ipv6/conf/%s/proxy_ndp", effectiveParentName)
-		sysctlVal, err = util.SysctlGet(ipv6ProxyNdpPath)
-		if err != nil {
-			return fmt.Errorf("Error reading net sysctl %s: %v", ipv6ProxyNdpPath, err)
-		}
-		if sysctlVal != "1\n" {
-			// Replace . in parent name with / for sysctl formatting.
-			return fmt.Errorf("Routed mode requires sysctl net.ipv6.conf.%s.proxy_ndp=1", strings.Replace(effectiveParentName, ".", "/", -1))
-		}
+	return nil
+}
+
+// checkParentSysctlsIPv4 checks the IPv4 sysctls needed for use with an l2proxy parent in routed mode.
+func (d *nicRouted) checkParentSysctlsIPv4(parentName string) error {
+	ipv4FwdPath := fmt.Sprintf("net/ipv4/conf/%s/forwarding", parentName)
+	sysctlVal, err := util.SysctlGet(ipv4FwdPath)
+	if err != nil {
+		return fmt.Errorf("Error reading net sysctl %s: %v", ipv4FwdPath, err)
+	}
+	if sysctlVal != "1\n" {
+		// Replace . in parent name with / for sysctl formatting.
+		return fmt.Errorf("Routed mode requires sysctl net.ipv4.conf.%s.forwarding=1", strings.Replace(parentName, ".", "/", -1))
+	}
+
+	return nil
+}
+
+// checkParentSysctlsIPv6 checks the IPv6 sysctls needed for use with an l2proxy parent in routed mode.
+// The "all" sysctls are always checked, the parent specific ones only if checkParent is true.
+func (d *nicRouted) checkParentSysctlsIPv6(parentName string, checkParent bool) error {
+	// net.ipv6.conf.all.forwarding=1 is required to enable general packet forwarding for IPv6.
+	ipv6FwdPath := fmt.Sprintf("net/ipv6/conf/%s/forwarding", "all")
+	sysctlVal, err := util.SysctlGet(ipv6FwdPath)
+	if err != nil {
+		return fmt.Errorf("Error reading net sysctl %s: %v", ipv6FwdPath, err)
+	}
+	if sysctlVal != "1\n" {
+		return fmt.Errorf("Routed mode requires sysctl net.ipv6.conf.%s.forwarding=1", "all")
+	}
+
+	// net.ipv6.conf.all.proxy_ndp=1 is needed otherwise unicast neighbour solicitations are rejected.
+	// This causes periodic latency spikes every 15-20s as the neighbour has to resort to using
+	// multicast NDP resolution and expires the previous neighbour entry.
+	ipv6ProxyNdpPath := fmt.Sprintf("net/ipv6/conf/%s/proxy_ndp", "all")
+	sysctlVal, err = util.SysctlGet(ipv6ProxyNdpPath)
+	if err != nil {
+		return fmt.Errorf("Error reading net sysctl %s: %v", ipv6ProxyNdpPath, err)
+	}
+	if sysctlVal != "1\n" {
+		return fmt.Errorf("Routed mode requires sysctl net.ipv6.conf.%s.proxy_ndp=1", "all")
+	}
+
+	if !checkParent {
+		return nil
+	}
+
+	// Check necessary device specific sysctls are configured for use with l2proxy parent for routed mode.
+	ipv6FwdPath = fmt.Sprintf("net/ipv6/conf/%s/forwarding", parentName)
+	sysctlVal, err = util.SysctlGet(ipv6FwdPath)
+	if err != nil {
+		return fmt.Errorf("Error reading net sysctl %s: %v", ipv6FwdPath, err)
+	}
+	if sysctlVal != "1\n" {
+		// Replace . in parent name with / for sysctl formatting.
+		return fmt.Errorf("Routed mode requires sysctl net.ipv6.conf.%s.forwarding=1", strings.Replace(parentName, ".", "/", -1))
+	}
+
+	ipv6ProxyNdpPath = fmt.Sprintf("net/ipv6/conf/%s/proxy_ndp", parentName)
+	sysctlVal, err = util.SysctlGet(ipv6ProxyNdpPath)
+	if err != nil {
+		return fmt.Errorf("Error reading net sysctl %s: %v", ipv6ProxyNdpPath, err)
+	}
+	if sysctlVal != "1\n" {
+		// Replace . in parent name with / for sysctl formatting.
+		return fmt.Errorf("Routed mode requires sysctl net.ipv6.conf.%s.proxy_ndp=1", strings.Replace(parentName, ".", "/", -1))
 	}
 
 	return nil
@@ -246,18 +273,25 @@ func (d *nicRouted) Start() (*deviceConfig.RunConfig, error) {
 
 	var peerName string
 
+	// When no MTU is specified, inherit it from the effective parent (which may be the VLAN interface
+	// created above) rather than the base device, so that its MTU is used end-to-end.
+	linkConfig := d.config.Clone()
+	if parentName != "" {
+		linkConfig["parent"] = parentName
+	}
+
 	// Create veth pair and configure the peer end with custom hwaddr and mtu if supplied.
 	if d.inst.Type() == instancetype.Container {
 		if saveData["host_name"] == "" {
 			saveData["host_name"] = network.RandomDevName("veth")
 		}
-		peerName, err = networkCreateVethPair(saveData["host_name"], d.config)
+		peerName, err = networkCreateVethPair(saveData["host_name"], linkConfig)
 	} else if d.inst.Type() == instancetype.VM {
 		if saveData["host_name"] == "" {
 			saveData["host_name"] = network.RandomDevName("tap")
 		}
 		peerName = saveData["host_name"] // VMs use the host_name to link to the TAP FD.
-		err = networkCreateTap(saveData["host_name"], d.config)
+		err = networkCreateTap(saveData["host_name"], linkConfig)
 	}
 	if err != nil {
 		return nil, err
@@ -280,16 +314,18 @@ func (d *nicRouted) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
-	// Prevent source address spoofing by requiring a return path.
-	err = util.SysctlSet(fmt.Sprintf("net/ipv4/conf/%s/rp_filter", saveData["host_name"]), "1")
-	if err != nil && !os.IsNotExist(err) {
-		return nil, err
-	}
+	if d.rpFilterEnabled() {
+		// Prevent source address spoofing by requiring a return path.
+		err = util.SysctlSet(fmt.Sprintf("net/ipv4/conf/%s/rp_filter", saveData["host_name"]), "1")
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
 
-	// Apply firewall rules for reverse path filtering of IPv4 and IPv6.
-	err = d.state.Firewall.InstanceSetupRPFilter(d.inst.Project(), d.inst.Name(), d.name, saveData["host_name"])
-	if err != nil {
-		return nil, errors.Wrapf(err, "Error setting up reverse path filter")
+		// Apply firewall rules for reverse path filtering of IPv4 and IPv6.
+		err = d.state.Firewall.InstanceSetupRPFilter(d.inst.Project(), d.inst.Name(), d.name, saveData["host_name"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error setting up reverse path filter")
+		}
 	}
 
 	// Perform host-side address configuration.
@@ -354,6 +390,21 @@ func (d *nicRouted) Start() (*deviceConfig.RunConfig, error) {
 				if err != nil {
 					return nil, fmt.Errorf("Failed adding host route %q to table %q: %w", r.Route, r.Table, err)
 				}
+
+				// Direct traffic from the instance's address to the custom routing table so that return
+				// traffic leaves via the same uplink on hosts with multiple default routes.
+				rule := ip.Rule{
+					Family: ipFamilyArg,
+					From:   fmt.Sprintf("%s/%d", addrStr, subnetSize),
+					Table:  r.Table,
+				}
+
+				err = rule.Add()
+				if err != nil {
+					return nil, fmt.Errorf("Failed adding policy rule from %q to table %q: %w", rule.From, rule.Table, err)
+				}
+
+				revert.Add(func() { rule.Delete() })
 			}
 
 			// If there is a parent interface, add neighbour proxy entry.
@@ -439,6 +490,19 @@ func (d *nicRouted) Start() (*deviceConfig.RunConfig, error) {
 			{Key: "link", Value: peerName},
 			{Key: "hwaddr", Value: d.config["hwaddr"]},
 		}...)
+
+		// Pass the addressing through as well, VMs configure it themselves (see nicRoutedNetworkConfig).
+		for _, keyPrefix := range []string{"ipv4", "ipv6"} {
+			ipAddresses := util.SplitNTrimSpace(d.config[fmt.Sprintf("%s.address", keyPrefix)], ",", -1, true)
+
+			if len(ipAddresses) > 0 && nicHasAutoGateway(d.config[fmt.Sprintf("%s.gateway", keyPrefix)]) {
+				nic = append(nic, deviceConfig.RunConfigItem{Key: fmt.Sprintf("%s.gateway", keyPrefix), Value: d.ipHostAddress(keyPrefix)})
+			}
+
+			for _, addrStr := range ipAddresses {
+				nic = append(nic, deviceConfig.RunConfigItem{Key: fmt.Sprintf("%s.address", keyPrefix), Value: addrStr})
+			}
+		}
 	}
 
 	runConf := deviceConfig.RunConfig{
@@ -554,6 +618,31 @@ func (d *nicRouted) postStop() error {
 		}
 	}
 
+	// Delete policy routing rules (unlike routes, these aren't removed with the host-side interface).
+	for _, keyPrefix := range []string{"ipv4", "ipv6"} {
+		hostTable := d.config[fmt.Sprintf("%s.host_table", keyPrefix)]
+		if hostTable == "" {
+			continue
+		}
+
+		subnetSize := 32
+		ipFamilyArg := ip.FamilyV4
+		if keyPrefix == "ipv6" {
+			subnetSize = 128
+			ipFamilyArg = ip.FamilyV6
+		}
+
+		for _, addr := range util.SplitNTrimSpace(d.config[fmt.Sprintf("%s.address", keyPrefix)], ",", -1, true) {
+			rule := &ip.Rule{
+				Family: ipFamilyArg,
+				From:   fmt.Sprintf("%s/%d", addr, subnetSize),
+				Table:  hostTable,
+			}
+
+			rule.Delete()
+		}
+	}
+
 	// This will delete the parent interface if we created it for VLAN parent.
 	if shared.IsTrue(v["last_state.created"]) {
 		err := networkRemoveInterfaceIfNeeded(d.state, parentName, d.inst, d.config["parent"], d.config["vlan"])
@@ -563,9 +652,11 @@ func (d *nicRouted) postStop() error {
 	}
 
 	// Remove reverse path filters.
-	err := d.state.Firewall.InstanceClearRPFilter(d.inst.Project(), d.inst.Name(), d.name)
-	if err != nil {
-		errs = append(errs, err)
+	if d.rpFilterEnabled() {
+		err := d.state.Firewall.InstanceClearRPFilter(d.inst.Project(), d.inst.Name(), d.name)
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
 
 	if len(errs) > 0 {
@@ -575,6 +666,11 @@ func (d *nicRouted) postStop() error {
 	return nil
 }
 
+// rpFilterEnabled returns whether reverse path filtering should be applied to the host side interface.
+func (d *nicRouted) rpFilterEnabled() bool {
+	return d.config["security.rp_filter"] == "" || shared.IsTrue(d.config["security.rp_filter"])
+}
+
 func (d *nicRouted) ipHostAddress(ipFamily string) string {
 	key := fmt.Sprintf("%s.host_address", ipFamily)
 	if d.config[key] != "" {
@@ -598,10 +694,87 @@ func (d *nicRouted) isUniqueWithGatewayAutoMode(instConf instance.ConfigReader)
 			}
 
 			if nicConfig[k] == "auto" || nicConfig[k] == "" {
-				return fmt.Errorf("Existing NIC %q already uses %q in auto mode", nicName, k)
+				return deviceConfig.ValidationError{Key: k, Reason: fmt.Sprintf("Existing NIC %q already uses auto mode", nicName)}
 			}
 		}
 	}
 
 	return nil
 }
+
+// nicRoutedNetworkConfig returns a cloud-init network configuration (version 2) setting up the addresses and
+// default gateways of the instance's routed NICs, or an empty string if there are none to configure.
+// Containers get this configuration through liblxc, VMs need it to be applied by the guest's network tooling.
+func nicRoutedNetworkConfig(inst instance.Instance) (string, error) {
+	type route struct {
+		To     string `yaml:"to"`
+		Via    string `yaml:"via"`
+		OnLink bool   `yaml:"on-link"`
+	}
+
+	type ethernet struct {
+		Match     map[string]string `yaml:"match"`
+		Addresses []string          `yaml:"addresses,omitempty"`
+		Routes    []route           `yaml:"routes,omitempty"`
+	}
+
+	instConfig := inst.ExpandedConfig()
+	ethernets := map[string]ethernet{}
+
+	for devName, devConfig := range inst.ExpandedDevices() {
+		if devConfig["type"] != "nic" || devConfig["nictype"] != "routed" {
+			continue
+		}
+
+		hwaddr := devConfig["hwaddr"]
+		if hwaddr == "" {
+			hwaddr = instConfig[fmt.Sprintf("volatile.%s.hwaddr", devName)]
+		}
+
+		if hwaddr == "" {
+			continue
+		}
+
+		eth := ethernet{Match: map[string]string{"macaddress": hwaddr}}
+
+		for _, keyPrefix := range []string{"ipv4", "ipv6"} {
+			subnetSize := 32
+			defaultRoute := "0.0.0.0/0"
+			if keyPrefix == "ipv6" {
+				subnetSize = 128
+				defaultRoute = "::/0"
+			}
+
+			ipAddresses := util.SplitNTrimSpace(devConfig[fmt.Sprintf("%s.address", keyPrefix)], ",", -1, true)
+			for _, addrStr := range ipAddresses {
+				eth.Addresses = append(eth.Addresses, fmt.Sprintf("%s/%d", addrStr, subnetSize))
+			}
+
+			if len(ipAddresses) > 0 && nicHasAutoGateway(devConfig[fmt.Sprintf("%s.gateway", keyPrefix)]) {
+				gateway := devConfig[fmt.Sprintf("%s.host_address", keyPrefix)]
+				if gateway == "" {
+					gateway = nicRoutedIPGateway[keyPrefix]
+				}
+
+				eth.Routes = append(eth.Routes, route{To: defaultRoute, Via: gateway, OnLink: true})
+			}
+		}
+
+		if len(eth.Addresses) == 0 {
+			continue
+		}
+
+		ethernets[devName] = eth
+	}
+
+	if len(ethernets) == 0 {
+		return "", nil
+	}
+
+	out, err := yaml.Marshal(map[string]interface{}{"version": 2, "ethernets": ethernets})
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
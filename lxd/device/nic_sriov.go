@@ -121,6 +121,15 @@ func (d *nicSRIOV) validateEnvironment() error {
 	return nil
 }
 
+// AvailableVFs returns the number of free virtual functions on the parent device, including those that are not
+// enabled yet but would be created on demand by Start.
+func (d *nicSRIOV) AvailableVFs() (int, error) {
+	network.SRIOVVirtualFunctionMutex.Lock()
+	defer network.SRIOVVirtualFunctionMutex.Unlock()
+
+	return network.SRIOVCountFreeVirtualFunctions(d.state, d.config["parent"])
+}
+
 // Start is run when the device is added to a running instance or instance is starting up.
 func (d *nicSRIOV) Start() (*deviceConfig.RunConfig, error) {
 	err := d.validateEnvironment()
@@ -0,0 +1,58 @@
+package dns
+
+import (
+	"crypto/sha256"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// zoneCache holds the parsed records of the zones, keyed by zone name.
+type zoneCache struct {
+	zones map[string]cachedZone
+
+	mu sync.Mutex
+}
+
+type cachedZone struct {
+	hash    [sha256.Size]byte
+	records []dns.RR
+}
+
+func newZoneCache() *zoneCache {
+	return &zoneCache{zones: map[string]cachedZone{}}
+}
+
+// records returns the parsed records of the zone content, re-using the previously parsed records if the content
+// of the zone hasn't changed. The returned records must not be modified.
+// The content is compared rather than the SOA serial, as the serial is time based and so the content can change
+// without the serial changing when the zone is generated more than once per second.
+func (c *zoneCache) records(zone *Zone) []dns.RR {
+	hash := sha256.Sum256([]byte(zone.Content))
+
+	c.mu.Lock()
+	cached, found := c.zones[zone.Info.Name]
+	c.mu.Unlock()
+
+	if found && cached.hash == hash {
+		return cached.records
+	}
+
+	records := []dns.RR{}
+	zoneRR := dns.NewZoneParser(strings.NewReader(zone.Content), "", "")
+	for {
+		rr, ok := zoneRR.Next()
+		if !ok {
+			break
+		}
+
+		records = append(records, rr)
+	}
+
+	c.mu.Lock()
+	c.zones[zone.Info.Name] = cachedZone{hash: hash, records: records}
+	c.mu.Unlock()
+
+	return records
+}
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/miekg/dns"
 
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
 
@@ -16,11 +19,15 @@ type dnsHandler struct {
 }
 
 func (d dnsHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	for _, q := range r.Question {
+		d.server.counters.query(dns.TypeToString[q.Qtype])
+	}
+
 	// Check if we're ready to serve queries.
 	if d.server.zoneRetriever == nil {
 		m := new(dns.Msg)
 		m.SetRcode(r, dns.RcodeServerFailure)
-		w.WriteMsg(m)
+		d.writeMsg(w, m)
 		return
 	}
 
@@ -28,15 +35,17 @@ func (d dnsHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	if len(r.Question) != 1 {
 		m := new(dns.Msg)
 		m.SetRcode(r, dns.RcodeServerFailure)
-		w.WriteMsg(m)
+		d.writeMsg(w, m)
 		return
 	}
 
-	// Check that it's AXFR.
-	if r.Question[0].Qtype != dns.TypeAXFR {
+	// Check that it's AXFR or a supported record query (other queries may still be forwarded upstream).
+	isAXFR := r.Question[0].Qtype == dns.TypeAXFR
+	upstreams := d.server.getUpstreams()
+	if !isAXFR && !isSupportedQuery(r.Question[0].Qtype) && len(upstreams) == 0 {
 		m := new(dns.Msg)
 		m.SetRcode(r, dns.RcodeNotImplemented)
-		w.WriteMsg(m)
+		d.writeMsg(w, m)
 		return
 	}
 
@@ -46,7 +55,7 @@ func (d dnsHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	if err != nil {
 		m := new(dns.Msg)
 		m.SetRcode(r, dns.RcodeServerFailure)
-		w.WriteMsg(m)
+		d.writeMsg(w, m)
 		return
 	}
 
@@ -55,53 +64,239 @@ func (d dnsHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	m.SetReply(r)
 	m.Authoritative = true
 
-	// Load the zone.
-	zone, err := d.server.zoneRetriever(name)
+	// Load the zone (AXFR requests name the zone directly, queries name a record within it).
+	var zone *Zone
+	if isAXFR {
+		zone, err = d.server.zoneRetriever(name)
+	} else {
+		zone, err = d.zoneForName(name)
+	}
+
+	if err != nil && !isAXFR && len(upstreams) > 0 && errors.Is(err, db.ErrNoSuchObject) {
+		// Not one of our zones, forward to the upstream resolvers if the client is trusted (so that we don't
+		// act as an open resolver).
+		if !d.isRecursionAllowed(ip, r.IsTsig(), w.TsigStatus() == nil) {
+			d.server.counters.denial(denyReasonRecursion)
+
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeRefused)
+			d.writeMsg(w, m)
+			return
+		}
+
+		d.forward(w, r, upstreams)
+		return
+	}
+
 	if err != nil {
-		// On failure, return NXDOMAIN.
+		// Return NXDOMAIN only if the zone doesn't exist, otherwise SERVFAIL so that secondaries retry
+		// rather than caching a negative answer for what may be a temporary failure.
 		m := new(dns.Msg)
-		m.SetRcode(r, dns.RcodeNameError)
-		w.WriteMsg(m)
+		if errors.Is(err, db.ErrNoSuchObject) {
+			m.SetRcode(r, dns.RcodeNameError)
+		} else {
+			m.SetRcode(r, dns.RcodeServerFailure)
+		}
+
+		d.writeMsg(w, m)
 		return
 	}
 
 	// Check access.
-	if !d.isAllowed(zone.Info, ip, r.IsTsig(), w.TsigStatus() == nil) {
+	allowed, peerName, denyReason := d.isAllowed(zone.Info, ip, r.IsTsig(), w.TsigStatus() == nil)
+	if !allowed {
+		d.server.counters.denial(denyReason)
+
 		// On auth failure, return NXDOMAIN to avoid information leaks.
 		m := new(dns.Msg)
 		m.SetRcode(r, dns.RcodeNameError)
-		w.WriteMsg(m)
+		d.writeMsg(w, m)
 		return
 	}
 
-	zoneRR := dns.NewZoneParser(strings.NewReader(zone.Content), "", "")
-	for {
-		rr, ok := zoneRR.Next()
-		if !ok {
-			break
-		}
+	// Forwarding unsupported query types is only done outside of our zones.
+	if !isAXFR && !isSupportedQuery(r.Question[0].Qtype) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNotImplemented)
+		d.writeMsg(w, m)
+		return
+	}
+
+	if isAXFR {
+		m.Answer = append(m.Answer, d.server.zoneCache.records(zone)...)
 
-		m.Answer = append(m.Answer, rr)
+		d.server.counters.transfer(zone.Info.Name, peerName)
+	} else {
+		answerQuery(m, d.server.zoneCache.records(zone), r.Question[0])
 	}
 
+	// Handle EDNS0 and fit the reply in the client's UDP buffer (before signing so the TSIG isn't dropped).
+	setEDNS0(w, r, m)
+
 	tsig := r.IsTsig()
 	if tsig != nil && w.TsigStatus() == nil {
 		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
 	}
 
-	w.WriteMsg(m)
+	d.writeMsg(w, m)
 
 	return
 }
 
-func (d *dnsHandler) isAllowed(zone api.NetworkZone, ip string, tsig *dns.TSIG, tsigStatus bool) bool {
-	type peer struct {
-		address string
-		key     string
+// forward sends the query to the upstream resolvers in turn and relays the first answer received, or SERVFAIL if
+// none of them answered.
+func (d dnsHandler) forward(w dns.ResponseWriter, r *dns.Msg, upstreams []string) {
+	client := &dns.Client{Net: "udp", Timeout: 2 * time.Second}
+	_, isTCP := w.RemoteAddr().(*net.TCPAddr)
+	if isTCP {
+		client.Net = "tcp"
 	}
 
-	// Build a list of peers.
-	peers := map[string]*peer{}
+	for _, upstream := range upstreams {
+		resp, _, err := client.Exchange(r, upstream)
+		if err != nil {
+			continue
+		}
+
+		resp.Id = r.Id
+		d.writeMsg(w, resp)
+		return
+	}
+
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeServerFailure)
+	d.writeMsg(w, m)
+}
+
+// isRecursionAllowed returns whether the client is allowed to have queries forwarded to the upstream resolvers,
+// which is only the case for the peers of the zones served.
+func (d dnsHandler) isRecursionAllowed(ip string, tsig *dns.TSIG, tsigStatus bool) bool {
+	if d.server.db == nil {
+		return false
+	}
+
+	zoneNames, err := d.server.db.GetAllNetworkZones()
+	if err != nil {
+		return false
+	}
+
+	for _, zoneName := range zoneNames {
+		_, _, zoneInfo, err := d.server.db.GetNetworkZone(zoneName)
+		if err != nil {
+			continue
+		}
+
+		allowed, _, _ := d.isAllowed(*zoneInfo, ip, tsig, tsigStatus)
+		if allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeMsg records the response code of the reply and sends it.
+func (d dnsHandler) writeMsg(w dns.ResponseWriter, m *dns.Msg) {
+	d.server.counters.response(dns.RcodeToString[m.Rcode])
+	w.WriteMsg(m)
+}
+
+// setEDNS0 echoes the client's EDNS0 OPT record, capping the advertised buffer size to what we're willing to send,
+// and truncates UDP replies to that size (or 512 bytes without EDNS0). Truncation sets the TC bit so that the
+// client retries over TCP.
+func setEDNS0(w dns.ResponseWriter, r *dns.Msg, m *dns.Msg) {
+	size := dns.MinMsgSize
+
+	opt := r.IsEdns0()
+	if opt != nil {
+		size = int(opt.UDPSize())
+		if size < dns.MinMsgSize {
+			size = dns.MinMsgSize
+		} else if size > dns.DefaultMsgSize {
+			size = dns.DefaultMsgSize
+		}
+
+		m.SetEdns0(uint16(size), opt.Do())
+	}
+
+	_, isUDP := w.RemoteAddr().(*net.UDPAddr)
+	if isUDP {
+		m.Truncate(size)
+	}
+}
+
+// zoneForName returns the most specific zone containing the record name.
+func (d dnsHandler) zoneForName(name string) (*Zone, error) {
+	labels := dns.SplitDomainName(name)
+	for i := range labels {
+		zone, err := d.server.zoneRetriever(strings.Join(labels[i:], "."))
+		if err == nil {
+			return zone, nil
+		}
+
+		// Don't try parent zones if the backend failed.
+		if !errors.Is(err, db.ErrNoSuchObject) {
+			return nil, err
+		}
+	}
+
+	return nil, db.ErrNoSuchObject
+}
+
+// isSupportedQuery returns whether the query type can be answered directly from the zone content.
+func isSupportedQuery(qtype uint16) bool {
+	switch qtype {
+	case dns.TypeA, dns.TypeAAAA, dns.TypePTR, dns.TypeCNAME:
+		return true
+	}
+
+	return false
+}
+
+// answerQuery fills the reply with the zone records that match the question.
+// A CNAME for the name is returned regardless of the queried type. If nothing matches, the zone's SOA record
+// is added to the authority section and NXDOMAIN is set if the name doesn't exist in the zone at all.
+func answerQuery(m *dns.Msg, records []dns.RR, q dns.Question) {
+	var soa dns.RR
+	nameFound := false
+
+	for _, rr := range records {
+		hdr := rr.Header()
+		if hdr.Rrtype == dns.TypeSOA && soa == nil {
+			soa = rr
+		}
+
+		if !strings.EqualFold(hdr.Name, q.Name) {
+			continue
+		}
+
+		nameFound = true
+		if hdr.Rrtype == q.Qtype || hdr.Rrtype == dns.TypeCNAME {
+			m.Answer = append(m.Answer, rr)
+		}
+	}
+
+	if len(m.Answer) == 0 {
+		if soa != nil {
+			m.Ns = append(m.Ns, soa)
+		}
+
+		if !nameFound {
+			m.Rcode = dns.RcodeNameError
+		}
+	}
+}
+
+// zonePeer represents a peer (secondary DNS server) defined in the zone configuration.
+type zonePeer struct {
+	address string
+	key     string
+	keys    []string // Names of the additional keys (used during key rollover).
+}
+
+// zonePeers returns the peers defined in the zone configuration keyed by peer name.
+func zonePeers(zone api.NetworkZone) map[string]*zonePeer {
+	peers := map[string]*zonePeer{}
 	for k, v := range zone.Config {
 		if !strings.HasPrefix(k, "peers.") {
 			continue
@@ -116,7 +311,7 @@ func (d *dnsHandler) isAllowed(zone api.NetworkZone, ip string, tsig *dns.TSIG,
 		peerName := fields[1]
 
 		if peers[peerName] == nil {
-			peers[peerName] = &peer{}
+			peers[peerName] = &zonePeer{}
 		}
 
 		// Add the correct validation rule for the dynamic field based on last part of key.
@@ -125,31 +320,78 @@ func (d *dnsHandler) isAllowed(zone api.NetworkZone, ip string, tsig *dns.TSIG,
 			peers[peerName].address = v
 		case "key":
 			peers[peerName].key = v
+		case "keys":
+			for _, entry := range strings.Split(v, ",") {
+				keyFields := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+				if len(keyFields) == 2 {
+					peers[peerName].keys = append(peers[peerName].keys, keyFields[0])
+				}
+			}
+		}
+	}
+
+	return peers
+}
+
+// peerAddressMatches returns whether the client IP matches the peer address, which is either a single IP address
+// or a CIDR subnet.
+func peerAddressMatches(peerAddress string, ip string) bool {
+	if strings.Contains(peerAddress, "/") {
+		_, subnet, err := net.ParseCIDR(peerAddress)
+		if err != nil {
+			return false
 		}
+
+		clientIP := net.ParseIP(ip)
+
+		return clientIP != nil && subnet.Contains(clientIP)
+	}
+
+	return ip == peerAddress
+}
+
+// isAllowed returns whether the client is one of the zone's peers, along with the name of the matching peer or
+// the reason for denying access.
+func (d *dnsHandler) isAllowed(zone api.NetworkZone, ip string, tsig *dns.TSIG, tsigStatus bool) (bool, string, string) {
+	// Build a list of peers.
+	peers := zonePeers(zone)
+	if len(peers) == 0 {
+		return false, "", denyReasonNoPeers
 	}
 
 	// Validate access.
+	denyReason := denyReasonAddress
 	for peerName, peer := range peers {
-		peerKeyName := fmt.Sprintf("%s_%s.", zone.Name, peerName)
+		// Any of the peer's keys can be used (allowing for rollover).
+		peerKeyNames := []string{}
+		if peer.key != "" {
+			peerKeyNames = append(peerKeyNames, fmt.Sprintf("%s_%s.", zone.Name, peerName))
+		}
+
+		for _, keyName := range peer.keys {
+			peerKeyNames = append(peerKeyNames, fmt.Sprintf("%s_%s_%s.", zone.Name, peerName, keyName))
+		}
 
-		if peer.address != "" && ip != peer.address {
+		if peer.address != "" && !peerAddressMatches(peer.address, ip) {
 			// Bad IP address.
 			continue
 		}
 
-		if peer.key != "" && (tsig == nil || !tsigStatus) {
+		if len(peerKeyNames) > 0 && (tsig == nil || !tsigStatus) {
 			// Missing or invalid TSIG.
+			denyReason = denyReasonTSIG
 			continue
 		}
 
-		if peer.key != "" && tsig.Hdr.Name != peerKeyName {
+		if len(peerKeyNames) > 0 && !shared.StringInSlice(tsig.Hdr.Name, peerKeyNames) {
 			// Bad key name (valid TSIG but potentially for another domain).
+			denyReason = denyReasonTSIG
 			continue
 		}
 
 		// We have a trusted peer.
-		return true
+		return true, peerName, ""
 	}
 
-	return false
+	return false, "", denyReason
 }
@@ -0,0 +1,90 @@
+package dns
+
+import (
+	"sync"
+
+	"github.com/lxc/lxd/lxd/metrics"
+)
+
+// Access denial reasons.
+const (
+	denyReasonNoPeers = "no_peers" // The zone doesn't have any peers configured.
+	denyReasonAddress = "address"  // No peer matched the client address.
+	denyReasonTSIG    = "tsig"     // A peer matched the client address but the TSIG was missing or invalid.
+
+	denyReasonRecursion = "recursion" // The client isn't a peer of any zone so its query wasn't forwarded upstream.
+)
+
+// counters holds the DNS server request counters.
+type counters struct {
+	queries   map[string]uint64    // Keyed by query type.
+	responses map[string]uint64    // Keyed by response code.
+	denials   map[string]uint64    // Keyed by denial reason.
+	transfers map[[2]string]uint64 // Keyed by zone and peer name.
+
+	mu sync.Mutex
+}
+
+func newCounters() *counters {
+	return &counters{
+		queries:   map[string]uint64{},
+		responses: map[string]uint64{},
+		denials:   map[string]uint64{},
+		transfers: map[[2]string]uint64{},
+	}
+}
+
+func (c *counters) query(qtype string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queries[qtype]++
+}
+
+func (c *counters) response(rcode string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.responses[rcode]++
+}
+
+func (c *counters) denial(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.denials[reason]++
+}
+
+func (c *counters) transfer(zone string, peer string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.transfers[[2]string{zone, peer}]++
+}
+
+// Metrics returns the request counters of the DNS server.
+func (s *Server) Metrics() *metrics.MetricSet {
+	c := s.counters
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	set := metrics.NewMetricSet(nil)
+
+	for qtype, value := range c.queries {
+		set.AddSamples(metrics.DNSQueriesTotal, metrics.Sample{Value: value, Labels: map[string]string{"type": qtype}})
+	}
+
+	for rcode, value := range c.responses {
+		set.AddSamples(metrics.DNSResponsesTotal, metrics.Sample{Value: value, Labels: map[string]string{"rcode": rcode}})
+	}
+
+	for reason, value := range c.denials {
+		set.AddSamples(metrics.DNSDeniedTotal, metrics.Sample{Value: value, Labels: map[string]string{"reason": reason}})
+	}
+
+	for key, value := range c.transfers {
+		set.AddSamples(metrics.DNSTransfersTotal, metrics.Sample{Value: value, Labels: map[string]string{"zone": key[0], "peer": key[1]}})
+	}
+
+	return set
+}
@@ -0,0 +1,64 @@
+package dns
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	log "gopkg.in/inconshreveable/log15.v2"
+
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// NotifyZone sends a DNS NOTIFY for the zone to all of its peers that have an address configured, prompting them
+// to transfer the zone again rather than waiting for the SOA refresh interval. Messages are sent in the background.
+func (s *Server) NotifyZone(name string) error {
+	// Locking.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.notifyZone(name)
+}
+
+func (s *Server) notifyZone(name string) error {
+	// Skip if not listening (peers would have nothing to transfer from).
+	if s.address == "" || s.zoneRetriever == nil {
+		return nil
+	}
+
+	zone, err := s.zoneRetriever(name)
+	if err != nil {
+		return err
+	}
+
+	for peerName, peer := range zonePeers(zone.Info) {
+		// Subnet peers don't have a single server to notify.
+		if peer.address == "" || strings.Contains(peer.address, "/") {
+			continue
+		}
+
+		go func(peerName string, address string) {
+			err := sendNotify(name, address)
+			if err != nil {
+				logger.Warn("Failed sending DNS NOTIFY", log.Ctx{"zone": name, "peer": peerName, "address": address, "err": err})
+			}
+		}(peerName, peer.address)
+	}
+
+	return nil
+}
+
+// sendNotify sends a NOTIFY message for the zone to the DNS server at the address.
+func sendNotify(zoneName string, address string) error {
+	m := new(dns.Msg)
+	m.SetNotify(dns.Fqdn(zoneName))
+
+	client := &dns.Client{Timeout: 5 * time.Second}
+	_, _, err := client.Exchange(m, net.JoinHostPort(address, "53"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -12,6 +12,8 @@ import (
 )
 
 // ZoneRetriever is a function which fetches a DNS zone.
+// It must return an error matching db.ErrNoSuchObject when the zone doesn't exist, any other error is treated as a
+// temporary failure.
 type ZoneRetriever func(name string) (*Zone, error)
 
 // Server represents a DNS server instance.
@@ -26,13 +28,21 @@ type Server struct {
 	// Internal state (to handle reconfiguration).
 	address string
 
+	// Request counters and parsed zones (with their own locking as the handler runs concurrently).
+	counters  *counters
+	zoneCache *zoneCache
+
+	// Upstream resolvers for names outside of the served zones (forwarding is disabled when empty).
+	upstreams   []string
+	upstreamsMu sync.Mutex
+
 	mu sync.Mutex
 }
 
 // NewServer returns a new server instance.
 func NewServer(db *db.Cluster, retriever ZoneRetriever) *Server {
 	// Setup new struct.
-	s := &Server{db: db, zoneRetriever: retriever}
+	s := &Server{db: db, zoneRetriever: retriever, counters: newCounters(), zoneCache: newZoneCache()}
 	return s
 }
 
@@ -146,6 +156,25 @@ func (s *Server) reconfigure(address string) error {
 	return nil
 }
 
+// SetUpstreams sets the upstream resolvers that queries for names outside of the served zones are forwarded to.
+// An empty list disables forwarding.
+func (s *Server) SetUpstreams(upstreams []string) {
+	s.upstreamsMu.Lock()
+	defer s.upstreamsMu.Unlock()
+
+	s.upstreams = make([]string, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		s.upstreams = append(s.upstreams, util.CanonicalNetworkAddress(upstream, 53))
+	}
+}
+
+func (s *Server) getUpstreams() []string {
+	s.upstreamsMu.Lock()
+	defer s.upstreamsMu.Unlock()
+
+	return s.upstreams
+}
+
 // UpdateTSIG fetches all TSIG keys and loads them into the DNS server.
 func (s *Server) UpdateTSIG() error {
 	// Locking.
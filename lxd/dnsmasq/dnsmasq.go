@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -24,11 +26,25 @@ type DHCPAllocation struct {
 	Static bool
 }
 
+// DNSSECRootTrustAnchor is the DS record of the DNS root zone key signing key (KSK-2017).
+const DNSSECRootTrustAnchor = ".,20326,8,2,E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"
+
+// LeasesDir returns the directory that network leases files can be relocated to.
+func LeasesDir() string {
+	return shared.VarPath("leases")
+}
+
+// IsLeasesPath returns whether the path is that of a file directly inside LeasesDir.
+func IsLeasesPath(path string) bool {
+	return filepath.IsAbs(path) && filepath.Clean(path) == path && filepath.Dir(path) == LeasesDir()
+}
+
 // ConfigMutex used to coordinate access to the dnsmasq config files.
 var ConfigMutex sync.Mutex
 
 // UpdateStaticEntry writes a single dhcp-host line for a network/instance combination.
-func UpdateStaticEntry(network string, projectName string, instanceName string, netConfig map[string]string, hwaddr string, ipv4Address string, ipv6Address string) error {
+// The host record uses dnsName if not empty, otherwise the instance name.
+func UpdateStaticEntry(network string, projectName string, instanceName string, dnsName string, netConfig map[string]string, hwaddr string, ipv4Address string, ipv6Address string) error {
 	hwaddr = strings.ToLower(hwaddr)
 	line := hwaddr
 
@@ -42,7 +58,11 @@ func UpdateStaticEntry(network string, projectName string, instanceName string,
 	}
 
 	if netConfig["dns.mode"] == "" || netConfig["dns.mode"] == "managed" {
-		line += fmt.Sprintf(",%s", project.DNS(projectName, instanceName))
+		if dnsName == "" {
+			dnsName = instanceName
+		}
+
+		line += fmt.Sprintf(",%s", project.DNS(projectName, dnsName))
 	}
 
 	if line == hwaddr {
@@ -101,6 +121,40 @@ func Kill(name string, reload bool) error {
 	return nil
 }
 
+// ReloadIfUnchanged reloads the running dnsmasq for a particular network if it was started with the same
+// command, arguments and AppArmor profile as the given process. This preserves its in-memory lease state while
+// still picking up changes to the static host entries.
+// Returns true if dnsmasq was reloaded, or false if it needs to be (re)started.
+func ReloadIfUnchanged(name string, p *subprocess.Process) (bool, error) {
+	pidPath := shared.VarPath("networks", name, "dnsmasq.pid")
+
+	// If the pid file doesn't exist, there is no process to reload.
+	if !shared.PathExists(pidPath) {
+		return false, nil
+	}
+
+	// Import saved subprocess details.
+	running, err := subprocess.ImportProcess(pidPath)
+	if err != nil {
+		return false, fmt.Errorf("Could not read pid file: %s", err)
+	}
+
+	if running.Name != p.Name || running.Apparmor != p.Apparmor || !reflect.DeepEqual(running.Args, p.Args) {
+		return false, nil
+	}
+
+	err = running.Reload()
+	if err != nil {
+		if err == subprocess.ErrNotRunning {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("Could not reload dnsmasq: %s", err)
+	}
+
+	return true, nil
+}
+
 // GetVersion returns the version of dnsmasq.
 func GetVersion() (*version.DottedVersion, error) {
 	output, err := shared.RunCommandCLocale("dnsmasq", "--version")
@@ -112,6 +166,23 @@ func GetVersion() (*version.DottedVersion, error) {
 	return version.Parse(lines[2])
 }
 
+// SupportsDNSSEC returns whether dnsmasq was built with DNSSEC support.
+func SupportsDNSSEC() (bool, error) {
+	output, err := shared.RunCommandCLocale("dnsmasq", "--version")
+	if err != nil {
+		return false, fmt.Errorf("Failed to check dnsmasq compile time options: %v", err)
+	}
+
+	// Unsupported options are listed with a "no-" prefix so look for an exact match.
+	for _, option := range strings.Fields(output) {
+		if option == "DNSSEC" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 // DHCPStaticAllocation retrieves the dnsmasq statically allocated MAC and IPs for an instance.
 // Returns MAC, IPv4 and IPv6 DHCPAllocation structs respectively.
 func DHCPStaticAllocation(network, projectName, instanceName string) (net.HardwareAddr, DHCPAllocation, DHCPAllocation, error) {
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/lxc/lxd/lxd/firewall/drivers"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+)
+
+var firewallTestCmd = APIEndpoint{
+	Path: "firewall-test",
+
+	Post: APIEndpointAction{Handler: firewallTestPost},
+}
+
+// swagger:operation POST /1.0/firewall-test server firewall_test_post
+//
+// Run a firewall self-test
+//
+// Installs and immediately removes a representative set of firewall rules (SNAT, an address forward and an
+// ACL drop rule) on a throwaway network using the active firewall driver, reporting which operations succeeded.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: target
+//     description: Cluster member name
+//     type: string
+//     example: lxd01
+// responses:
+//   "200":
+//     description: Firewall test result
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/FirewallTest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func firewallTestPost(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	ops, err := d.firewall.SelfTest()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, firewallTestResult(d.firewall.String(), ops))
+}
+
+// firewallTestResult converts the outcome of the firewall driver self-test operations to its API representation.
+func firewallTestResult(driver string, ops []drivers.SelfTestOperation) api.FirewallTest {
+	result := api.FirewallTest{
+		Driver:     driver,
+		Success:    true,
+		Operations: make([]api.FirewallTestOperation, 0, len(ops)),
+	}
+
+	for _, op := range ops {
+		testOp := api.FirewallTestOperation{
+			Name:    op.Name,
+			Success: op.Error == nil,
+		}
+
+		if op.Error != nil {
+			testOp.Error = op.Error.Error()
+			result.Success = false
+		}
+
+		result.Operations = append(result.Operations, testOp)
+	}
+
+	return result
+}
@@ -28,6 +28,16 @@ type Opts struct {
 	SNATV4     *SNATOpts    // Enable IPv4 SNAT with specified options. Off if not provided.
 	SNATV6     *SNATOpts    // Enable IPv6 SNAT with specified options. Off if not provided.
 	ACL        bool         // Enable ACL during setup.
+	DNSPort    uint64       // Port of the network's DNS server to allow access to. Uses 53 if not provided.
+}
+
+// dnsPort returns the port of the network's DNS server to allow access to.
+func (opts Opts) dnsPort() uint64 {
+	if opts.DNSPort == 0 {
+		return 53
+	}
+
+	return opts.DNSPort
 }
 
 // ACLRule represents an ACL rule that can be added to a firewall.
@@ -53,3 +63,11 @@ type AddressForward struct {
 	ListenPorts   []uint64
 	TargetPorts   []uint64
 }
+
+// NetworkCounters represents the packet and byte counters of the firewall rules associated to a network.
+type NetworkCounters struct {
+	ACLPackets     uint64 // Packets matched by ACL rules.
+	ACLBytes       uint64 // Bytes matched by ACL rules.
+	ForwardPackets uint64 // Packets matched by address forward rules (connections for NAT based forwards).
+	ForwardBytes   uint64 // Bytes matched by address forward rules.
+}
@@ -261,7 +261,7 @@ func (d Nftables) networkSetupOutboundNAT(networkName string, SNATV4 *SNATOpts,
 }
 
 // networkSetupICMPDHCPDNSAccess sets up basic nftables overrides for ICMP, DHCP and DNS.
-func (d Nftables) networkSetupICMPDHCPDNSAccess(networkName string, ipVersions []uint) error {
+func (d Nftables) networkSetupICMPDHCPDNSAccess(networkName string, ipVersions []uint, dnsPort uint64) error {
 	ipFamilies := []string{}
 	for _, ipVersion := range ipVersions {
 		switch ipVersion {
@@ -278,6 +278,7 @@ func (d Nftables) networkSetupICMPDHCPDNSAccess(networkName string, ipVersions [
 		"networkName":    networkName,
 		"family":         "inet",
 		"ipFamilies":     ipFamilies,
+		"dnsPort":        dnsPort,
 	}
 
 	err := d.applyNftConfig(nftablesNetICMPDHCPDNS, tplFields)
@@ -288,12 +289,13 @@ func (d Nftables) networkSetupICMPDHCPDNSAccess(networkName string, ipVersions [
 	return nil
 }
 
-func (d Nftables) networkSetupACLChainAndJumpRules(networkName string) error {
+func (d Nftables) networkSetupACLChainAndJumpRules(networkName string, dnsPort uint64) error {
 	tplFields := map[string]interface{}{
 		"namespace":      nftablesNamespace,
 		"chainSeparator": nftablesChainSeparator,
 		"networkName":    networkName,
 		"family":         "inet",
+		"dnsPort":        dnsPort,
 	}
 
 	config := &strings.Builder{}
@@ -314,7 +316,7 @@ func (d Nftables) networkSetupACLChainAndJumpRules(networkName string) error {
 func (d Nftables) NetworkSetup(networkName string, opts Opts) error {
 	// Do this first before adding other network rules, so jump to ACL rules come first.
 	if opts.ACL {
-		err := d.networkSetupACLChainAndJumpRules(networkName)
+		err := d.networkSetupACLChainAndJumpRules(networkName, opts.dnsPort())
 		if err != nil {
 			return err
 		}
@@ -352,7 +354,7 @@ func (d Nftables) NetworkSetup(networkName string, opts Opts) error {
 			return err
 		}
 
-		err = d.networkSetupICMPDHCPDNSAccess(networkName, dhcpDNSAccess)
+		err = d.networkSetupICMPDHCPDNSAccess(networkName, dhcpDNSAccess, opts.dnsPort())
 		if err != nil {
 			return err
 		}
@@ -798,6 +800,9 @@ func (d Nftables) aclRuleCriteriaToRules(networkName string, ipVersion uint, rul
 		}
 	}
 
+	// Count matched packets so they can be reported in the network's state.
+	args = append(args, "counter")
+
 	// Handle logging.
 	if rule.Log {
 		args = append(args, "log")
@@ -1040,3 +1045,83 @@ func (d Nftables) NetworkApplyForwards(networkName string, rules []AddressForwar
 
 	return nil
 }
+
+// nftChainCounters returns the sum of the packet and byte counters of the rules in the specified chains.
+func (d Nftables) nftChainCounters(family string, chains ...string) (uint64, uint64, error) {
+	// Dump ruleset as JSON. Use -nn flags to avoid doing DNS lookups of IPs mentioned in any rules.
+	output, err := shared.RunCommandCLocale("nft", "--json", "-nn", "list", "ruleset")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	v := &struct {
+		Nftables []struct {
+			Rule *struct {
+				Family string                       `json:"family"`
+				Table  string                       `json:"table"`
+				Chain  string                       `json:"chain"`
+				Expr   []map[string]json.RawMessage `json:"expr"`
+			} `json:"rule"`
+		} `json:"nftables"`
+	}{}
+
+	err = json.Unmarshal([]byte(output), v)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var packets, bytes uint64
+
+	for _, item := range v.Nftables {
+		rule := item.Rule
+		if rule == nil || rule.Family != family || rule.Table != nftablesNamespace || !shared.StringInSlice(rule.Chain, chains) {
+			continue
+		}
+
+		for _, expr := range rule.Expr {
+			counterJSON, found := expr["counter"]
+			if !found {
+				continue
+			}
+
+			counter := struct {
+				Packets uint64 `json:"packets"`
+				Bytes   uint64 `json:"bytes"`
+			}{}
+
+			err = json.Unmarshal(counterJSON, &counter)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			packets += counter.Packets
+			bytes += counter.Bytes
+		}
+	}
+
+	return packets, bytes, nil
+}
+
+// NetworkCounters returns the packet and byte counters of the ACL and address forward rules of a network.
+func (d Nftables) NetworkCounters(networkName string) (*NetworkCounters, error) {
+	counters := &NetworkCounters{}
+	var err error
+
+	counters.ACLPackets, counters.ACLBytes, err = d.nftChainCounters("inet", fmt.Sprintf("acl%s%s", nftablesChainSeparator, networkName))
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting ACL rule counters for network %q: %w", networkName, err)
+	}
+
+	// Only the prerouting and output chains are counted as the postrouting chain only handles hairpin NAT.
+	counters.ForwardPackets, counters.ForwardBytes, err = d.nftChainCounters("inet", fmt.Sprintf("fwdprert%s%s", nftablesChainSeparator, networkName), fmt.Sprintf("fwdout%s%s", nftablesChainSeparator, networkName))
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting address forward rule counters for network %q: %w", networkName, err)
+	}
+
+	return counters, nil
+}
+
+// SelfTest installs and removes a representative set of rules on a throwaway network and reports the outcome.
+func (d Nftables) SelfTest() ([]SelfTestOperation, error) {
+	return selfTest(d)
+}
@@ -44,8 +44,8 @@ var nftablesNetICMPDHCPDNS = template.Must(template.New("nftablesNetDHCPDNS").Pa
 chain in{{.chainSeparator}}{{.networkName}} {
 	type filter hook input priority 0; policy accept;
 
-	iifname "{{.networkName}}" tcp dport 53 accept
-	iifname "{{.networkName}}" udp dport 53 accept
+	iifname "{{.networkName}}" tcp dport {{.dnsPort}} accept
+	iifname "{{.networkName}}" udp dport {{.dnsPort}} accept
 
 	{{- range .ipFamilies}}
 	{{if eq . "ip" -}}
@@ -89,14 +89,14 @@ table {{.family}} {{.namespace}} {
 	chain {{.chainPrefix}}prert{{.chainSeparator}}{{.label}} {
 		type nat hook prerouting priority -100; policy accept;
 		{{- range .dnatRules}}
-		{{.ipFamily}} daddr {{.listenAddress}} {{if .protocol}}{{.protocol}} dport {{.listenPorts}}{{end}} dnat to {{.targetDest}}
+		{{.ipFamily}} daddr {{.listenAddress}} {{if .protocol}}{{.protocol}} dport {{.listenPorts}}{{end}} counter dnat to {{.targetDest}}
 		{{- end}}
 	}
 
 	chain {{.chainPrefix}}out{{.chainSeparator}}{{.label}} {
 		type nat hook output priority -100; policy accept;
 		{{- range .dnatRules}}
-		{{.ipFamily}} daddr {{.listenAddress}} {{if .protocol}}{{.protocol}} dport {{.listenPorts}}{{end}} dnat to {{.targetDest}}
+		{{.ipFamily}} daddr {{.listenAddress}} {{if .protocol}}{{.protocol}} dport {{.listenPorts}}{{end}} counter dnat to {{.targetDest}}
 		{{- end}}
 	}
 
@@ -123,8 +123,8 @@ flush chain {{.family}} {{.namespace}} aclfwd{{.chainSeparator}}{{.networkName}}
 table {{.family}} {{.namespace}} {
 	chain aclin{{.chainSeparator}}{{.networkName}} {
 		# Allow DNS to LXD host.
-		iifname "{{.networkName}}" tcp dport 53 accept
-		iifname "{{.networkName}}" udp dport 53 accept
+		iifname "{{.networkName}}" tcp dport {{.dnsPort}} accept
+		iifname "{{.networkName}}" udp dport {{.dnsPort}} accept
 
 		# Allow DHCPv6 to LXD host.
 		iifname "{{$.networkName}}" udp dport 67 accept
@@ -165,7 +165,7 @@ flush chain {{.family}} {{.namespace}} acl{{.chainSeparator}}{{.networkName}}
 
 table {{.family}} {{.namespace}} {
 	chain acl{{.chainSeparator}}{{.networkName}} {
-                ct state established,related accept
+                ct state established,related counter accept
 
 		{{- range .rules}}
 		{{.}}
@@ -0,0 +1,119 @@
+package drivers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// selfTestLock serializes the firewall self-tests, so that concurrent requests don't interfere with each other.
+var selfTestLock sync.Mutex
+
+// SelfTestOperation represents the outcome of a single firewall operation performed by the self-test.
+type SelfTestOperation struct {
+	Name  string // Name of the operation.
+	Error error  // Error returned by the operation. Nil if the operation succeeded.
+}
+
+// selfTestDriver is the subset of the firewall driver functions exercised by the self-test.
+type selfTestDriver interface {
+	NetworkSetup(networkName string, opts Opts) error
+	NetworkClear(networkName string, delete bool, ipVersions []uint) error
+	NetworkApplyACLRules(networkName string, rules []ACLRule) error
+	NetworkApplyForwards(networkName string, rules []AddressForward) error
+}
+
+// selfTestNetworkName returns a random name for the throwaway network used to run the firewall self-test, which
+// doesn't match any existing interface (whose rules would otherwise be removed by the test).
+func selfTestNetworkName() (string, error) {
+	for i := 0; i < 10; i++ {
+		randBytes := make([]byte, 4)
+		_, err := rand.Read(randBytes)
+		if err != nil {
+			return "", err
+		}
+
+		// Keep within the interface name length limit, like the names of real networks.
+		name := fmt.Sprintf("lxdtest%s", hex.EncodeToString(randBytes))
+		if !shared.PathExists(filepath.Join("/sys/class/net", name)) {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("Failed generating an unused network name for the firewall self-test")
+}
+
+// selfTest installs and then removes a representative set of network rules (SNAT, an address forward and an
+// ACL drop rule) for a throwaway network using the supplied driver, and returns the outcome of each operation.
+// Documentation addresses are used for all rules so that no real traffic is matched while they are installed.
+func selfTest(d selfTestDriver) ([]SelfTestOperation, error) {
+	selfTestLock.Lock()
+	defer selfTestLock.Unlock()
+
+	networkName, err := selfTestNetworkName()
+	if err != nil {
+		return nil, err
+	}
+
+	_, subnetV4, _ := net.ParseCIDR("192.0.2.0/24")
+	_, subnetV6, _ := net.ParseCIDR("2001:db8::/64")
+
+	ops := []struct {
+		name string
+		run  func() error
+	}{
+		{
+			name: "snat",
+			run: func() error {
+				return d.NetworkSetup(networkName, Opts{
+					SNATV4: &SNATOpts{Subnet: subnetV4},
+					SNATV6: &SNATOpts{Subnet: subnetV6},
+					ACL:    true,
+				})
+			},
+		},
+		{
+			name: "forward",
+			run: func() error {
+				return d.NetworkApplyForwards(networkName, []AddressForward{
+					{
+						ListenAddress: net.ParseIP("198.51.100.1"),
+						TargetAddress: net.ParseIP("192.0.2.2"),
+						Protocol:      "tcp",
+						ListenPorts:   []uint64{80},
+						TargetPorts:   []uint64{8080},
+					},
+				})
+			},
+		},
+		{
+			name: "acl",
+			run: func() error {
+				return d.NetworkApplyACLRules(networkName, []ACLRule{
+					{
+						Direction:       "ingress",
+						Action:          "drop",
+						Source:          "198.51.100.0/24",
+						Protocol:        "tcp",
+						DestinationPort: "22",
+					},
+				})
+			},
+		},
+	}
+
+	results := make([]SelfTestOperation, 0, len(ops)+1)
+	for _, op := range ops {
+		results = append(results, SelfTestOperation{Name: op.name, Error: op.run()})
+	}
+
+	// Always attempt to remove the rules, even if some of them failed to be installed.
+	results = append(results, SelfTestOperation{Name: "clear", Error: d.NetworkClear(networkName, true, []uint{4, 6})})
+
+	return results, nil
+}
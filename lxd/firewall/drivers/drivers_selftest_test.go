@@ -0,0 +1,64 @@
+package drivers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// selfTestFakeDriver records the networks it is called for and fails the operations listed in errors.
+type selfTestFakeDriver struct {
+	networks []string
+	errors   map[string]error
+}
+
+func (d *selfTestFakeDriver) NetworkSetup(networkName string, opts Opts) error {
+	d.networks = append(d.networks, networkName)
+	return d.errors["snat"]
+}
+
+func (d *selfTestFakeDriver) NetworkClear(networkName string, delete bool, ipVersions []uint) error {
+	d.networks = append(d.networks, networkName)
+	return d.errors["clear"]
+}
+
+func (d *selfTestFakeDriver) NetworkApplyACLRules(networkName string, rules []ACLRule) error {
+	d.networks = append(d.networks, networkName)
+	return d.errors["acl"]
+}
+
+func (d *selfTestFakeDriver) NetworkApplyForwards(networkName string, rules []AddressForward) error {
+	d.networks = append(d.networks, networkName)
+	return d.errors["forward"]
+}
+
+func Test_selfTest(t *testing.T) {
+	errACL := fmt.Errorf("Failed adding ACL rules")
+	d := &selfTestFakeDriver{errors: map[string]error{"acl": errACL}}
+
+	ops, err := selfTest(d)
+	assert.NoError(t, err)
+
+	// The rules are always cleared, even after a failed operation.
+	assert.Equal(t, []SelfTestOperation{
+		{Name: "snat"},
+		{Name: "forward"},
+		{Name: "acl", Error: errACL},
+		{Name: "clear"},
+	}, ops)
+
+	// All operations use the same generated network name.
+	assert.Len(t, d.networks, 4)
+	for _, networkName := range d.networks {
+		assert.Equal(t, d.networks[0], networkName)
+	}
+
+	assert.Regexp(t, "^lxdtest[0-9a-f]{8}$", d.networks[0])
+
+	// Each run uses a new network name.
+	other := &selfTestFakeDriver{}
+	_, err = selfTest(other)
+	assert.NoError(t, err)
+	assert.NotEqual(t, d.networks[0], other.networks[0])
+}
@@ -200,7 +200,7 @@ func (d Xtables) networkSetupNICFilteringChain(networkName string, ipVersion uin
 }
 
 // networkSetupACLFilteringChains creates any missing ACL chains and adds jump rules.
-func (d Xtables) networkSetupACLFilteringChains(networkName string) error {
+func (d Xtables) networkSetupACLFilteringChains(networkName string, dnsPort uint64) error {
 	chain := fmt.Sprintf("%s_%s", iptablesChainACLFilterPrefix, networkName)
 
 	for _, ipVersion := range []uint{4, 6} {
@@ -236,12 +236,12 @@ func (d Xtables) networkSetupACLFilteringChains(networkName string) error {
 		// used to block baseline service traffic.
 
 		// Allow DNS to LXD host.
-		err = d.iptablesPrepend(ipVersion, comment, "filter", "INPUT", "-i", networkName, "-p", "tcp", "--dport", "53", "-j", "ACCEPT")
+		err = d.iptablesPrepend(ipVersion, comment, "filter", "INPUT", "-i", networkName, "-p", "tcp", "--dport", fmt.Sprintf("%d", dnsPort), "-j", "ACCEPT")
 		if err != nil {
 			return err
 		}
 
-		err = d.iptablesPrepend(ipVersion, comment, "filter", "INPUT", "-i", networkName, "-p", "udp", "--dport", "53", "-j", "ACCEPT")
+		err = d.iptablesPrepend(ipVersion, comment, "filter", "INPUT", "-i", networkName, "-p", "udp", "--dport", fmt.Sprintf("%d", dnsPort), "-j", "ACCEPT")
 		if err != nil {
 			return err
 		}
@@ -379,16 +379,18 @@ func (d Xtables) networkSetupOutboundNAT(networkName string, subnet *net.IPNet,
 }
 
 // networkSetupICMPDHCPDNSAccess sets up basic iptables overrides for ICMP, DHCP and DNS.
-func (d Xtables) networkSetupICMPDHCPDNSAccess(networkName string, ipVersion uint) error {
+func (d Xtables) networkSetupICMPDHCPDNSAccess(networkName string, ipVersion uint, dnsPort uint64) error {
+	port := fmt.Sprintf("%d", dnsPort)
+
 	var rules [][]string
 	if ipVersion == 4 {
 		rules = [][]string{
 			{"4", networkName, "filter", "INPUT", "-i", networkName, "-p", "udp", "--dport", "67", "-j", "ACCEPT"},
-			{"4", networkName, "filter", "INPUT", "-i", networkName, "-p", "udp", "--dport", "53", "-j", "ACCEPT"},
-			{"4", networkName, "filter", "INPUT", "-i", networkName, "-p", "tcp", "--dport", "53", "-j", "ACCEPT"},
+			{"4", networkName, "filter", "INPUT", "-i", networkName, "-p", "udp", "--dport", port, "-j", "ACCEPT"},
+			{"4", networkName, "filter", "INPUT", "-i", networkName, "-p", "tcp", "--dport", port, "-j", "ACCEPT"},
 			{"4", networkName, "filter", "OUTPUT", "-o", networkName, "-p", "udp", "--sport", "67", "-j", "ACCEPT"},
-			{"4", networkName, "filter", "OUTPUT", "-o", networkName, "-p", "udp", "--sport", "53", "-j", "ACCEPT"},
-			{"4", networkName, "filter", "OUTPUT", "-o", networkName, "-p", "tcp", "--sport", "53", "-j", "ACCEPT"}}
+			{"4", networkName, "filter", "OUTPUT", "-o", networkName, "-p", "udp", "--sport", port, "-j", "ACCEPT"},
+			{"4", networkName, "filter", "OUTPUT", "-o", networkName, "-p", "tcp", "--sport", port, "-j", "ACCEPT"}}
 
 		// Allow core ICMPv4 to/from LXD host.
 		for _, icmpType := range []int{3, 11, 12} {
@@ -398,11 +400,11 @@ func (d Xtables) networkSetupICMPDHCPDNSAccess(networkName string, ipVersion uin
 	} else if ipVersion == 6 {
 		rules = [][]string{
 			{"6", networkName, "filter", "INPUT", "-i", networkName, "-p", "udp", "--dport", "547", "-j", "ACCEPT"},
-			{"6", networkName, "filter", "INPUT", "-i", networkName, "-p", "udp", "--dport", "53", "-j", "ACCEPT"},
-			{"6", networkName, "filter", "INPUT", "-i", networkName, "-p", "tcp", "--dport", "53", "-j", "ACCEPT"},
+			{"6", networkName, "filter", "INPUT", "-i", networkName, "-p", "udp", "--dport", port, "-j", "ACCEPT"},
+			{"6", networkName, "filter", "INPUT", "-i", networkName, "-p", "tcp", "--dport", port, "-j", "ACCEPT"},
 			{"6", networkName, "filter", "OUTPUT", "-o", networkName, "-p", "udp", "--sport", "547", "-j", "ACCEPT"},
-			{"6", networkName, "filter", "OUTPUT", "-o", networkName, "-p", "udp", "--sport", "53", "-j", "ACCEPT"},
-			{"6", networkName, "filter", "OUTPUT", "-o", networkName, "-p", "tcp", "--sport", "53", "-j", "ACCEPT"}}
+			{"6", networkName, "filter", "OUTPUT", "-o", networkName, "-p", "udp", "--sport", port, "-j", "ACCEPT"},
+			{"6", networkName, "filter", "OUTPUT", "-o", networkName, "-p", "tcp", "--sport", port, "-j", "ACCEPT"}}
 
 		// Allow core ICMPv6 to/from LXD host.
 		for _, icmpType := range []int{1, 2, 3, 4, 133, 135, 136, 143} {
@@ -458,7 +460,7 @@ func (d Xtables) NetworkSetup(networkName string, opts Opts) error {
 
 	if opts.FeaturesV4 != nil {
 		if opts.FeaturesV4.ICMPDHCPDNSAccess {
-			err := d.networkSetupICMPDHCPDNSAccess(networkName, 4)
+			err := d.networkSetupICMPDHCPDNSAccess(networkName, 4, opts.dnsPort())
 			if err != nil {
 				return err
 			}
@@ -477,7 +479,7 @@ func (d Xtables) NetworkSetup(networkName string, opts Opts) error {
 
 	if opts.FeaturesV6 != nil {
 		if opts.FeaturesV6.ICMPDHCPDNSAccess {
-			err := d.networkSetupICMPDHCPDNSAccess(networkName, 6)
+			err := d.networkSetupICMPDHCPDNSAccess(networkName, 6, opts.dnsPort())
 			if err != nil {
 				return err
 			}
@@ -491,7 +493,7 @@ func (d Xtables) NetworkSetup(networkName string, opts Opts) error {
 
 	if opts.ACL {
 		// Needs to be after networkSetupForwardingPolicy but before networkSetupNICFilteringChain.
-		err := d.networkSetupACLFilteringChains(networkName)
+		err := d.networkSetupACLFilteringChains(networkName, opts.dnsPort())
 		if err != nil {
 			return err
 		}
@@ -1518,3 +1520,100 @@ func (d Xtables) NetworkApplyForwards(networkName string, rules []AddressForward
 
 	return nil
 }
+
+// iptablesChainCounters returns the sum of the packet and byte counters of the rules in a chain.
+// If comment is not empty then only rules with that comment are counted.
+func (d Xtables) iptablesChainCounters(ipVersion uint, table string, chain string, comment string) (uint64, uint64, error) {
+	var cmd string
+	if ipVersion == 4 {
+		cmd = "iptables"
+	} else if ipVersion == 6 {
+		cmd = "ip6tables"
+	} else {
+		return 0, 0, fmt.Errorf("Invalid IP version")
+	}
+
+	// Detect kernels that lack IPv6 support.
+	if !shared.PathExists("/proc/sys/net/ipv6") && ipVersion == 6 {
+		return 0, 0, nil
+	}
+
+	exists, hasRules, err := d.iptablesChainExists(ipVersion, table, chain)
+	if err != nil || !exists || !hasRules {
+		return 0, 0, err
+	}
+
+	output, err := shared.TryRunCommand(cmd, "-w", "-t", table, "-L", chain, "-v", "-x", "-n")
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "Failed listing %q chain %q in table %q", cmd, chain, table)
+	}
+
+	var packets, bytes uint64
+
+	for i, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		// Skip the chain name and column header lines.
+		if i < 2 {
+			continue
+		}
+
+		if comment != "" && !strings.Contains(line, fmt.Sprintf("/* %s %s */", iptablesCommentPrefix, comment)) {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		rulePackets, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "Failed parsing packet counter %q", fields[0])
+		}
+
+		ruleBytes, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "Failed parsing byte counter %q", fields[1])
+		}
+
+		packets += rulePackets
+		bytes += ruleBytes
+	}
+
+	return packets, bytes, nil
+}
+
+// NetworkCounters returns the packet and byte counters of the ACL and address forward rules of a network.
+// As address forwards use NAT rules, the forward counters only reflect the first packet of each connection.
+func (d Xtables) NetworkCounters(networkName string) (*NetworkCounters, error) {
+	counters := &NetworkCounters{}
+	aclChain := fmt.Sprintf("%s_%s", iptablesChainACLFilterPrefix, networkName)
+	forwardComment := d.networkForwardIPTablesComment(networkName)
+
+	for _, ipVersion := range []uint{4, 6} {
+		packets, bytes, err := d.iptablesChainCounters(ipVersion, "filter", aclChain, "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed getting ACL rule counters for network %q", networkName)
+		}
+
+		counters.ACLPackets += packets
+		counters.ACLBytes += bytes
+
+		// Only the PREROUTING and OUTPUT chains are counted as POSTROUTING only handles hairpin NAT.
+		for _, chain := range []string{"PREROUTING", "OUTPUT"} {
+			packets, bytes, err := d.iptablesChainCounters(ipVersion, "nat", chain, forwardComment)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed getting address forward rule counters for network %q", networkName)
+			}
+
+			counters.ForwardPackets += packets
+			counters.ForwardBytes += bytes
+		}
+	}
+
+	return counters, nil
+}
+
+// SelfTest installs and removes a representative set of rules on a throwaway network and reports the outcome.
+func (d Xtables) SelfTest() ([]SelfTestOperation, error) {
+	return selfTest(d)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/firewall/drivers"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestFirewallTestResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		ops      []drivers.SelfTestOperation
+		expected api.FirewallTest
+	}{
+		{
+			name: "All operations succeeded",
+			ops: []drivers.SelfTestOperation{
+				{Name: "snat"},
+				{Name: "clear"},
+			},
+			expected: api.FirewallTest{
+				Driver:  "nftables",
+				Success: true,
+				Operations: []api.FirewallTestOperation{
+					{Name: "snat", Success: true},
+					{Name: "clear", Success: true},
+				},
+			},
+		},
+		{
+			name: "One operation failed",
+			ops: []drivers.SelfTestOperation{
+				{Name: "snat"},
+				{Name: "acl", Error: fmt.Errorf("Failed adding ACL rules")},
+				{Name: "clear"},
+			},
+			expected: api.FirewallTest{
+				Driver:  "nftables",
+				Success: false,
+				Operations: []api.FirewallTestOperation{
+					{Name: "snat", Success: true},
+					{Name: "acl", Success: false, Error: "Failed adding ACL rules"},
+					{Name: "clear", Success: true},
+				},
+			},
+		},
+		{
+			name: "No operations",
+			ops:  nil,
+			expected: api.FirewallTest{
+				Driver:     "nftables",
+				Success:    true,
+				Operations: []api.FirewallTestOperation{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, firewallTestResult("nftables", test.ops))
+		})
+	}
+}
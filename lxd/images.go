@@ -3413,6 +3413,7 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 	files[0].Identifier = filename
 	files[0].Path = imagePath
 	files[0].Filename = filename
+	files[0].SHA256 = imgInfo.Fingerprint // Unified image fingerprints are the SHA-256 of the image file.
 
 	requestor := request.CreateRequestor(r)
 	d.State().Events.SendLifecycle(projectName, lifecycle.ImageRetrieved.Event(imgInfo.Fingerprint, projectName, requestor, nil))
@@ -1,6 +1,7 @@
 package drivers
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"path/filepath"
@@ -221,6 +222,15 @@ func (d *common) SetOperation(op *operations.Operation) {
 	d.op = op
 }
 
+// operationContext returns the context of the instance's current operation, or a background context if none.
+func (d *common) operationContext() context.Context {
+	if d.op == nil {
+		return context.Background()
+	}
+
+	return d.op.Context()
+}
+
 // Snapshots returns a list of snapshots.
 func (d *common) Snapshots() ([]instance.Instance, error) {
 	var snaps []db.Instance
@@ -911,7 +911,7 @@ func (d *qemu) restoreState(monitor *qmp.Monitor) error {
 		return err
 	}
 
-	err = monitor.MigrateIncoming("fd:migration")
+	err = monitor.MigrateIncoming(d.operationContext(), "fd:migration")
 	if err != nil {
 		return err
 	}
@@ -956,7 +956,7 @@ func (d *qemu) saveState(monitor *qmp.Monitor) error {
 	}
 
 	// Issue the migration command.
-	err = monitor.Migrate("fd:migration")
+	err = monitor.Migrate(d.operationContext(), "fd:migration")
 	if err != nil {
 		compressedState.Close()
 		stateFile.Close()
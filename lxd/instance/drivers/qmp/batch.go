@@ -0,0 +1,57 @@
+package qmp
+
+import (
+	"encoding/json"
+)
+
+// Command represents a QMP command to run as part of a batch.
+type Command struct {
+	Execute   string
+	Arguments interface{}
+}
+
+// Result represents the raw return value of a command run as part of a batch.
+type Result struct {
+	Execute string
+	Return  json.RawMessage
+}
+
+// RunBatch sends all the commands to QEMU at once, saving a socket round-trip per command, and returns their
+// results in order. The replies are matched to the commands by the ID each command is tagged with.
+// It stops at the first failed command and returns the results collected before it. As the commands are all
+// sent up front, QEMU still runs the commands following a failed one, so only independent commands should be
+// batched together.
+func (m *Monitor) RunBatch(commands []Command) ([]Result, error) {
+	// Check if disconnected
+	if m.disconnected {
+		return nil, ErrMonitorDisconnect
+	}
+
+	replies, err := m.qmp.RunBatch(commands)
+
+	results := make([]Result, 0, len(replies))
+	for i, reply := range replies {
+		var resp struct {
+			Return json.RawMessage `json:"return"`
+		}
+
+		errDecode := json.Unmarshal(reply, &resp)
+		if errDecode != nil {
+			return results, ErrMonitorBadReturn
+		}
+
+		results = append(results, Result{Execute: commands[i].Execute, Return: resp.Return})
+	}
+
+	if err != nil {
+		// Confirm the daemon didn't die.
+		errPing := m.ping()
+		if errPing != nil {
+			return results, errPing
+		}
+
+		return results, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,131 @@
+package qmp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQMPBatchServer accepts a single monitor connection and, after the handshake, reads count commands before
+// replying to any of them (in reverse order), returning the command name or an error for the failing command.
+func fakeQMPBatchServer(t *testing.T, path string, count int, failing string) {
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	go func() {
+		defer listener.Close()
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		enc := json.NewEncoder(conn)
+		dec := json.NewDecoder(conn)
+
+		type command struct {
+			Execute string `json:"execute"`
+			ID      string `json:"id"`
+		}
+
+		greeting := map[string]interface{}{"QMP": map[string]interface{}{"capabilities": []string{}}}
+		if enc.Encode(greeting) != nil {
+			return
+		}
+
+		var handshake command
+		if dec.Decode(&handshake) != nil || enc.Encode(map[string]interface{}{"return": map[string]string{}}) != nil {
+			return
+		}
+
+		// Only reply once all commands have been received, which requires them to be pipelined.
+		cmds := make([]command, 0, count)
+		for len(cmds) < count {
+			var cmd command
+			if dec.Decode(&cmd) != nil {
+				return
+			}
+
+			cmds = append(cmds, cmd)
+		}
+
+		enc.Encode(map[string]interface{}{"event": "RESUME", "data": map[string]string{}})
+
+		for i := len(cmds) - 1; i >= 0; i-- {
+			if cmds[i].Execute == failing {
+				enc.Encode(map[string]interface{}{"error": map[string]string{"class": "GenericError", "desc": "Failed"}, "id": cmds[i].ID})
+				continue
+			}
+
+			enc.Encode(map[string]interface{}{"return": cmds[i].Execute, "id": cmds[i].ID})
+		}
+
+		// Answer any further commands (such as the ping after a failure).
+		for {
+			var cmd command
+			if dec.Decode(&cmd) != nil {
+				return
+			}
+
+			enc.Encode(map[string]interface{}{"return": map[string]string{}, "id": cmd.ID})
+		}
+	}()
+}
+
+// RunBatch pipelines the commands and matches the replies to them by ID, whatever order they arrive in.
+func TestRunBatch(t *testing.T) {
+	commands := []Command{{Execute: "query-a"}, {Execute: "query-b"}, {Execute: "query-c"}}
+
+	tests := []struct {
+		name    string
+		failing string
+		results []Result
+	}{
+		{
+			name: "success",
+			results: []Result{
+				{Execute: "query-a", Return: json.RawMessage(`"query-a"`)},
+				{Execute: "query-b", Return: json.RawMessage(`"query-b"`)},
+				{Execute: "query-c", Return: json.RawMessage(`"query-c"`)},
+			},
+		},
+		{
+			name:    "failure",
+			failing: "query-b",
+			results: []Result{
+				{Execute: "query-a", Return: json.RawMessage(`"query-a"`)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "lxd-qmp-test-")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "qmp.monitor")
+			fakeQMPBatchServer(t, path, len(commands), test.failing)
+
+			monitor, err := Connect(path, "", nil)
+			require.NoError(t, err)
+			defer monitor.Disconnect()
+
+			results, err := monitor.RunBatch(commands)
+			if test.failing != "" {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.results, results)
+		})
+	}
+}
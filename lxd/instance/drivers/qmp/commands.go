@@ -1,12 +1,14 @@
 package qmp
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
 
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/shared"
@@ -30,6 +32,78 @@ func (m *Monitor) Status() (string, error) {
 	return resp.Return.Status, nil
 }
 
+// Version represents the version of QEMU.
+type Version struct {
+	Major   int
+	Minor   int
+	Micro   int
+	Package string
+}
+
+// AtLeast returns whether the version is the same or newer than the given one.
+func (v Version) AtLeast(major int, minor int, micro int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+
+	return v.Micro >= micro
+}
+
+// QueryVersion returns the version of QEMU.
+func (m *Monitor) QueryVersion() (*Version, error) {
+	// Prepare the response.
+	var resp struct {
+		Return struct {
+			QEMU struct {
+				Major int `json:"major"`
+				Minor int `json:"minor"`
+				Micro int `json:"micro"`
+			} `json:"qemu"`
+			Package string `json:"package"`
+		} `json:"return"`
+	}
+
+	err := m.run("query-version", nil, &resp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed querying QEMU version")
+	}
+
+	version := &Version{
+		Major:   resp.Return.QEMU.Major,
+		Minor:   resp.Return.QEMU.Minor,
+		Micro:   resp.Return.QEMU.Micro,
+		Package: strings.TrimSpace(resp.Return.Package),
+	}
+
+	return version, nil
+}
+
+// QueryCommands returns the names of the QMP commands supported by QEMU.
+func (m *Monitor) QueryCommands() ([]string, error) {
+	// Prepare the response.
+	var resp struct {
+		Return []struct {
+			Name string `json:"name"`
+		} `json:"return"`
+	}
+
+	err := m.run("query-commands", nil, &resp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed querying QMP commands")
+	}
+
+	commands := make([]string, 0, len(resp.Return))
+	for _, command := range resp.Return {
+		commands = append(commands, command.Name)
+	}
+
+	return commands, nil
+}
+
 // Console fetches the File for a particular console.
 func (m *Monitor) Console(target string) (*os.File, error) {
 	// Prepare the response.
@@ -68,15 +142,100 @@ func (m *Monitor) Console(target string) (*os.File, error) {
 	return nil, ErrMonitorBadConsole
 }
 
+// DisplayInfo represents the listener of a remote display (VNC or SPICE).
+type DisplayInfo struct {
+	Host    string
+	Port    string
+	TLSPort string
+	Auth    string
+}
+
+// QueryVNC returns the VNC listener of the VM.
+func (m *Monitor) QueryVNC() (*DisplayInfo, error) {
+	// Prepare the response.
+	var resp struct {
+		Return struct {
+			Enabled bool   `json:"enabled"`
+			Host    string `json:"host"`
+			Service string `json:"service"`
+			Auth    string `json:"auth"`
+		} `json:"return"`
+	}
+
+	err := m.run("query-vnc", nil, &resp)
+	if err != nil {
+		if strings.Contains(err.Error(), "has not been found") {
+			return nil, ErrMonitorDisplayNotEnabled
+		}
+
+		return nil, errors.Wrapf(err, "Failed querying VNC")
+	}
+
+	if !resp.Return.Enabled {
+		return nil, ErrMonitorDisplayNotEnabled
+	}
+
+	return &DisplayInfo{Host: resp.Return.Host, Port: resp.Return.Service, Auth: resp.Return.Auth}, nil
+}
+
+// QuerySPICE returns the SPICE listener of the VM.
+func (m *Monitor) QuerySPICE() (*DisplayInfo, error) {
+	// Prepare the response.
+	var resp struct {
+		Return struct {
+			Enabled bool   `json:"enabled"`
+			Host    string `json:"host"`
+			Port    int    `json:"port"`
+			TLSPort int    `json:"tls-port"`
+			Auth    string `json:"auth"`
+		} `json:"return"`
+	}
+
+	err := m.run("query-spice", nil, &resp)
+	if err != nil {
+		// QEMU may be built without SPICE support.
+		if strings.Contains(err.Error(), "has not been found") {
+			return nil, ErrMonitorDisplayNotEnabled
+		}
+
+		return nil, errors.Wrapf(err, "Failed querying SPICE")
+	}
+
+	if !resp.Return.Enabled {
+		return nil, ErrMonitorDisplayNotEnabled
+	}
+
+	info := &DisplayInfo{Host: resp.Return.Host, Auth: resp.Return.Auth}
+	if resp.Return.Port > 0 {
+		info.Port = fmt.Sprintf("%d", resp.Return.Port)
+	}
+
+	if resp.Return.TLSPort > 0 {
+		info.TLSPort = fmt.Sprintf("%d", resp.Return.TLSPort)
+	}
+
+	return info, nil
+}
+
 // SendFile adds a new file descriptor to the QMP fd table associated to name.
+// The file is duplicated before being sent so the caller keeps ownership of (and must close) the file it passed,
+// whether or not the call succeeded.
 func (m *Monitor) SendFile(name string, file *os.File) error {
 	// Check if disconnected
 	if m.disconnected {
 		return ErrMonitorDisconnect
 	}
 
+	fd, err := unix.Dup(int(file.Fd()))
+	if err != nil {
+		return errors.Wrapf(err, "Failed duplicating file descriptor")
+	}
+
+	sendFile := os.NewFile(uintptr(fd), file.Name())
+	defer sendFile.Close()
+
 	// Query the status.
-	_, err := m.qmp.RunWithFile([]byte(fmt.Sprintf("{'execute': 'getfd', 'arguments': {'fdname': '%s'}}", name)), file)
+	_, err = m.qmp.RunWithFile("getfd", map[string]string{"fdname": name}, sendFile)
 	if err != nil {
 		// Confirm the daemon didn't die.
 		errPing := m.ping()
@@ -91,7 +250,8 @@ func (m *Monitor) SendFile(name string, file *os.File) error {
 }
 
 // Migrate starts a migration stream.
-func (m *Monitor) Migrate(uri string) error {
+// If the context is cancelled before the migration completes, the migration is cancelled and ctx.Err() returned.
+func (m *Monitor) Migrate(ctx context.Context, uri string) error {
 	// Query the status.
 	args := map[string]string{"uri": uri}
 	err := m.run("migrate", args, nil)
@@ -100,8 +260,41 @@ func (m *Monitor) Migrate(uri string) error {
 	}
 
 	// Wait until it completes or fails.
+	err = m.waitMigration(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			m.run("migrate_cancel", nil, nil)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// MigrateIncoming starts the receiver of a migration stream.
+// If the context is cancelled before the migration completes, ctx.Err() is returned. QEMU has no way to cancel an
+// incoming migration so the caller is then expected to stop the VM.
+func (m *Monitor) MigrateIncoming(ctx context.Context, uri string) error {
+	// Query the status.
+	args := map[string]string{"uri": uri}
+	err := m.run("migrate-incoming", args, nil)
+	if err != nil {
+		return err
+	}
+
+	// Wait until it completes or fails.
+	return m.waitMigration(ctx)
+}
+
+// waitMigration polls the migration status until it completes, fails or the context is cancelled.
+func (m *Monitor) waitMigration(ctx context.Context) error {
 	for {
-		time.Sleep(1 * time.Second)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(1 * time.Second):
+		}
 
 		// Prepare the response.
 		var resp struct {
@@ -120,48 +313,86 @@ func (m *Monitor) Migrate(uri string) error {
 		}
 
 		if resp.Return.Status == "completed" {
-			break
+			return nil
 		}
 	}
+}
 
-	return nil
+// DumpStatus represents the progress of a guest memory dump.
+type DumpStatus struct {
+	Status    string `json:"status"`
+	Completed int64  `json:"completed"`
+	Total     int64  `json:"total"`
 }
 
-// MigrateIncoming starts the receiver of a migration stream.
-func (m *Monitor) MigrateIncoming(uri string) error {
-	// Query the status.
-	args := map[string]string{"uri": uri}
-	err := m.run("migrate-incoming", args, nil)
+// QueryDump returns the progress of the current (or last) guest memory dump.
+func (m *Monitor) QueryDump() (*DumpStatus, error) {
+	// Prepare the response.
+	var resp struct {
+		Return DumpStatus `json:"return"`
+	}
+
+	err := m.run("query-dump", nil, &resp)
 	if err != nil {
-		return err
+		return nil, errors.Wrapf(err, "Failed querying memory dump")
+	}
+
+	return &resp.Return, nil
+}
+
+// DumpGuestMemory dumps the guest memory to path in the given format (elf, kdump-zlib, kdump-lzo or kdump-snappy)
+// and waits for it to complete. The file is opened here and passed to QEMU so that it doesn't need to be writable
+// by the (possibly unprivileged) QEMU process. QEMU can't cancel a dump, so a cancelled context only stops waiting.
+func (m *Monitor) DumpGuestMemory(ctx context.Context, path string, paging bool, format string) (*DumpStatus, error) {
+	if !shared.StringInSlice(format, []string{"elf", "kdump-zlib", "kdump-lzo", "kdump-snappy"}) {
+		return nil, fmt.Errorf("Unsupported memory dump format %q", format)
+	}
+
+	dumpFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed opening memory dump file %q", path)
+	}
+	defer dumpFile.Close()
+
+	// Send the target file to qemu.
+	err = m.SendFile("dump", dumpFile)
+	if err != nil {
+		return nil, err
+	}
+
+	args := map[string]interface{}{
+		"paging":   paging,
+		"protocol": "fd:dump",
+		"detach":   true,
+		"format":   format,
+	}
+
+	err = m.run("dump-guest-memory", args, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed starting memory dump")
 	}
 
 	// Wait until it completes or fails.
 	for {
-		time.Sleep(1 * time.Second)
-
-		// Preapre the response.
-		var resp struct {
-			Return struct {
-				Status string `json:"status"`
-			} `json:"return"`
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(1 * time.Second):
 		}
 
-		err := m.run("query-migrate", nil, &resp)
+		status, err := m.QueryDump()
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		if resp.Return.Status == "failed" {
-			return fmt.Errorf("Migration call failed")
+		if status.Status == "failed" {
+			return status, fmt.Errorf("Memory dump failed")
 		}
 
-		if resp.Return.Status == "completed" {
-			break
+		if status.Status == "completed" {
+			return status, nil
 		}
 	}
-
-	return nil
 }
 
 // Powerdown tells the VM to gracefully shutdown.
@@ -209,6 +440,40 @@ func (m *Monitor) GetCPUs() ([]int, error) {
 	return pids, nil
 }
 
+// GetRTCDelta returns the difference between the guest's RTC and the host's clock (positive if the guest is ahead).
+// The RTC only has a one second resolution and is expected to run in UTC.
+func (m *Monitor) GetRTCDelta() (time.Duration, error) {
+	// Prepare the response.
+	var resp struct {
+		Return struct {
+			Year   int `json:"tm_year"`
+			Month  int `json:"tm_mon"`
+			Day    int `json:"tm_mday"`
+			Hour   int `json:"tm_hour"`
+			Minute int `json:"tm_min"`
+			Second int `json:"tm_sec"`
+		} `json:"return"`
+	}
+
+	args := map[string]string{
+		"path":     "/machine",
+		"property": "rtc-time",
+	}
+
+	err := m.run("qom-get", args, &resp)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed querying RTC time")
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// The fields follow struct tm (years since 1900 and zero based months).
+	rtc := resp.Return
+	guestTime := time.Date(rtc.Year+1900, time.Month(rtc.Month+1), rtc.Day, rtc.Hour, rtc.Minute, rtc.Second, 0, time.UTC)
+
+	return guestTime.Sub(now), nil
+}
+
 // GetMemorySizeBytes returns the current size of the base memory in bytes.
 func (m *Monitor) GetMemorySizeBytes() (int64, error) {
 	// Prepare the response.
@@ -315,6 +580,67 @@ func (m *Monitor) RemoveNIC(netDevID string, deviceID string) error {
 	return nil
 }
 
+// AddVsock adds a vhost-vsock-pci device with the given context ID, optionally on a specific PCI bus and address.
+// The vhost device is opened here and passed to QEMU as it may have dropped the privileges needed to open it itself.
+func (m *Monitor) AddVsock(cid uint32, bus string, addr string) error {
+	revert := revert.New()
+	defer revert.Fail()
+
+	vhostFile, err := os.OpenFile("/dev/vhost-vsock", os.O_RDWR, 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed opening vsock vhost device")
+	}
+	defer vhostFile.Close()
+
+	err = m.SendFile("qemu_vsock", vhostFile)
+	if err != nil {
+		return errors.Wrapf(err, "Failed sending vsock vhost device")
+	}
+
+	revert.Add(func() {
+		args := map[string]string{"fdname": "qemu_vsock"}
+
+		err = m.run("closefd", args, nil)
+		if err != nil {
+			return
+		}
+	})
+
+	device := map[string]string{
+		"driver":    "vhost-vsock-pci",
+		"id":        "qemu_vsock",
+		"guest-cid": fmt.Sprintf("%d", cid),
+		"vhostfd":   "qemu_vsock",
+	}
+
+	if bus != "" {
+		device["bus"] = bus
+		device["addr"] = addr
+	}
+
+	err = m.run("device_add", device, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding vsock device")
+	}
+
+	revert.Success()
+	return nil
+}
+
+// RemoveVsock removes the vsock device.
+func (m *Monitor) RemoveVsock() error {
+	deviceID := map[string]string{
+		"id": "qemu_vsock",
+	}
+
+	err := m.run("device_del", deviceID, nil)
+	if err != nil && !strings.Contains(err.Error(), "not found") {
+		return errors.Wrapf(err, "Failed removing vsock device")
+	}
+
+	return nil
+}
+
 // Reset VM.
 func (m *Monitor) Reset() error {
 	err := m.run("system_reset", nil, nil)
@@ -0,0 +1,113 @@
+package qmp
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQMPServer accepts a single monitor connection and answers every command with an empty return, except for
+// getfd which fails when failGetfd is set.
+func fakeQMPServer(t *testing.T, path string, failGetfd bool) {
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	go func() {
+		defer listener.Close()
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		enc := json.NewEncoder(conn)
+		dec := json.NewDecoder(quoteReader{conn})
+
+		greeting := map[string]interface{}{"QMP": map[string]interface{}{"capabilities": []string{"oob"}}}
+		if enc.Encode(greeting) != nil {
+			return
+		}
+
+		for {
+			var cmd struct {
+				Execute string `json:"execute"`
+				ID      string `json:"id"`
+			}
+
+			if dec.Decode(&cmd) != nil {
+				return
+			}
+
+			if cmd.Execute == "getfd" && failGetfd {
+				enc.Encode(map[string]interface{}{"error": map[string]string{"class": "GenericError", "desc": "Failed"}, "id": cmd.ID})
+				continue
+			}
+
+			enc.Encode(map[string]interface{}{"return": map[string]string{}, "id": cmd.ID})
+		}
+	}()
+}
+
+// quoteReader turns the single quoted strings that QEMU accepts into valid JSON.
+type quoteReader struct {
+	r io.Reader
+}
+
+func (q quoteReader) Read(p []byte) (int, error) {
+	n, err := q.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\'' {
+			p[i] = '"'
+		}
+	}
+
+	return n, err
+}
+
+func openFDs(t *testing.T) int {
+	entries, err := ioutil.ReadDir("/proc/self/fd")
+	require.NoError(t, err)
+
+	return len(entries)
+}
+
+// SendFile never leaves file descriptors behind, the caller only has to close its own file.
+func TestSendFile_NoLeak(t *testing.T) {
+	for _, failGetfd := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "lxd-qmp-test-")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "qmp.monitor")
+		fakeQMPServer(t, path, failGetfd)
+
+		monitor, err := Connect(path, "", nil)
+		require.NoError(t, err)
+
+		file, err := os.Open("/dev/null")
+		require.NoError(t, err)
+
+		before := openFDs(t)
+		err = monitor.SendFile("test", file)
+		after := openFDs(t)
+
+		if failGetfd {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, before, after)
+
+		file.Close()
+		monitor.Disconnect()
+	}
+}
@@ -12,3 +12,6 @@ var ErrMonitorBadReturn = fmt.Errorf("Monitor returned invalid data")
 
 // ErrMonitorBadConsole is retuned when the requested console doesn't exist.
 var ErrMonitorBadConsole = fmt.Errorf("Requested console couldn't be found")
+
+// ErrMonitorDisplayNotEnabled is returned when the requested remote display (VNC or SPICE) isn't configured.
+var ErrMonitorDisplayNotEnabled = fmt.Errorf("Requested display isn't enabled")
@@ -1,15 +1,14 @@
 package qmp
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/digitalocean/go-qemu/qmp"
-
 	"github.com/lxc/lxd/shared/logger"
 )
 
@@ -22,15 +21,21 @@ var RingbufSize = 16
 // Monitor represents a QMP monitor.
 type Monitor struct {
 	path string
-	qmp  *qmp.SocketMonitor
+	qmp  *socketMonitor
 
 	agentReady    bool
 	disconnected  bool
 	chDisconnect  chan struct{}
 	eventHandler  func(name string, data map[string]interface{})
 	serialCharDev string
+
+	// QueryRetries is the number of times a query command is retried after a transient socket error.
+	QueryRetries int
 }
 
+// queryRetryDelay is the delay before the first retry of a query command, doubling on every further attempt.
+const queryRetryDelay = 100 * time.Millisecond
+
 // start handles the background goroutines for event handling and monitoring the ringbuffer
 func (m *Monitor) start() error {
 	// Ringbuffer monitoring function.
@@ -66,10 +71,7 @@ func (m *Monitor) start() error {
 	}
 
 	// Start event monitoring go routine.
-	chEvents, err := m.qmp.Events(context.Background())
-	if err != nil {
-		return err
-	}
+	chEvents := m.qmp.Events()
 
 	go func() {
 		// Initial read from the ringbuffer.
@@ -120,7 +122,7 @@ func (m *Monitor) ping() error {
 	}
 
 	// Query the capabilities to validate the monitor.
-	_, err := m.qmp.Run([]byte("{'execute': 'query-version'}"))
+	_, err := m.qmp.Run("query-version", nil)
 	if err != nil {
 		m.Disconnect()
 		return ErrMonitorDisconnect
@@ -137,20 +139,24 @@ func (m *Monitor) run(cmd string, args interface{}, resp interface{}) error {
 	}
 
 	// Run the command.
-	requestArgs := struct {
-		Execute   string      `json:"execute"`
-		Arguments interface{} `json:"arguments,omitempty"`
-	}{
-		Execute:   cmd,
-		Arguments: args,
-	}
+	out, err := m.qmp.Run(cmd, args)
 
-	request, err := json.Marshal(requestArgs)
-	if err != nil {
-		return err
-	}
+	// Retry query commands (which don't change any state) on transient socket errors.
+	if strings.HasPrefix(cmd, "query-") {
+		delay := queryRetryDelay
+		for attempt := 0; attempt < m.QueryRetries && isTransientError(err); attempt++ {
+			time.Sleep(delay)
+			delay *= 2
 
-	out, err := m.qmp.Run(request)
+			// Confirm the daemon didn't die.
+			errPing := m.ping()
+			if errPing != nil {
+				return errPing
+			}
+
+			out, err = m.qmp.Run(cmd, args)
+		}
+	}
 
 	if err != nil {
 		// Confirm the daemon didn't die.
@@ -179,6 +185,13 @@ func (m *Monitor) run(cmd string, args interface{}, resp interface{}) error {
 	return nil
 }
 
+// isTransientError returns whether the error comes from the socket rather than from QEMU rejecting the command.
+func isTransientError(err error) bool {
+	var netErr net.Error
+
+	return err != nil && errors.As(err, &netErr)
+}
+
 // Connect creates or retrieves an existing QMP monitor for the path.
 func Connect(path string, serialCharDev string, eventHandler func(name string, data map[string]interface{})) (*Monitor, error) {
 	monitorsLock.Lock()
@@ -192,24 +205,34 @@ func Connect(path string, serialCharDev string, eventHandler func(name string, d
 	}
 
 	// Setup the connection.
-	qmpConn, err := qmp.NewSocketMonitor("unix", path, time.Second)
-	if err != nil {
-		return nil, err
+	type connectResult struct {
+		qmpConn *socketMonitor
+		err     error
 	}
 
-	chError := make(chan error, 1)
+	chResult := make(chan connectResult, 1)
 	go func() {
-		err = qmpConn.Connect()
-		chError <- err
+		qmpConn, err := socketConnect(path)
+		chResult <- connectResult{qmpConn: qmpConn, err: err}
 	}()
 
+	var qmpConn *socketMonitor
 	select {
-	case err := <-chError:
-		if err != nil {
-			return nil, err
+	case result := <-chResult:
+		if result.err != nil {
+			return nil, result.err
 		}
+
+		qmpConn = result.qmpConn
 	case <-time.After(5 * time.Second):
-		qmpConn.Disconnect()
+		// Close the connection once the handshake completes (if ever).
+		go func() {
+			result := <-chResult
+			if result.err == nil {
+				result.qmpConn.Disconnect()
+			}
+		}()
+
 		return nil, fmt.Errorf("QMP connection timed out")
 	}
 
@@ -220,9 +243,10 @@ func Connect(path string, serialCharDev string, eventHandler func(name string, d
 	monitor.chDisconnect = make(chan struct{}, 1)
 	monitor.eventHandler = eventHandler
 	monitor.serialCharDev = serialCharDev
+	monitor.QueryRetries = 3
 
 	// Spawn goroutines.
-	err = monitor.start()
+	err := monitor.start()
 	if err != nil {
 		return nil, err
 	}
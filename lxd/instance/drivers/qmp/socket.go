@@ -0,0 +1,333 @@
+package qmp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-qemu/qmp"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// socketMonitor is a connection to a QMP socket which tags every command with an ID and matches the replies
+// to it, so that multiple commands can be in flight at the same time (see Monitor.RunBatch).
+type socketMonitor struct {
+	conn         net.Conn
+	capabilities []string
+
+	// Serializes writes to the socket.
+	writeLock sync.Mutex
+
+	// Commands waiting for a reply, keyed by ID, along with the order in which they were sent.
+	pendingLock sync.Mutex
+	pending     map[string]chan socketReply
+	pendingIDs  []string
+	nextID      uint64
+	err         error // Set once reading from the socket failed.
+
+	events chan qmp.Event
+	done   chan struct{}
+}
+
+// socketReply is the raw reply to a command (or the error that prevented receiving it).
+type socketReply struct {
+	buf []byte
+	err error
+}
+
+// socketMessage is used to tell replies and events apart, and to check replies for errors.
+type socketMessage struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Error *struct {
+		Class string `json:"class"`
+		Desc  string `json:"desc"`
+	} `json:"error"`
+}
+
+// socketConnect connects to the QMP socket at path and performs the capabilities handshake.
+func socketConnect(path string) (*socketMonitor, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+
+	// Read the greeting.
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var greeting struct {
+		QMP struct {
+			Capabilities []string `json:"capabilities"`
+		} `json:"QMP"`
+	}
+
+	err = json.Unmarshal(line, &greeting)
+	if err != nil {
+		conn.Close()
+		return nil, ErrMonitorBadReturn
+	}
+
+	// Leave negotiation mode.
+	_, err = conn.Write([]byte("{\"execute\": \"qmp_capabilities\"}\n"))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	line, err = reader.ReadBytes('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var msg socketMessage
+	err = json.Unmarshal(line, &msg)
+	if err != nil {
+		conn.Close()
+		return nil, ErrMonitorBadReturn
+	}
+
+	if msg.Error != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s", msg.Error.Desc)
+	}
+
+	s := &socketMonitor{
+		conn:         conn,
+		capabilities: greeting.QMP.Capabilities,
+		pending:      map[string]chan socketReply{},
+		events:       make(chan qmp.Event),
+		done:         make(chan struct{}),
+	}
+
+	go s.listen(reader)
+
+	return s, nil
+}
+
+// listen reads the replies and events from the socket until it fails, delivering the replies to the commands
+// waiting for them and the events to the events channel.
+func (s *socketMonitor) listen(reader *bufio.Reader) {
+	defer close(s.events)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			s.fail(err)
+			return
+		}
+
+		var msg socketMessage
+		err = json.Unmarshal(line, &msg)
+		if err != nil {
+			continue
+		}
+
+		if msg.Event != "" {
+			var event qmp.Event
+			err = json.Unmarshal(line, &event)
+			if err != nil {
+				continue
+			}
+
+			select {
+			case s.events <- event:
+			case <-s.done:
+				return
+			}
+
+			continue
+		}
+
+		reply := socketReply{buf: line}
+		if msg.Error != nil {
+			reply.err = fmt.Errorf("%s", msg.Error.Desc)
+		}
+
+		s.pendingLock.Lock()
+
+		// QEMU only omits the ID if it couldn't parse the command, in which case the reply is for the oldest
+		// command as they are processed in order.
+		id := msg.ID
+		if id == "" && len(s.pendingIDs) > 0 {
+			id = s.pendingIDs[0]
+		}
+
+		chReply, ok := s.pending[id]
+		s.forget(id)
+		s.pendingLock.Unlock()
+
+		if ok {
+			chReply <- reply
+		}
+	}
+}
+
+// fail records the error that stopped the socket and delivers it to all the commands waiting for a reply.
+func (s *socketMonitor) fail(err error) {
+	s.pendingLock.Lock()
+	defer s.pendingLock.Unlock()
+
+	s.err = err
+	for _, chReply := range s.pending {
+		chReply <- socketReply{err: err}
+	}
+
+	s.pending = map[string]chan socketReply{}
+	s.pendingIDs = nil
+}
+
+// forget stops waiting for the reply to the command with the given ID. Must be called with pendingLock held.
+func (s *socketMonitor) forget(id string) {
+	delete(s.pending, id)
+
+	for i, pendingID := range s.pendingIDs {
+		if pendingID == id {
+			s.pendingIDs = append(s.pendingIDs[:i], s.pendingIDs[i+1:]...)
+			break
+		}
+	}
+}
+
+// forgetAll stops waiting for the replies to the commands with the given IDs (after failing to send them).
+func (s *socketMonitor) forgetAll(ids []string) {
+	s.pendingLock.Lock()
+	defer s.pendingLock.Unlock()
+
+	for _, id := range ids {
+		s.forget(id)
+	}
+}
+
+// encode returns the command tagged with a new ID, and registers the channel its reply will be delivered on.
+func (s *socketMonitor) encode(execute string, args interface{}) (string, []byte, chan socketReply, error) {
+	s.pendingLock.Lock()
+	defer s.pendingLock.Unlock()
+
+	if s.err != nil {
+		return "", nil, nil, s.err
+	}
+
+	s.nextID++
+	id := fmt.Sprintf("lxd-%d", s.nextID)
+
+	request, err := json.Marshal(struct {
+		Execute   string      `json:"execute"`
+		Arguments interface{} `json:"arguments,omitempty"`
+		ID        string      `json:"id"`
+	}{
+		Execute:   execute,
+		Arguments: args,
+		ID:        id,
+	})
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	// Buffered so that the listener never blocks on a command that stopped waiting.
+	chReply := make(chan socketReply, 1)
+	s.pending[id] = chReply
+	s.pendingIDs = append(s.pendingIDs, id)
+
+	return id, append(request, '\n'), chReply, nil
+}
+
+// RunBatch sends all the commands before waiting for any of their replies, and returns the replies in the order
+// of the commands. It stops at the first failed command, returning the replies received before it.
+func (s *socketMonitor) RunBatch(commands []Command) ([][]byte, error) {
+	ids := make([]string, 0, len(commands))
+	requests := make([]byte, 0)
+	chReplies := make([]chan socketReply, 0, len(commands))
+	for _, command := range commands {
+		id, request, chReply, err := s.encode(command.Execute, command.Arguments)
+		if err != nil {
+			s.forgetAll(ids)
+			return nil, err
+		}
+
+		ids = append(ids, id)
+		requests = append(requests, request...)
+		chReplies = append(chReplies, chReply)
+	}
+
+	// Send all the commands at once, QEMU processes them in order.
+	s.writeLock.Lock()
+	_, err := s.conn.Write(requests)
+	s.writeLock.Unlock()
+	if err != nil {
+		s.forgetAll(ids)
+		return nil, err
+	}
+
+	replies := make([][]byte, 0, len(commands))
+	for i, chReply := range chReplies {
+		reply := <-chReply
+		if reply.err != nil {
+			return replies, fmt.Errorf("Failed running %q: %w", commands[i].Execute, reply.err)
+		}
+
+		replies = append(replies, reply.buf)
+	}
+
+	return replies, nil
+}
+
+// Run runs a single command and returns its raw reply.
+func (s *socketMonitor) Run(execute string, args interface{}) ([]byte, error) {
+	return s.RunWithFile(execute, args, nil)
+}
+
+// RunWithFile behaves like Run but passes a file descriptor along with the command (if file is not nil).
+func (s *socketMonitor) RunWithFile(execute string, args interface{}, file *os.File) ([]byte, error) {
+	if file != nil && !shared.StringInSlice("oob", s.capabilities) {
+		return nil, fmt.Errorf("The QEMU server doesn't support oob (needed for RunWithFile)")
+	}
+
+	id, request, chReply, err := s.encode(execute, args)
+	if err != nil {
+		return nil, err
+	}
+
+	s.writeLock.Lock()
+	if file == nil {
+		_, err = s.conn.Write(request)
+	} else {
+		_, _, err = s.conn.(*net.UnixConn).WriteMsgUnix(request, unix.UnixRights(int(file.Fd())), nil)
+	}
+	s.writeLock.Unlock()
+
+	if err != nil {
+		s.forgetAll([]string{id})
+		return nil, err
+	}
+
+	reply := <-chReply
+	if reply.err != nil {
+		return nil, reply.err
+	}
+
+	return reply.buf, nil
+}
+
+// Events returns the channel the QMP events are delivered on. It is closed when the socket fails.
+func (s *socketMonitor) Events() <-chan qmp.Event {
+	return s.events
+}
+
+// Disconnect closes the socket.
+func (s *socketMonitor) Disconnect() {
+	close(s.done)
+	s.conn.Close()
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
+	"github.com/lxc/lxd/lxd/backup"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
@@ -605,16 +606,18 @@ func instanceBackupExportGet(d *Daemon, r *http.Request) response.Response {
 	}
 
 	fullName := name + shared.SnapshotDelimiter + backupName
-	backup, err := instance.BackupLoadByName(d.State(), projectName, fullName)
+	b, err := instance.BackupLoadByName(d.State(), projectName, fullName)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	backupPath := shared.VarPath("backups", "instances", project.Instance(projectName, b.Name()))
 	ent := response.FileResponseEntry{
-		Path: shared.VarPath("backups", "instances", project.Instance(projectName, backup.Name())),
+		Path:   backupPath,
+		SHA256: backup.ReadDigest(backupPath),
 	}
 
-	d.State().Events.SendLifecycle(projectName, lifecycle.InstanceBackupRetrieved.Event(name, backup.Instance(), nil))
+	d.State().Events.SendLifecycle(projectName, lifecycle.InstanceBackupRetrieved.Event(name, b.Instance(), nil))
 
 	return response.FileResponse(r, []response.FileResponseEntry{ent}, nil, false)
 }
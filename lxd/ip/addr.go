@@ -10,11 +10,15 @@ type Addr struct {
 	Address string
 	Scope   string
 	Family  string
+	Flags   []string
 }
 
 // Add adds new protocol address
 func (a *Addr) Add() error {
-	_, err := shared.RunCommand("ip", a.Family, "addr", "add", "dev", a.DevName, a.Address)
+	cmd := []string{a.Family, "addr", "add", "dev", a.DevName, a.Address}
+	cmd = append(cmd, a.Flags...)
+
+	_, err := shared.RunCommand("ip", cmd...)
 	if err != nil {
 		return err
 	}
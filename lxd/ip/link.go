@@ -352,6 +352,75 @@ func (l *Link) BridgeVLANDelete(vid string, self bool, master bool) error {
 	return nil
 }
 
+// BridgeVLAN represents a vlan filter entry of a bridge port
+type BridgeVLAN struct {
+	VID      uint64
+	PVID     bool
+	Untagged bool
+}
+
+// BridgeVLANs returns the vlan filter entries of the link device
+func (l *Link) BridgeVLANs() ([]BridgeVLAN, error) {
+	output, err := shared.RunCommand("bridge", "-j", "vlan", "show", "dev", l.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	type vlanEntry struct {
+		VLAN    uint64   `json:"vlan"`
+		VLANEnd uint64   `json:"vlanEnd"`
+		Flags   []string `json:"flags"`
+	}
+
+	type vlanPort struct {
+		IfName string      `json:"ifname"`
+		VLANs  []vlanEntry `json:"vlans"`
+	}
+
+	var ports []vlanPort
+
+	err = json.Unmarshal([]byte(output), &ports)
+	if err != nil {
+		// Older versions of the bridge tool output an object keyed by interface name.
+		var oldPorts map[string][]vlanEntry
+
+		oldErr := json.Unmarshal([]byte(output), &oldPorts)
+		if oldErr != nil {
+			return nil, fmt.Errorf("Failed parsing bridge vlan output: %w", err)
+		}
+
+		ports = []vlanPort{}
+		for ifName, vlans := range oldPorts {
+			ports = append(ports, vlanPort{IfName: ifName, VLANs: vlans})
+		}
+	}
+
+	vlans := []BridgeVLAN{}
+	for _, port := range ports {
+		if port.IfName != l.Name {
+			continue
+		}
+
+		for _, entry := range port.VLANs {
+			// Expand VLAN ranges into separate entries.
+			end := entry.VLANEnd
+			if end < entry.VLAN {
+				end = entry.VLAN
+			}
+
+			for vid := entry.VLAN; vid <= end; vid++ {
+				vlans = append(vlans, BridgeVLAN{
+					VID:      vid,
+					PVID:     shared.StringInSlice("PVID", entry.Flags),
+					Untagged: shared.StringInSlice("Egress Untagged", entry.Flags),
+				})
+			}
+		}
+	}
+
+	return vlans, nil
+}
+
 // BridgeLinkSetIsolated sets bridge 'isolated' attribute on a port
 func (l *Link) BridgeLinkSetIsolated(isolated bool) error {
 	isolatedState := "on"
@@ -366,6 +435,20 @@ func (l *Link) BridgeLinkSetIsolated(isolated bool) error {
 	return nil
 }
 
+// BridgeLinkSetNeighSuppress sets bridge 'neigh_suppress' attribute on a port
+func (l *Link) BridgeLinkSetNeighSuppress(suppress bool) error {
+	suppressState := "on"
+	if suppress == false {
+		suppressState = "off"
+	}
+
+	_, err := shared.RunCommand("bridge", "link", "set", "dev", l.Name, "neigh_suppress", suppressState)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 // BridgeLinkSetHairpin sets bridge 'hairpin' attribute on a port
 func (l *Link) BridgeLinkSetHairpin(hairpin bool) error {
 	hairpinState := "on"
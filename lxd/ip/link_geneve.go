@@ -0,0 +1,27 @@
+package ip
+
+// Geneve represents arguments for link of type geneve
+type Geneve struct {
+	Link
+	ID      string
+	Remote  string
+	DstPort string
+	TTL     string
+}
+
+// additionalArgs generates geneve specific arguments
+func (geneve *Geneve) additionalArgs() []string {
+	args := []string{"id", geneve.ID, "remote", geneve.Remote}
+	if geneve.TTL != "" {
+		args = append(args, "ttl", geneve.TTL)
+	}
+	if geneve.DstPort != "" {
+		args = append(args, "dstport", geneve.DstPort)
+	}
+	return args
+}
+
+// Add adds new virtual link
+func (geneve *Geneve) Add() error {
+	return geneve.Link.add("geneve", geneve.additionalArgs())
+}
@@ -0,0 +1,32 @@
+package ip
+
+import (
+	"github.com/lxc/lxd/shared"
+)
+
+// Rule represents arguments for policy routing rule manipulation
+type Rule struct {
+	Family string
+	From   string
+	Table  string
+}
+
+// Add adds new rule
+func (r *Rule) Add() error {
+	_, err := shared.RunCommand("ip", r.Family, "rule", "add", "from", r.From, "lookup", r.Table)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Delete deletes rule
+func (r *Rule) Delete() error {
+	_, err := shared.RunCommand("ip", r.Family, "rule", "delete", "from", r.From, "lookup", r.Table)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
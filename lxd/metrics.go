@@ -6,10 +6,14 @@ import (
 
 	log "gopkg.in/inconshreveable/log15.v2"
 
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/metrics"
+	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
 )
 
@@ -112,6 +116,17 @@ func metricsGet(d *Daemon, r *http.Request) response.Response {
 		metrics.Merge(instanceMetrics)
 	}
 
+	// Add the built-in DNS server metrics (not tied to any project).
+	if projectName == "" {
+		metrics.Merge(d.dns.Metrics())
+	}
+
+	// Add the DHCP lease usage of the managed bridges.
+	metrics.Merge(networkDHCPMetrics(d, projectName))
+
+	// Add the forkdns peer list churn of the managed bridges.
+	metrics.Merge(networkForkdnsMetrics(projectName))
+
 	metricsStr := metrics.String()
 
 	// Store freshly built metrics in cache.
@@ -123,3 +138,75 @@ func metricsGet(d *Daemon, r *http.Request) response.Response {
 
 	return response.SyncResponsePlain(true, metricsStr)
 }
+
+// networkDHCPMetrics returns the DHCPv4 lease usage of the local managed networks that support it.
+// If projectName is not empty, only the networks in that project are considered.
+func networkDHCPMetrics(d *Daemon, projectName string) *metrics.MetricSet {
+	set := metrics.NewMetricSet(nil)
+
+	var projectNetworks map[string]map[int64]api.Network
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		projectNetworks, err = tx.GetCreatedNetworks()
+		return err
+	})
+	if err != nil {
+		logger.Warn("Failed to get networks for metrics", log.Ctx{"err": err})
+		return set
+	}
+
+	type dhcpLeaseUser interface {
+		DHCPv4LeaseUsage() (uint64, uint64, error)
+	}
+
+	for netProject, networks := range projectNetworks {
+		if projectName != "" && projectName != netProject {
+			continue
+		}
+
+		for _, netInfo := range networks {
+			n, err := network.LoadByName(d.State(), netProject, netInfo.Name)
+			if err != nil {
+				continue
+			}
+
+			leaseUser, ok := n.(dhcpLeaseUser)
+			if !ok {
+				continue
+			}
+
+			used, size, err := leaseUser.DHCPv4LeaseUsage()
+			if err != nil {
+				logger.Warn("Failed to get network DHCP lease usage", log.Ctx{"network": netInfo.Name, "project": netProject, "err": err})
+				continue
+			}
+
+			if size == 0 {
+				continue
+			}
+
+			labels := map[string]string{"name": netInfo.Name, "project": netProject}
+			set.AddSamples(metrics.NetworkDHCPLeasesUsed, metrics.Sample{Value: used, Labels: labels})
+			set.AddSamples(metrics.NetworkDHCPPoolSize, metrics.Sample{Value: size, Labels: labels})
+		}
+	}
+
+	return set
+}
+
+// networkForkdnsMetrics returns the number of forkdns peer list changes of the local bridge networks.
+// If projectName is not empty, the networks are only included for the default project (where bridges live).
+func networkForkdnsMetrics(projectName string) *metrics.MetricSet {
+	set := metrics.NewMetricSet(nil)
+
+	if projectName != "" && projectName != project.Default {
+		return set
+	}
+
+	for name, changes := range network.ForkdnsServersChanges() {
+		labels := map[string]string{"name": name, "project": project.Default}
+		set.AddSamples(metrics.NetworkForkdnsChangesTotal, metrics.Sample{Value: changes, Labels: labels})
+	}
+
+	return set
+}
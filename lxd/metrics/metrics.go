@@ -69,8 +69,9 @@ func (m *MetricSet) String() string {
 
 		metricTypeName := ""
 
-		// ProcsTotal is a gauge according to the OpenMetrics spec as its value can decrease.
-		if metricType == ProcsTotal {
+		// ProcsTotal and the DHCP lease usage are gauges according to the OpenMetrics spec as their values
+		// can decrease.
+		if metricType == ProcsTotal || metricType == NetworkDHCPLeasesUsed || metricType == NetworkDHCPPoolSize {
 			metricTypeName = "gauge"
 		} else if strings.HasSuffix(MetricNames[metricType], "_total") {
 			metricTypeName = "counter"
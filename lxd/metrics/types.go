@@ -26,6 +26,14 @@ const (
 	DiskWrittenBytesTotal
 	// DiskWritesCompletedTotal represents the completed writes for a disk
 	DiskWritesCompletedTotal
+	// DNSDeniedTotal represents the number of DNS requests denied by the zone access control
+	DNSDeniedTotal
+	// DNSQueriesTotal represents the number of DNS requests received by the built-in DNS server
+	DNSQueriesTotal
+	// DNSResponsesTotal represents the number of DNS responses sent by the built-in DNS server
+	DNSResponsesTotal
+	// DNSTransfersTotal represents the number of successful DNS zone transfers
+	DNSTransfersTotal
 	// FilesystemAvailBytes represents the available bytes on a filesystem
 	FilesystemAvailBytes
 	// FilesystemFreeBytes represents the free bytes on a filesystem
@@ -70,6 +78,12 @@ const (
 	MemoryUnevictableBytes
 	// MemoryWritebackBytes represents the amount of memory queued for syncing to disk
 	MemoryWritebackBytes
+	// NetworkDHCPLeasesUsed represents the number of addresses leased out from the DHCPv4 pool of a network
+	NetworkDHCPLeasesUsed
+	// NetworkDHCPPoolSize represents the size of the DHCPv4 pool of a network
+	NetworkDHCPPoolSize
+	// NetworkForkdnsChangesTotal represents the number of times the forkdns peer list of a network changed
+	NetworkForkdnsChangesTotal
 	// NetworkReceiveBytesTotal represents the amount of received bytes on a given interface
 	NetworkReceiveBytesTotal
 	// NetworkReceiveDropTotal represents the amount of received dropped bytes on a given interface
@@ -97,6 +111,10 @@ var MetricNames = map[MetricType]string{
 	DiskReadsCompletedTotal:     "lxd_disk_reads_completed_total",
 	DiskWrittenBytesTotal:       "lxd_disk_written_bytes_total",
 	DiskWritesCompletedTotal:    "lxd_disk_writes_completed_total",
+	DNSDeniedTotal:              "lxd_dns_denied_total",
+	DNSQueriesTotal:             "lxd_dns_queries_total",
+	DNSResponsesTotal:           "lxd_dns_responses_total",
+	DNSTransfersTotal:           "lxd_dns_transfers_total",
 	FilesystemAvailBytes:        "lxd_filesystem_avail_bytes",
 	FilesystemFreeBytes:         "lxd_filesystem_free_bytes",
 	FilesystemSizeBytes:         "lxd_filesystem_size_bytes",
@@ -119,6 +137,9 @@ var MetricNames = map[MetricType]string{
 	MemorySwapBytes:             "lxd_memory_Swap_bytes",
 	MemoryUnevictableBytes:      "lxd_memory_Unevictable_bytes",
 	MemoryWritebackBytes:        "lxd_memory_Writeback_bytes",
+	NetworkDHCPLeasesUsed:       "lxd_network_dhcp_leases_used",
+	NetworkDHCPPoolSize:         "lxd_network_dhcp_pool_size",
+	NetworkForkdnsChangesTotal:  "lxd_network_forkdns_changes_total",
 	NetworkReceiveBytesTotal:    "lxd_network_receive_bytes_total",
 	NetworkReceiveDropTotal:     "lxd_network_receive_drop_total",
 	NetworkReceiveErrsTotal:     "lxd_network_receive_errs_total",
@@ -137,6 +158,10 @@ var MetricHeaders = map[MetricType]string{
 	DiskReadsCompletedTotal:     "# HELP lxd_disk_reads_completed_total The total number of completed reads.",
 	DiskWrittenBytesTotal:       "# HELP lxd_disk_written_bytes_total The total number of bytes written.",
 	DiskWritesCompletedTotal:    "# HELP lxd_disk_writes_completed_total The total number of completed writes.",
+	DNSDeniedTotal:              "# HELP lxd_dns_denied_total The total number of DNS requests denied by zone access control.",
+	DNSQueriesTotal:             "# HELP lxd_dns_queries_total The total number of DNS requests received.",
+	DNSResponsesTotal:           "# HELP lxd_dns_responses_total The total number of DNS responses sent.",
+	DNSTransfersTotal:           "# HELP lxd_dns_transfers_total The total number of successful DNS zone transfers.",
 	FilesystemAvailBytes:        "# HELP lxd_filesystem_avail_bytes The number of available space in bytes.",
 	FilesystemFreeBytes:         "# HELP lxd_filesystem_free_bytes The number of free space in bytes.",
 	FilesystemSizeBytes:         "# HELP lxd_filesystem_size_bytes The size of the filesystem in bytes.",
@@ -159,6 +184,9 @@ var MetricHeaders = map[MetricType]string{
 	MemorySwapBytes:             "# HELP lxd_memory_Swap_bytes The amount of used swap memory.",
 	MemoryUnevictableBytes:      "# HELP lxd_memory_Unevictable_bytes The amount of unevictable memory.",
 	MemoryWritebackBytes:        "# HELP lxd_memory_Writeback_bytes The amount of memory queued for syncing to disk.",
+	NetworkDHCPLeasesUsed:       "# HELP lxd_network_dhcp_leases_used The number of addresses leased out from the DHCPv4 pool of a network.",
+	NetworkDHCPPoolSize:         "# HELP lxd_network_dhcp_pool_size The number of addresses in the DHCPv4 pool of a network.",
+	NetworkForkdnsChangesTotal:  "# HELP lxd_network_forkdns_changes_total The number of times the forkdns peer list of a network changed.",
 	NetworkReceiveBytesTotal:    "# HELP lxd_network_receive_bytes_total The amount of received bytes on a given interface.",
 	NetworkReceiveDropTotal:     "# HELP lxd_network_receive_drop_total The amount of received dropped bytes on a given interface.",
 	NetworkReceiveErrsTotal:     "# HELP lxd_network_receive_errs_total The amount of received errors on a given interface.",
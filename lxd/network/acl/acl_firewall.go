@@ -2,8 +2,13 @@ package acl
 
 import (
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
 
 	firewallDrivers "github.com/lxc/lxd/lxd/firewall/drivers"
 	"github.com/lxc/lxd/lxd/state"
@@ -14,15 +19,17 @@ import (
 )
 
 // FirewallApplyACLRules applies ACL rules to network firewall.
+// The network's ACLs are loaded from aclProjectName unless they are project qualified (see ParseName).
 func FirewallApplyACLRules(s *state.State, logger logger.Logger, aclProjectName string, aclNet NetworkACLUsage) error {
 	var dropRules []firewallDrivers.ACLRule
 	var rejectRules []firewallDrivers.ACLRule
 	var allowRules []firewallDrivers.ACLRule
 
 	// convertACLRules converts the ACL rules to Firewall ACL rules.
+	now := time.Now()
 	convertACLRules := func(direction string, logPrefix string, rules ...api.NetworkACLRule) error {
 		for ruleIndex, rule := range rules {
-			if rule.State == "disabled" {
+			if rule.State == "disabled" || !firewallRuleScheduleActive(rule.Schedule, now) {
 				continue
 			}
 
@@ -62,10 +69,11 @@ func FirewallApplyACLRules(s *state.State, logger logger.Logger, aclProjectName
 	logPrefix := aclNet.Name
 
 	// Load ACLs specified by network.
-	for _, aclName := range util.SplitNTrimSpace(aclNet.Config["security.acls"], ",", -1, true) {
-		_, aclInfo, err := s.Cluster.GetNetworkACL(aclProjectName, aclName)
+	for _, aclRef := range util.SplitNTrimSpace(aclNet.Config["security.acls"], ",", -1, true) {
+		projectName, aclName := ParseName(aclProjectName, aclRef)
+		_, aclInfo, err := s.Cluster.GetNetworkACL(projectName, aclName)
 		if err != nil {
-			return errors.Wrapf(err, "Failed loading ACL %q for network %q", aclName, aclNet.Name)
+			return errors.Wrapf(err, "Failed loading ACL %q for network %q", aclRef, aclNet.Name)
 		}
 
 		err = convertACLRules("ingress", logPrefix, aclInfo.Ingress...)
@@ -122,3 +130,211 @@ func firewallACLDefaults(netConfig map[string]string, direction string) (string,
 
 	return defaults[fmt.Sprintf("security.acls.default.%s.action", direction)], shared.IsTrue(defaults[fmt.Sprintf("security.acls.default.%s.logged", direction)])
 }
+
+// FirewallPacket represents the criteria of a packet to evaluate against a network's firewall ACL rules.
+type FirewallPacket struct {
+	Direction       string // Either "ingress" or "egress".
+	Source          net.IP
+	Destination     net.IP
+	Protocol        string // Either "tcp", "udp", "icmp4" or "icmp6" (optional).
+	SourcePort      uint64 // Only used for "tcp" and "udp" protocols.
+	DestinationPort uint64 // Only used for "tcp" and "udp" protocols.
+}
+
+// FirewallEvaluation represents the result of evaluating a packet against a network's firewall ACL rules.
+type FirewallEvaluation struct {
+	Allowed bool
+	Action  string
+	ACLName string              // Name of the ACL containing the matched rule (empty if default rule matched).
+	Rule    *api.NetworkACLRule // Matched rule (nil if default rule matched).
+}
+
+// FirewallEvaluateACLRules evaluates a packet against the network's ACL rules (in the same order as they are
+// applied by FirewallApplyACLRules) and the network's default rule for the packet's direction.
+// This only uses the ACL config in the database and doesn't check against the live firewall.
+func FirewallEvaluateACLRules(s *state.State, aclProjectName string, aclNet NetworkACLUsage, packet FirewallPacket) (*FirewallEvaluation, error) {
+	if !shared.StringInSlice(packet.Direction, []string{"ingress", "egress"}) {
+		return nil, fmt.Errorf("Invalid direction %q", packet.Direction)
+	}
+
+	var acls []*api.NetworkACL
+	for _, aclRef := range util.SplitNTrimSpace(aclNet.Config["security.acls"], ",", -1, true) {
+		projectName, aclName := ParseName(aclProjectName, aclRef)
+		_, aclInfo, err := s.Cluster.GetNetworkACL(projectName, aclName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed loading ACL %q for network %q", aclRef, aclNet.Name)
+		}
+
+		acls = append(acls, aclInfo)
+	}
+
+	// Rules are applied with all drop rules first, then reject rules and finally allow rules.
+	now := time.Now()
+	for _, action := range []string{"drop", "reject", "allow"} {
+		for _, aclInfo := range acls {
+			rules := aclInfo.Ingress
+			if packet.Direction == "egress" {
+				rules = aclInfo.Egress
+			}
+
+			for i := range rules {
+				rule := rules[i]
+				if rule.State == "disabled" || rule.Action != action || !firewallRuleScheduleActive(rule.Schedule, now) {
+					continue
+				}
+
+				if firewallRuleMatchesPacket(&rule, packet) {
+					return &FirewallEvaluation{
+						Allowed: action == "allow",
+						Action:  action,
+						ACLName: aclInfo.Name,
+						Rule:    &rule,
+					}, nil
+				}
+			}
+		}
+	}
+
+	// Fallback to the network's default rule.
+	defaultAction, _ := firewallACLDefaults(aclNet.Config, packet.Direction)
+
+	return &FirewallEvaluation{
+		Allowed: defaultAction == "allow",
+		Action:  defaultAction,
+	}, nil
+}
+
+// FirewallACLScheduleChanged returns whether any of the scheduled rules in the network's ACLs has become active
+// or inactive between the two times, meaning that FirewallApplyACLRules needs to be run again.
+func FirewallACLScheduleChanged(s *state.State, aclProjectName string, aclNet NetworkACLUsage, from time.Time, to time.Time) (bool, error) {
+	for _, aclRef := range util.SplitNTrimSpace(aclNet.Config["security.acls"], ",", -1, true) {
+		projectName, aclName := ParseName(aclProjectName, aclRef)
+		_, aclInfo, err := s.Cluster.GetNetworkACL(projectName, aclName)
+		if err != nil {
+			return false, errors.Wrapf(err, "Failed loading ACL %q for network %q", aclRef, aclNet.Name)
+		}
+
+		for _, rules := range [][]api.NetworkACLRule{aclInfo.Ingress, aclInfo.Egress} {
+			for _, rule := range rules {
+				if rule.Schedule == "" || rule.State == "disabled" {
+					continue
+				}
+
+				if firewallRuleScheduleActive(rule.Schedule, from) != firewallRuleScheduleActive(rule.Schedule, to) {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// firewallRuleScheduleActive returns whether the minute containing t matches any of the rule's comma and space
+// separated cron expressions. Rules without a schedule are always active.
+func firewallRuleScheduleActive(schedule string, t time.Time) bool {
+	if schedule == "" {
+		return true
+	}
+
+	minute := t.Truncate(time.Minute)
+	for _, spec := range strings.Split(schedule, ", ") {
+		sched, err := cron.ParseStandard(strings.TrimSpace(spec))
+		if err != nil {
+			continue // Schedules are validated when the ACL is saved.
+		}
+
+		// The next scheduled minute after the one before is this one if this minute is in the schedule.
+		if sched.Next(minute.Add(-time.Second)).Equal(minute) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firewallRuleMatchesPacket returns whether the packet matches all of the rule's criteria.
+// Named subjects aren't supported by the firewall drivers and so never match.
+func firewallRuleMatchesPacket(rule *api.NetworkACLRule, packet FirewallPacket) bool {
+	if rule.Protocol != "" && rule.Protocol != packet.Protocol {
+		return false
+	}
+
+	if rule.Source != "" && !firewallSubjectsContainIP(rule.Source, packet.Source) {
+		return false
+	}
+
+	if rule.Destination != "" && !firewallSubjectsContainIP(rule.Destination, packet.Destination) {
+		return false
+	}
+
+	if rule.SourcePort != "" && !firewallPortsContainPort(rule.SourcePort, packet.SourcePort) {
+		return false
+	}
+
+	if rule.DestinationPort != "" && !firewallPortsContainPort(rule.DestinationPort, packet.DestinationPort) {
+		return false
+	}
+
+	// The packet criteria don't include ICMP type or code, so only rules not restricting them can match.
+	if rule.ICMPType != "" || rule.ICMPCode != "" {
+		return false
+	}
+
+	return true
+}
+
+// firewallSubjectsContainIP returns whether the IP is matched by any of the comma separated subjects.
+func firewallSubjectsContainIP(subjects string, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	// Use the 16 byte form to allow comparison with parsed IP range addresses.
+	ip = ip.To16()
+
+	for _, subject := range util.SplitNTrimSpace(subjects, ",", -1, false) {
+		if strings.Contains(subject, "/") {
+			_, subnet, err := net.ParseCIDR(subject)
+			if err == nil && subnet.Contains(ip) {
+				return true
+			}
+		} else if strings.Contains(subject, "-") {
+			ips := strings.SplitN(subject, "-", 2)
+			ipRange := shared.IPRange{Start: net.ParseIP(ips[0]), End: net.ParseIP(ips[1])}
+			if ipRange.Start != nil && ipRange.End != nil && ipRange.ContainsIP(ip) {
+				return true
+			}
+		} else if subjectIP := net.ParseIP(subject); subjectIP != nil && subjectIP.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firewallPortsContainPort returns whether the port is matched by any of the comma separated ports or port ranges.
+func firewallPortsContainPort(ports string, port uint64) bool {
+	for _, portRange := range util.SplitNTrimSpace(ports, ",", -1, false) {
+		fields := strings.SplitN(portRange, "-", 2)
+
+		start, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		end := start
+		if len(fields) > 1 {
+			end, err = strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		if port >= start && port <= end {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,6 +2,7 @@ package acl
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -77,6 +78,41 @@ func Exists(s *state.State, projectName string, name ...string) error {
 	return nil
 }
 
+// ParseName splits an ACL reference into its project and ACL name.
+// References can be qualified with a project as "<project>/<name>", otherwise defaultProjectName is used.
+func ParseName(defaultProjectName string, reference string) (string, string) {
+	parts := strings.SplitN(reference, "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+
+	return defaultProjectName, reference
+}
+
+// ExistsQualified checks the ACL reference(s) provided exist, resolving project qualified references (see
+// ParseName) from their project and unqualified ones from defaultProjectName.
+// If multiple references are provided, also checks that the same ACL isn't specified multiple times.
+func ExistsQualified(s *state.State, defaultProjectName string, reference ...string) error {
+	projectACLNames := map[string][]string{}
+	for _, aclRef := range reference {
+		aclProjectName, aclName := ParseName(defaultProjectName, aclRef)
+		projectACLNames[aclProjectName] = append(projectACLNames[aclProjectName], aclName)
+	}
+
+	for aclProjectName, aclNames := range projectACLNames {
+		err := Exists(s, aclProjectName, aclNames...)
+		if err != nil {
+			if aclProjectName != defaultProjectName {
+				return errors.Wrapf(err, "Failed checking ACLs in project %q", aclProjectName)
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
 // UsedBy finds all networks, profiles and instance NICs that use any of the specified ACLs and executes usageFunc
 // once for each resource using one or more of the ACLs with info about the resource and matched ACLs being used.
 func UsedBy(s *state.State, aclProjectName string, usageFunc func(matchedACLNames []string, usageType interface{}, nicName string, nicConfig map[string]string) error, matchACLNames ...string) error {
@@ -96,14 +132,7 @@ func UsedBy(s *state.State, aclProjectName string, usageFunc func(matchedACLName
 			return errors.Wrapf(err, "Failed to get network config for %q", networkName)
 		}
 
-		netACLNames := util.SplitNTrimSpace(network.Config["security.acls"], ",", -1, true)
-		matchedACLNames := []string{}
-		for _, netACLName := range netACLNames {
-			if shared.StringInSlice(netACLName, matchACLNames) {
-				matchedACLNames = append(matchedACLNames, netACLName)
-			}
-		}
-
+		matchedACLNames := isInUseByNetwork(aclProjectName, aclProjectName, network.Config, matchACLNames...)
 		if len(matchedACLNames) > 0 {
 			// Call usageFunc with a list of matched ACLs and info about the network.
 			err := usageFunc(matchedACLNames, network, "", nil)
@@ -113,6 +142,30 @@ func UsedBy(s *state.State, aclProjectName string, usageFunc func(matchedACLName
 		}
 	}
 
+	// Find bridge networks in the default project using the ACLs with a project qualified reference.
+	if aclProjectName != project.Default {
+		networkNames, err := s.Cluster.GetCreatedNetworks(project.Default)
+		if err != nil && err != db.ErrNoSuchObject {
+			return errors.Wrapf(err, "Failed loading networks for project %q", project.Default)
+		}
+
+		for _, networkName := range networkNames {
+			_, network, _, err := s.Cluster.GetNetworkInAnyState(project.Default, networkName)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to get network config for %q", networkName)
+			}
+
+			matchedACLNames := isInUseByNetwork(project.Default, aclProjectName, network.Config, matchACLNames...)
+			if len(matchedACLNames) > 0 {
+				// Call usageFunc with a list of matched ACLs and info about the network and its project.
+				err := usageFunc(matchedACLNames, ProjectNetwork{Project: project.Default, Network: network}, "", nil)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	// Look for profiles. Next cheapest to do.
 	var profiles []db.Profile
 	err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
@@ -230,6 +283,21 @@ func UsedBy(s *state.State, aclProjectName string, usageFunc func(matchedACLName
 	return nil
 }
 
+// isInUseByNetwork returns any of the supplied matching ACL names from aclProjectName found referenced by the
+// config of a network in networkProjectName.
+func isInUseByNetwork(networkProjectName string, aclProjectName string, netConfig map[string]string, matchACLNames ...string) []string {
+	matchedACLNames := []string{}
+
+	for _, netACLRef := range util.SplitNTrimSpace(netConfig["security.acls"], ",", -1, true) {
+		netACLProjectName, netACLName := ParseName(networkProjectName, netACLRef)
+		if netACLProjectName == aclProjectName && shared.StringInSlice(netACLName, matchACLNames) {
+			matchedACLNames = append(matchedACLNames, netACLName)
+		}
+	}
+
+	return matchedACLNames
+}
+
 // isInUseByDevice returns any of the supplied matching ACL names found referenced by the NIC device.
 func isInUseByDevice(d deviceConfig.Device, matchACLNames ...string) []string {
 	matchedACLNames := []string{}
@@ -248,12 +316,20 @@ func isInUseByDevice(d deviceConfig.Device, matchACLNames ...string) []string {
 	return matchedACLNames
 }
 
+// ProjectNetwork is the usage type passed by UsedBy for networks outside of the ACL's project that use the ACLs
+// with a project qualified reference (see ParseName).
+type ProjectNetwork struct {
+	Project string
+	Network *api.Network
+}
+
 // NetworkACLUsage info about a network and what ACL it uses.
 type NetworkACLUsage struct {
-	ID     int64
-	Name   string
-	Type   string
-	Config map[string]string
+	ID      int64
+	Name    string
+	Type    string
+	Config  map[string]string
+	Project string // Project of the network if different from the ACL's project.
 }
 
 // NetworkUsage populates the provided aclNets map with networks that are using any of the specified ACLs.
@@ -295,6 +371,22 @@ func NetworkUsage(s *state.State, aclProjectName string, aclNames []string, aclN
 					}
 				}
 			}
+		case ProjectNetwork:
+			key := fmt.Sprintf("%s/%s", u.Project, u.Network.Name)
+			if _, found := aclNets[key]; !found {
+				networkID, network, _, err := s.Cluster.GetNetworkInAnyState(u.Project, u.Network.Name)
+				if err != nil {
+					return errors.Wrapf(err, "Failed to load network %q in project %q", u.Network.Name, u.Project)
+				}
+
+				aclNets[key] = NetworkACLUsage{
+					ID:      networkID,
+					Name:    network.Name,
+					Type:    network.Type,
+					Config:  network.Config,
+					Project: u.Project,
+				}
+			}
 		case *api.NetworkACL:
 			return nil // Nothing to do for ACL rules referencing us.
 		default:
@@ -866,6 +866,8 @@ func OVNPortGroupDeleteIfUnused(s *state.State, logger logger.Logger, client *op
 					delete(removeACLPortGroups, OVNACLNetworkPortGroupName(aclNameIDs[matchedACLName], netID))
 				}
 			}
+		case ProjectNetwork:
+			return nil // Only bridge networks can use ACLs from another project.
 		case db.Profile:
 			ignoreProfile, isIgnoreProfile := ignoreUsageType.(db.Profile)
 
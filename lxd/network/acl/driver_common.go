@@ -136,6 +136,13 @@ func (d *common) usedBy(firstOnly bool) ([]string, error) {
 				uri += fmt.Sprintf("?project=%s", d.projectName)
 			}
 
+			usedBy = append(usedBy, uri)
+		case ProjectNetwork:
+			uri := fmt.Sprintf("/%s/networks/%s", version.APIVersion, u.Network.Name)
+			if u.Project != project.Default {
+				uri += fmt.Sprintf("?project=%s", u.Project)
+			}
+
 			usedBy = append(usedBy, uri)
 		case db.Profile:
 			uri := fmt.Sprintf("/%s/profiles/%s", version.APIVersion, u.Name)
@@ -298,6 +305,14 @@ func (d *common) validateRule(direction ruleDirection, rule api.NetworkACLRule)
 		return fmt.Errorf("State must be one of: %s", strings.Join(validStates, ", "))
 	}
 
+	// Validate Schedule field.
+	if rule.Schedule != "" {
+		err := validate.IsCron(nil)(rule.Schedule)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid schedule")
+		}
+	}
+
 	// Get map of ACL names to DB IDs (used for generating OVN port group names).
 	acls, err := d.state.Cluster.GetNetworkACLIDsByNames(d.Project())
 	if err != nil {
@@ -622,7 +637,13 @@ func (d *common) Update(config *api.NetworkACLPut, clientType request.ClientType
 
 	// Apply ACL changes to non-OVN networks on this member.
 	for _, aclNet := range aclNets {
-		err = FirewallApplyACLRules(d.state, d.logger, d.projectName, aclNet)
+		// Networks using the ACL from another project resolve their unqualified ACLs from their own project.
+		aclNetProjectName := d.projectName
+		if aclNet.Project != "" {
+			aclNetProjectName = aclNet.Project
+		}
+
+		err = FirewallApplyACLRules(d.state, d.logger, aclNetProjectName, aclNet)
 		if err != nil {
 			return err
 		}
@@ -5,15 +5,18 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/mdlayher/netx/eui64"
 	"github.com/pkg/errors"
@@ -47,14 +50,74 @@ import (
 	"github.com/lxc/lxd/shared/version"
 )
 
+// bridgeForkdnsRefreshWorkers is the maximum number of cluster members queried concurrently when refreshing the
+// forkdns peers.
+const bridgeForkdnsRefreshWorkers = 8
+
 // ForkdnsServersListPath defines the path that contains the forkdns server candidate file.
 const ForkdnsServersListPath = "forkdns.servers"
 
 // ForkdnsServersListFile file that contains the server candidates list.
 const ForkdnsServersListFile = "servers.conf"
 
+var forwardTargetsLock sync.Mutex
+
+// forwardTargets records the firewall address forwards last applied for networks with forward port
+// specifications having multiple target addresses, keyed by network name. Protected by forwardTargetsLock.
+var forwardTargets = map[string]string{}
+
+// forwardTargetsFailed records the forward target addresses considered unreachable, keyed by network name.
+// Protected by forwardTargetsLock.
+var forwardTargetsFailed = map[string]map[string]struct{}{}
+
 var forkdnsServersLock sync.Mutex
 
+// forkdnsServersChanges counts the changes of the forkdns servers file, keyed by network name.
+// Protected by forkdnsServersLock.
+var forkdnsServersChanges = map[string]uint64{}
+
+var bgpGracefulShutdownsLock sync.Mutex
+
+// bgpGracefulShutdown tracks a network whose BGP prefixes were announced with the GRACEFUL_SHUTDOWN community.
+type bgpGracefulShutdown struct {
+	until      time.Time         // When the prefixes can be withdrawn.
+	withdrawal *time.Timer       // Pending withdrawal of the prefixes of a stopped network (nil while running).
+	config     map[string]string // Config of the network when it was stopped.
+}
+
+// bgpGracefulShutdowns records the networks that announced their BGP graceful shutdown, keyed by network ID.
+// Protected by bgpGracefulShutdownsLock.
+var bgpGracefulShutdowns = map[int64]*bgpGracefulShutdown{}
+
+// ForkdnsServersChanges returns the number of times the forkdns servers list of each local network has changed
+// since LXD started, keyed by network name.
+func ForkdnsServersChanges() map[string]uint64 {
+	forkdnsServersLock.Lock()
+	defer forkdnsServersLock.Unlock()
+
+	changes := make(map[string]uint64, len(forkdnsServersChanges))
+	for name, count := range forkdnsServersChanges {
+		changes[name] = count
+	}
+
+	return changes
+}
+
+// BridgeHealth represents the health of the subsystems of a bridge network, keyed by subsystem name.
+// A nil error indicates a healthy subsystem. Subsystems that aren't in use by the network are omitted.
+type BridgeHealth map[string]error
+
+// Healthy returns whether all of the subsystems are healthy.
+func (h BridgeHealth) Healthy() bool {
+	for _, err := range h {
+		if err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
 // bridge represents a LXD bridge network.
 type bridge struct {
 	common
@@ -74,6 +137,27 @@ func (n *bridge) DBType() db.NetworkType {
 func (n *bridge) Info() Info {
 	info := n.common.Info()
 	info.AddressForwards = true
+	info.ACLs = true
+	info.BGP = true
+	info.Fan = true
+	info.Tunnels = true
+
+	// Include the keys validated dynamically, using "NAME" in place of the tunnel or BGP peer name.
+	bgpRules, _ := n.bgpValidationRules(nil)
+	info.ConfigKeys = configKeys([]string{
+		"bgp.peers.NAME.address",
+		"bgp.peers.NAME.asn",
+		"bgp.peers.NAME.password",
+		"tunnel.NAME.group",
+		"tunnel.NAME.id",
+		"tunnel.NAME.interface",
+		"tunnel.NAME.local",
+		"tunnel.NAME.mtu",
+		"tunnel.NAME.port",
+		"tunnel.NAME.protocol",
+		"tunnel.NAME.remote",
+		"tunnel.NAME.ttl",
+	}, n.configRules(), bgpRules)
 
 	return info
 }
@@ -129,7 +213,7 @@ func (n *bridge) FillConfig(config map[string]string) error {
 			}
 		}
 
-		if config["ipv6.address"] == "auto" && config["ipv6.nat"] == "" {
+		if shared.StringInSlice(config["ipv6.address"], []string{"auto", "auto-stable"}) && config["ipv6.nat"] == "" {
 			config["ipv6.nat"] = "true"
 		}
 	}
@@ -143,6 +227,62 @@ func (n *bridge) FillConfig(config map[string]string) error {
 	return nil
 }
 
+// EffectiveConfig returns a copy of the network config with its literal "auto" values resolved to the values in
+// effect, without persisting them. Addresses are taken from the bridge interface (and left as is if the network
+// isn't running), and the fan underlay subnet from the default gateway. No creation defaults are applied.
+func (n *bridge) EffectiveConfig() (map[string]string, error) {
+	config := make(map[string]string, len(n.config))
+	for k, v := range n.config {
+		config[k] = v
+	}
+
+	if config["fan.underlay_subnet"] == "auto" {
+		subnet, _, err := DefaultGatewaySubnetV4()
+		if err != nil {
+			return nil, err
+		}
+
+		config["fan.underlay_subnet"] = subnet.String()
+	}
+
+	resolveAddress := []string{}
+	if config["ipv4.address"] == "auto" {
+		resolveAddress = append(resolveAddress, "ipv4.address")
+	}
+
+	if shared.StringInSlice(config["ipv6.address"], []string{"auto", "auto-stable"}) {
+		resolveAddress = append(resolveAddress, "ipv6.address")
+	}
+
+	if len(resolveAddress) == 0 || !n.isRunning() {
+		return config, nil
+	}
+
+	iface, err := net.InterfaceByName(n.name)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range resolveAddress {
+		for _, addr := range addrs {
+			ip, subnet, err := net.ParseCIDR(addr.String())
+			if err != nil || !ip.IsGlobalUnicast() || (ip.To4() != nil) != (key == "ipv4.address") {
+				continue
+			}
+
+			config[key] = (&net.IPNet{IP: ip, Mask: subnet.Mask}).String()
+			break
+		}
+	}
+
+	return config, nil
+}
+
 // populateAutoConfig replaces "auto" in config with generated values.
 func (n *bridge) populateAutoConfig(config map[string]string) error {
 	changedConfig := false
@@ -168,6 +308,17 @@ func (n *bridge) populateAutoConfig(config map[string]string) error {
 		changedConfig = true
 	}
 
+	// The stable subnet is derived from the network name, so can only be generated once the network is loaded.
+	if config["ipv6.address"] == "auto-stable" && n.state != nil && n.name != "" {
+		subnet, err := n.stableSubnetV6()
+		if err != nil {
+			return err
+		}
+
+		config["ipv6.address"] = subnet
+		changedConfig = true
+	}
+
 	if config["fan.underlay_subnet"] == "auto" {
 		subnet, _, err := DefaultGatewaySubnetV4()
 		if err != nil {
@@ -197,14 +348,35 @@ func (n *bridge) ValidateName(name string) error {
 	return n.common.ValidateName(name)
 }
 
-// Validate network config.
-func (n *bridge) Validate(config map[string]string) error {
-	// Build driver specific rules dynamically.
-	rules := map[string]func(value string) error{
+// validateGatewayRecord validates the name of the DNS record published for the gateway.
+func (n *bridge) validateGatewayRecord(value string) error {
+	if value == "none" {
+		return nil
+	}
+
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return fmt.Errorf("Invalid character %q in DNS record name", r)
+		}
+	}
+
+	return nil
+}
+
+// configRules returns the validation rules for the static config keys of the network.
+func (n *bridge) configRules() map[string]func(value string) error {
+	return map[string]func(value string) error{
 		"bgp.ipv4.nexthop": validate.Optional(validate.IsNetworkAddressV4),
 		"bgp.ipv6.nexthop": validate.Optional(validate.IsNetworkAddressV6),
 
-		"bridge.driver": validate.Optional(validate.IsOneOf("native", "openvswitch")),
+		"bgp.ipv4.community": validate.Optional(validBGPCommunities),
+		"bgp.ipv6.community": validate.Optional(validBGPCommunities),
+		"bgp.local_pref":     validate.Optional(validate.IsUint32),
+
+		"bgp.graceful_shutdown_delay": validate.Optional(validate.IsInRange(0, 300)),
+
+		"bridge.ageing_time": validate.Optional(validate.IsUint32),
+		"bridge.driver":      validate.Optional(validate.IsOneOf("native", "openvswitch")),
 		"bridge.external_interfaces": validate.Optional(func(value string) error {
 			for _, entry := range strings.Split(value, ",") {
 				entry = strings.TrimSpace(entry)
@@ -215,9 +387,30 @@ func (n *bridge) Validate(config map[string]string) error {
 
 			return nil
 		}),
-		"bridge.hwaddr": validate.Optional(validate.IsNetworkMAC),
-		"bridge.mtu":    validate.Optional(validate.IsNetworkMTU),
-		"bridge.mode":   validate.Optional(validate.IsOneOf("standard", "fan")),
+		"bridge.group_fwd_mask": validate.Optional(func(value string) error {
+			mask, err := strconv.ParseUint(value, 0, 16)
+			if err != nil {
+				return fmt.Errorf("Invalid value for a 16-bit mask: %s", value)
+			}
+
+			// The kernel never forwards STP, MAC pause and 802.3ad frames.
+			if mask&0x7 != 0 {
+				return fmt.Errorf("Forwarding of the first three reserved group addresses cannot be enabled")
+			}
+
+			return nil
+		}),
+		"bridge.hwaddr":            validate.Optional(validate.IsNetworkMAC),
+		"bridge.hwaddr.seed":       validate.IsAny,
+		"bridge.igmp_snooping":     validate.Optional(validate.IsBool),
+		"bridge.mtu":               validate.Optional(validate.IsNetworkMTU),
+		"bridge.mode":              validate.Optional(validate.IsOneOf("standard", "fan")),
+		"bridge.multicast_querier": validate.Optional(validate.IsBool),
+		"bridge.port_isolation":    validate.Optional(validate.IsBool),
+		"bridge.pvid":              validate.Optional(validate.IsInRange(1, 4094)),
+		"bridge.stp":               validate.Optional(validate.IsBool),
+		"bridge.stp.forward_delay": validate.Optional(validate.IsInRange(4, 30)),
+		"bridge.vlan_filtering":    validate.Optional(validate.IsBool),
 
 		"fan.overlay_subnet": validate.Optional(validate.IsNetworkV4),
 		"fan.underlay_subnet": validate.Optional(func(value string) error {
@@ -227,7 +420,8 @@ func (n *bridge) Validate(config map[string]string) error {
 
 			return validate.IsNetworkV4(value)
 		}),
-		"fan.type": validate.Optional(validate.IsOneOf("vxlan", "ipip")),
+		"fan.type":               validate.Optional(validate.IsOneOf("vxlan", "ipip")),
+		"fan.underlay_interface": validate.Optional(validate.IsInterfaceName),
 
 		"ipv4.address": validate.Optional(func(value string) error {
 			if validate.IsOneOf("none", "auto")(value) == nil {
@@ -236,25 +430,31 @@ func (n *bridge) Validate(config map[string]string) error {
 
 			return validate.IsNetworkAddressCIDRV4(value)
 		}),
-		"ipv4.firewall":     validate.Optional(validate.IsBool),
-		"ipv4.nat":          validate.Optional(validate.IsBool),
-		"ipv4.nat.order":    validate.Optional(validate.IsOneOf("before", "after")),
-		"ipv4.nat.address":  validate.Optional(validate.IsNetworkAddressV4),
-		"ipv4.dhcp":         validate.Optional(validate.IsBool),
-		"ipv4.dhcp.gateway": validate.Optional(validate.IsNetworkAddressV4),
-		"ipv4.dhcp.expiry":  validate.IsAny,
-		"ipv4.dhcp.ranges":  validate.Optional(validate.IsNetworkRangeV4List),
-		"ipv4.routes":       validate.Optional(validate.IsNetworkV4List),
-		"ipv4.routing":      validate.Optional(validate.IsBool),
-		"ipv4.ovn.ranges":   validate.Optional(validate.IsNetworkRangeV4List),
+		"ipv4.address.anycast":         validate.Optional(validate.IsBool),
+		"ipv4.firewall":                validate.Optional(validate.IsBool),
+		"ipv4.nat":                     validate.Optional(validate.IsBool),
+		"ipv4.nat.order":               validate.Optional(validate.IsOneOf("before", "after")),
+		"ipv4.nat.address":             validate.Optional(validate.IsNetworkAddressV4),
+		"ipv4.dhcp":                    validate.Optional(validate.IsBool),
+		"ipv4.dhcp.gateway":            validate.Optional(validate.IsNetworkAddressV4),
+		"ipv4.dhcp.expiry":             validate.IsAny,
+		"ipv4.dhcp.ranges":             validate.Optional(validate.IsNetworkRangeV4List),
+		"ipv4.dhcp.max_leases_per_mac": validate.Optional(validate.IsInRange(1, math.MaxUint32)),
+		"ipv4.dhcp.limit":              validate.Optional(validate.IsInRange(1, math.MaxUint32)),
+		"ipv4.routes":                  validate.Optional(validate.IsNetworkV4List),
+		"ipv4.routing":                 validate.Optional(validate.IsBool),
+		"ipv4.routing.global":          validate.Optional(validate.IsBool),
+		"ipv4.ovn.ranges":              validate.Optional(validate.IsNetworkRangeV4List),
 
 		"ipv6.address": validate.Optional(func(value string) error {
-			if validate.IsOneOf("none", "auto")(value) == nil {
+			if validate.IsOneOf("none", "auto", "auto-stable")(value) == nil {
 				return nil
 			}
 
 			return validate.IsNetworkAddressCIDRV6(value)
 		}),
+		"ipv6.address.anycast":                 validate.Optional(validate.IsBool),
+		"ipv6.dad":                             validate.Optional(validate.IsBool),
 		"ipv6.firewall":                        validate.Optional(validate.IsBool),
 		"ipv6.nat":                             validate.Optional(validate.IsBool),
 		"ipv6.nat.order":                       validate.Optional(validate.IsOneOf("before", "after")),
@@ -263,12 +463,22 @@ func (n *bridge) Validate(config map[string]string) error {
 		"ipv6.dhcp.expiry":                     validate.IsAny,
 		"ipv6.dhcp.stateful":                   validate.Optional(validate.IsBool),
 		"ipv6.dhcp.ranges":                     validate.Optional(validate.IsNetworkRangeV6List),
+		"ipv6.ra.dns":                          validate.Optional(validate.IsNetworkAddressV6List),
+		"ipv6.ra.search":                       validate.Optional(validate.IsListOf(validDNSDomain)),
 		"ipv6.routes":                          validate.Optional(validate.IsNetworkV6List),
 		"ipv6.routing":                         validate.Optional(validate.IsBool),
+		"ipv6.routing.global":                  validate.Optional(validate.IsBool),
 		"ipv6.ovn.ranges":                      validate.Optional(validate.IsNetworkRangeV6List),
+		"dns.dnssec":                           validate.Optional(validate.IsBool),
 		"dns.domain":                           validate.IsAny,
-		"dns.mode":                             validate.Optional(validate.IsOneOf("dynamic", "managed", "none")),
+		"dns.forkdns.exclude":                  validate.IsAny,
+		"dns.gateway_record":                   validate.Optional(n.validateGatewayRecord),
+		"dns.leasefile":                        validate.Optional(validateLeasesPath),
+		"dns.logging":                          validate.Optional(validate.IsOneOf("off", "queries", "dhcp", "all")),
+		"dns.mode":                             validate.Optional(validate.IsOneOf("dynamic", "managed", "none", "external")),
+		"dns.port":                             networkValidPort,
 		"dns.search":                           validate.IsAny,
+		"dns.ttl":                              validate.Optional(validate.IsUint32),
 		"dns.zone.forward":                     validate.Optional(n.validateZoneName),
 		"dns.zone.reverse.ipv4":                validate.Optional(n.validateZoneName),
 		"dns.zone.reverse.ipv6":                validate.Optional(n.validateZoneName),
@@ -280,7 +490,15 @@ func (n *bridge) Validate(config map[string]string) error {
 		"security.acls.default.egress.action":  validate.Optional(validate.IsOneOf(acl.ValidActions...)),
 		"security.acls.default.ingress.logged": validate.Optional(validate.IsBool),
 		"security.acls.default.egress.logged":  validate.Optional(validate.IsBool),
+		"security.apparmor":                    validate.Optional(validate.IsBool),
+		"security.firewall":                    validate.Optional(validate.IsBool),
 	}
+}
+
+// Validate network config.
+func (n *bridge) Validate(config map[string]string) error {
+	// Build driver specific rules dynamically.
+	rules := n.configRules()
 
 	// Add dynamic validation rules.
 	for k := range config {
@@ -301,7 +519,7 @@ func (n *bridge) Validate(config map[string]string) error {
 			// Add the correct validation rule for the dynamic field based on last part of key.
 			switch tunnelKey {
 			case "protocol":
-				rules[k] = validate.Optional(validate.IsOneOf("gre", "vxlan"))
+				rules[k] = validate.Optional(validate.IsOneOf("gre", "vxlan", "geneve"))
 			case "local":
 				rules[k] = validate.Optional(validate.IsNetworkAddress)
 			case "remote":
@@ -312,10 +530,12 @@ func (n *bridge) Validate(config map[string]string) error {
 				rules[k] = validate.Optional(validate.IsNetworkAddress)
 			case "id":
 				rules[k] = validate.Optional(validate.IsInt64)
-			case "inteface":
+			case "interface":
 				rules[k] = validate.IsInterfaceName
 			case "ttl":
 				rules[k] = validate.Optional(validate.IsUint8)
+			case "mtu":
+				rules[k] = validate.Optional(validate.IsNetworkMTU)
 			}
 		}
 	}
@@ -344,6 +564,29 @@ func (n *bridge) Validate(config map[string]string) error {
 		return fmt.Errorf("Network name too long to use with the FAN (must be 11 characters or less)")
 	}
 
+	// Anycast gateways rely on all members presenting the same gateway address.
+	for _, keyPrefix := range []string{"ipv4", "ipv6"} {
+		if shared.IsTrue(config[fmt.Sprintf("%s.address.anycast", keyPrefix)]) && shared.StringInSlice(config[fmt.Sprintf("%s.address", keyPrefix)], []string{"", "none"}) {
+			return fmt.Errorf(`"%s.address.anycast" requires "%s.address" to be set`, keyPrefix, keyPrefix)
+		}
+	}
+
+	if shared.IsTrue(config["ipv6.address.anycast"]) && shared.IsTrue(config["ipv6.dad"]) {
+		return fmt.Errorf(`"ipv6.dad" cannot be enabled with "ipv6.address.anycast"`)
+	}
+
+	if config["bridge.pvid"] != "" && config["bridge.vlan_filtering"] != "" && !shared.IsTrue(config["bridge.vlan_filtering"]) {
+		return fmt.Errorf(`"bridge.pvid" cannot be used when "bridge.vlan_filtering" is disabled`)
+	}
+
+	if config["bridge.driver"] == "openvswitch" {
+		for _, key := range []string{"bridge.group_fwd_mask", "bridge.multicast_querier", "bridge.port_isolation", "bridge.pvid", "bridge.vlan_filtering", "ipv4.address.anycast", "ipv6.address.anycast"} {
+			if config[key] != "" {
+				return fmt.Errorf(`%q cannot be used with the "openvswitch" bridge driver`, key)
+			}
+		}
+	}
+
 	for k, v := range config {
 		key := k
 		// Bridge mode checks
@@ -398,16 +641,24 @@ func (n *bridge) Validate(config map[string]string) error {
 		}
 	}
 
+	// Check the DHCP lease limit fits within the subnet.
+	if config["ipv4.dhcp.limit"] != "" {
+		_, subnet, err := net.ParseCIDR(config["ipv4.address"])
+		if err == nil {
+			ones, bits := subnet.Mask.Size()
+			limit, _ := strconv.ParseInt(config["ipv4.dhcp.limit"], 10, 64)
+			if limit > 1<<(bits-ones) {
+				return fmt.Errorf(`"ipv4.dhcp.limit" cannot be larger than the number of addresses in the subnet (%d)`, 1<<(bits-ones))
+			}
+		}
+	}
+
 	// Check IPv4 OVN ranges.
 	if config["ipv4.ovn.ranges"] != "" {
 		dhcpSubnet := n.DHCPv4Subnet()
 		allowedNets := []*net.IPNet{}
 
 		if dhcpSubnet != nil {
-			if config["ipv4.dhcp.ranges"] == "" {
-				return fmt.Errorf(`"ipv4.ovn.ranges" must be used in conjunction with non-overlapping "ipv4.dhcp.ranges" when DHCPv4 is enabled`)
-			}
-
 			allowedNets = append(allowedNets, dhcpSubnet)
 		}
 
@@ -416,19 +667,21 @@ func (n *bridge) Validate(config map[string]string) error {
 			return errors.Wrapf(err, "Failed parsing ipv4.ovn.ranges")
 		}
 
-		dhcpRanges, err := parseIPRanges(config["ipv4.dhcp.ranges"], allowedNets...)
-		if err != nil {
-			return errors.Wrapf(err, "Failed parsing ipv4.dhcp.ranges")
-		}
+		// If no DHCP ranges are specified the OVN ranges are excluded from the default DHCP range instead.
+		if dhcpSubnet != nil && config["ipv4.dhcp.ranges"] != "" {
+			dhcpRanges, err := parseIPRanges(config["ipv4.dhcp.ranges"], allowedNets...)
+			if err != nil {
+				return errors.Wrapf(err, "Failed parsing ipv4.dhcp.ranges")
+			}
 
-		for _, ovnRange := range ovnRanges {
-			for _, dhcpRange := range dhcpRanges {
-				if IPRangesOverlap(ovnRange, dhcpRange) {
-					return fmt.Errorf(`The range specified in "ipv4.ovn.ranges" (%q) cannot overlap with "ipv4.dhcp.ranges"`, ovnRange)
+			for _, ovnRange := range ovnRanges {
+				for _, dhcpRange := range dhcpRanges {
+					if IPRangesOverlap(ovnRange, dhcpRange) {
+						return fmt.Errorf(`The range specified in "ipv4.ovn.ranges" (%q) cannot overlap with "ipv4.dhcp.ranges"`, ovnRange)
+					}
 				}
 			}
 		}
-
 	}
 
 	// Check IPv6 OVN ranges.
@@ -437,10 +690,6 @@ func (n *bridge) Validate(config map[string]string) error {
 		allowedNets := []*net.IPNet{}
 
 		if dhcpSubnet != nil {
-			if config["ipv6.dhcp.ranges"] == "" && shared.IsTrue(config["ipv6.dhcp.stateful"]) {
-				return fmt.Errorf(`"ipv6.ovn.ranges" must be used in conjunction with non-overlapping "ipv6.dhcp.ranges" when stateful DHCPv6 is enabled`)
-			}
-
 			allowedNets = append(allowedNets, dhcpSubnet)
 		}
 
@@ -451,7 +700,8 @@ func (n *bridge) Validate(config map[string]string) error {
 
 		// If stateful DHCPv6 is enabled, check OVN ranges don't overlap with DHCPv6 stateful ranges.
 		// Otherwise SLAAC will be being used to generate client IPs and predefined ranges aren't used.
-		if dhcpSubnet != nil && shared.IsTrue(config["ipv6.dhcp.stateful"]) {
+		// If no DHCP ranges are specified the OVN ranges are excluded from the default DHCP range instead.
+		if dhcpSubnet != nil && shared.IsTrue(config["ipv6.dhcp.stateful"]) && config["ipv6.dhcp.ranges"] != "" {
 			dhcpRanges, err := parseIPRanges(config["ipv6.dhcp.ranges"], allowedNets...)
 			if err != nil {
 				return errors.Wrapf(err, "Failed parsing ipv6.dhcp.ranges")
@@ -469,10 +719,93 @@ func (n *bridge) Validate(config map[string]string) error {
 
 	// Check Security ACLs are supported and exist.
 	if config["security.acls"] != "" {
-		err = acl.Exists(n.state, n.Project(), util.SplitNTrimSpace(config["security.acls"], ",", -1, true)...)
+		if !firewallManaged(config) {
+			return fmt.Errorf(`Network ACLs cannot be used when "security.firewall" is disabled`)
+		}
+
+		err = acl.ExistsQualified(n.state, n.Project(), util.SplitNTrimSpace(config["security.acls"], ",", -1, true)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkDHCPRanges checks that the IPv4 DHCP ranges fall within the subnet, otherwise dnsmasq silently doesn't
+// serve them, that they don't include the bridge's own address, and that they don't overlap each other,
+// otherwise dnsmasq may hand out the same address twice.
+// This isn't part of Validate so that existing networks with such ranges keep starting.
+func (n *bridge) checkDHCPRanges(config map[string]string) error {
+	if config["ipv4.dhcp.ranges"] == "" {
+		return nil
+	}
+
+	allowedNets := []*net.IPNet{}
+
+	gatewayIP, subnet, err := net.ParseCIDR(config["ipv4.address"])
+	if err == nil {
+		allowedNets = append(allowedNets, subnet)
+	}
+
+	dhcpRanges, err := parseIPRanges(config["ipv4.dhcp.ranges"], allowedNets...)
+	if err != nil {
+		return errors.Wrapf(err, "Failed parsing ipv4.dhcp.ranges")
+	}
+
+	for _, dhcpRange := range dhcpRanges {
+		if gatewayIP != nil && dhcpRange.ContainsIP(gatewayIP) {
+			return fmt.Errorf(`The range %q specified in "ipv4.dhcp.ranges" cannot include the gateway address %q`, dhcpRange, gatewayIP)
+		}
+	}
+
+	for i := range dhcpRanges {
+		for j := i + 1; j < len(dhcpRanges); j++ {
+			if IPRangesOverlap(dhcpRanges[i], dhcpRanges[j]) {
+				return fmt.Errorf(`The ranges %q and %q specified in "ipv4.dhcp.ranges" cannot overlap`, dhcpRanges[i], dhcpRanges[j])
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkRoutesOverlap checks that the routes don't overlap with external subnets in use by other networks and NICs.
+// This isn't part of Validate as it needs to load all instance NICs and shouldn't stop existing networks starting.
+func (n *bridge) checkRoutesOverlap(config map[string]string) error {
+	var routes []*net.IPNet
+	for _, key := range []string{"ipv4.routes", "ipv6.routes"} {
+		for _, route := range util.SplitNTrimSpace(config[key], ",", -1, true) {
+			_, routeNet, err := net.ParseCIDR(route)
+			if err != nil {
+				return errors.Wrapf(err, "Failed parsing %q", key)
+			}
+
+			routes = append(routes, routeNet)
+		}
+	}
+
+	if len(routes) > 0 {
+		externalSubnetsInUse, err := n.getExternalSubnetInUse()
 		if err != nil {
 			return err
 		}
+
+		for _, route := range routes {
+			for _, externalSubnetUser := range externalSubnetsInUse {
+				// Skip our own network (including NIC devices on our own network).
+				// Because NIC routes may legitimately be a subset of the network's routes.
+				if externalSubnetUser.networkProject == n.project && externalSubnetUser.networkName == n.name {
+					continue
+				}
+
+				if SubnetContains(&externalSubnetUser.subnet, route) || SubnetContains(route, &externalSubnetUser.subnet) {
+					// This error is purposefully vague so that it doesn't reveal any names of
+					// resources potentially outside of the network.
+					return fmt.Errorf("Route %q overlaps with another network or NIC", route.String())
+				}
+			}
+		}
 	}
 
 	return nil
@@ -486,9 +819,59 @@ func (n *bridge) Create(clientType request.ClientType) error {
 		return fmt.Errorf("Network interface %q already exists", n.name)
 	}
 
+	if clientType == request.ClientTypeNormal {
+		err := n.checkDHCPRanges(n.config)
+		if err != nil {
+			return err
+		}
+
+		err = n.checkRoutesOverlap(n.config)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Now that the network name is known, generate the stable IPv6 subnet if requested and store it.
+	if n.config["ipv6.address"] == "auto-stable" {
+		err := n.populateAutoConfig(n.config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed generating auto config")
+		}
+
+		err = n.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.UpdateNetwork(n.id, n.description, n.config)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Failed saving stable IPv6 subnet")
+		}
+	}
+
 	return nil
 }
 
+// stableSubnetV6 returns an unused IPv6 ULA subnet derived from the network name and the server certificate
+// fingerprint, so that the same subnet is generated each time a network with that name is created.
+func (n *bridge) stableSubnetV6() (string, error) {
+	// Load server certificate. This is needs to be the same certificate for all nodes in a cluster.
+	cert, err := util.LoadCert(n.state.OS.VarDir)
+	if err != nil {
+		return "", err
+	}
+
+	seed := fmt.Sprintf("%s.%s", cert.Fingerprint(), n.name)
+	r, err := util.GetStableRandomGenerator(seed)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed generating stable random IPv6 subnet")
+	}
+
+	return generateSubnetV6(r.Intn)
+}
+
+// apparmorEnabled returns whether the dnsmasq and forkdns processes should be confined by AppArmor.
+func (n *bridge) apparmorEnabled() bool {
+	return n.config["security.apparmor"] == "" || shared.IsTrue(n.config["security.apparmor"])
+}
+
 // isRunning returns whether the network is up.
 func (n *bridge) isRunning() bool {
 	return InterfaceExists(n.name)
@@ -505,7 +888,7 @@ func (n *bridge) Delete(clientType request.ClientType) error {
 	}
 
 	if n.isRunning() {
-		err := n.Stop()
+		err = n.Stop()
 		if err != nil {
 			return err
 		}
@@ -580,6 +963,97 @@ func (n *bridge) Start() error {
 	return err
 }
 
+// Drain stops dnsmasq from handing out new DHCP leases on this member, for example ahead of maintenance.
+// The bridge and its DHCP ranges are left in place, but only clients that already hold a lease or have a static
+// entry are served, so running instances stay connected and keep renewing their addresses. The drained state is
+// kept across network restarts until Undrain is called.
+func (n *bridge) Drain() error {
+	n.logger.Debug("Drain")
+
+	// Record the clients currently holding a lease so that they remain known to dnsmasq.
+	hosts, err := n.drainedHosts()
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(n.drainedPath(), []byte(strings.Join(hosts, "\n")+"\n"), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "Failed marking network as drained")
+	}
+
+	if !n.isRunning() {
+		return nil
+	}
+
+	return n.setup(n.config)
+}
+
+// Undrain restores normal DHCP service on this member after Drain.
+func (n *bridge) Undrain() error {
+	n.logger.Debug("Undrain")
+
+	err := os.Remove(n.drainedPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Nothing to do.
+		}
+
+		return errors.Wrapf(err, "Failed removing network drained marker")
+	}
+
+	if !n.isRunning() {
+		return nil
+	}
+
+	return n.setup(n.config)
+}
+
+// drainedPath returns the path of the file marking the network as drained on this member.
+// It contains the dnsmasq dhcp-host entries of the clients that held a lease when the network was drained.
+func (n *bridge) drainedPath() string {
+	return shared.VarPath("networks", n.name, "dnsmasq.drained")
+}
+
+// isDrained returns whether the network is drained on this member.
+func (n *bridge) isDrained() bool {
+	return shared.PathExists(n.drainedPath())
+}
+
+// drainedHosts returns dnsmasq dhcp-host entries for the clients currently holding a dynamic lease.
+func (n *bridge) drainedHosts() ([]string, error) {
+	hosts := []string{}
+
+	content, err := ioutil.ReadFile(n.leasesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return hosts, nil
+		}
+
+		return nil, errors.Wrapf(err, "Failed reading leases file")
+	}
+
+	for _, lease := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(lease)
+		if len(fields) < 5 {
+			continue
+		}
+
+		if strings.Contains(fields[2], ":") {
+			// DHCPv6 leases are identified by the client DUID.
+			hosts = append(hosts, fmt.Sprintf("id:%s,[%s]", fields[4], fields[2]))
+		} else {
+			hosts = append(hosts, fmt.Sprintf("%s,%s", fields[1], fields[2]))
+		}
+	}
+
+	return hosts, nil
+}
+
+// bridgeAnycast returns whether the config presents the IPv4 or IPv6 gateway address as an anycast gateway.
+func bridgeAnycast(config map[string]string) bool {
+	return shared.IsTrue(config["ipv4.address.anycast"]) || shared.IsTrue(config["ipv6.address.anycast"])
+}
+
 // setup restarts the network.
 func (n *bridge) setup(oldConfig map[string]string) error {
 	// If we are in mock mode, just no-op.
@@ -628,21 +1102,145 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 	}
 
-	// Get a list of tunnels.
-	tunnels := n.getTunnels()
+	// Configure the spanning tree protocol (RSTP for Open vSwitch), the MAC address table ageing time and
+	// multicast snooping (enabled by default on native bridges, disabled by default on Open vSwitch).
+	// Only the settings which are configured, or which were just unset (to restore their default), are applied
+	// so that those managed outside of LXD are left alone.
+	bridgeKeyApply := func(key string) bool {
+		return n.config[key] != "" || (oldConfig != nil && oldConfig[key] != "")
+	}
 
-	// IPv6 bridge configuration.
-	if !shared.StringInSlice(n.config["ipv6.address"], []string{"", "none"}) {
-		if !shared.PathExists("/proc/sys/net/ipv6") {
-			return fmt.Errorf("Network has ipv6.address but kernel IPv6 support is missing")
-		}
+	igmpSnooping := n.config["bridge.driver"] != "openvswitch"
+	if n.config["bridge.igmp_snooping"] != "" {
+		igmpSnooping = shared.IsTrue(n.config["bridge.igmp_snooping"])
+	}
 
-		err := util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/autoconf", n.name), "0")
+	forwardDelay := uint64(15)
+	if n.config["bridge.stp.forward_delay"] != "" {
+		delay, err := strconv.ParseUint(n.config["bridge.stp.forward_delay"], 10, 64)
 		if err != nil {
-			return err
+			return errors.Wrapf(err, "Invalid bridge.stp.forward_delay")
 		}
 
-		err = util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/accept_dad", n.name), "0")
+		forwardDelay = delay
+	}
+
+	ageingTime := uint64(300)
+	if n.config["bridge.ageing_time"] != "" {
+		ageing, err := strconv.ParseUint(n.config["bridge.ageing_time"], 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid bridge.ageing_time")
+		}
+
+		ageingTime = ageing
+	}
+
+	if n.config["bridge.driver"] == "openvswitch" {
+		settings := []string{}
+		if bridgeKeyApply("bridge.stp") {
+			settings = append(settings, fmt.Sprintf("rstp_enable=%t", shared.IsTrue(n.config["bridge.stp"])))
+		}
+
+		if bridgeKeyApply("bridge.stp.forward_delay") {
+			settings = append(settings, fmt.Sprintf("other_config:rstp-forward-delay=%d", forwardDelay))
+		}
+
+		if bridgeKeyApply("bridge.ageing_time") {
+			settings = append(settings, fmt.Sprintf("other_config:mac-aging-time=%d", ageingTime))
+		}
+
+		if bridgeKeyApply("bridge.igmp_snooping") {
+			settings = append(settings, fmt.Sprintf("mcast_snooping_enable=%t", igmpSnooping))
+		}
+
+		if len(settings) > 0 {
+			ovs := openvswitch.NewOVS()
+			err := ovs.BridgeSet(n.name, settings...)
+			if err != nil {
+				return errors.Wrapf(err, "Failed configuring bridge")
+			}
+		}
+	} else {
+		if bridgeKeyApply("bridge.stp.forward_delay") {
+			err := BridgeSetForwardDelay(n.name, forwardDelay)
+			if err != nil {
+				return err
+			}
+		}
+
+		if bridgeKeyApply("bridge.stp") {
+			err := BridgeSetSTP(n.name, shared.IsTrue(n.config["bridge.stp"]))
+			if err != nil {
+				return err
+			}
+		}
+
+		if bridgeKeyApply("bridge.ageing_time") {
+			err := BridgeSetAgeingTime(n.name, ageingTime)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Forward the configured reserved link-local multicast frames (such as LLDP) to the bridge ports.
+		if bridgeKeyApply("bridge.group_fwd_mask") {
+			groupFwdMask := uint64(0)
+			if n.config["bridge.group_fwd_mask"] != "" {
+				mask, err := strconv.ParseUint(n.config["bridge.group_fwd_mask"], 0, 16)
+				if err != nil {
+					return errors.Wrapf(err, "Invalid bridge.group_fwd_mask")
+				}
+
+				groupFwdMask = mask
+			}
+
+			err := BridgeSetGroupForwardMask(n.name, uint16(groupFwdMask))
+			if err != nil {
+				return err
+			}
+		}
+
+		if bridgeKeyApply("bridge.igmp_snooping") {
+			err := BridgeSetMulticastSnooping(n.name, igmpSnooping)
+			if err != nil {
+				return err
+			}
+		}
+
+		if bridgeKeyApply("bridge.multicast_querier") {
+			err := BridgeSetMulticastQuerier(n.name, shared.IsTrue(n.config["bridge.multicast_querier"]))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Get a list of tunnels.
+	tunnels := n.getTunnels()
+
+	// IPv6 bridge configuration.
+	if !shared.StringInSlice(n.config["ipv6.address"], []string{"", "none"}) {
+		if !shared.PathExists("/proc/sys/net/ipv6") {
+			return fmt.Errorf("Network has ipv6.address but kernel IPv6 support is missing")
+		}
+
+		err := util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/autoconf", n.name), "0")
+		if err != nil {
+			return err
+		}
+
+		// Duplicate address detection is disabled unless explicitly enabled.
+		acceptDAD := "0"
+		if shared.IsTrue(n.config["ipv6.dad"]) {
+			acceptDAD = "1"
+
+			err = util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/dad_transmits", n.name), "1")
+			if err != nil {
+				return err
+			}
+		}
+
+		err = util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/accept_dad", n.name), acceptDAD)
 		if err != nil {
 			return err
 		}
@@ -721,18 +1319,24 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			seedNodeID = 0
 		}
 
-		// Load server certificate. This is needs to be the same certificate for all nodes in a cluster.
-		cert, err := util.LoadCert(n.state.OS.VarDir)
-		if err != nil {
-			return err
-		}
-
 		// Generate the random seed, this uses the server certificate fingerprint (to ensure that multiple
 		// standalone nodes with the same network ID connected to the same external network don't generate
 		// the same MAC for their networks). It relies on the certificate being the same for all nodes in a
 		// cluster to allow the same MAC to be generated on each bridge interface in the network when
-		// seedNodeID is 0 (when safe to do so).
-		seed := fmt.Sprintf("%s.%d.%d", cert.Fingerprint(), seedNodeID, n.ID())
+		// seedNodeID is 0 (when safe to do so). An explicit bridge.hwaddr.seed replaces the fingerprint so
+		// that the MAC doesn't change when the certificate is regenerated.
+		seedPrefix := n.config["bridge.hwaddr.seed"]
+		if seedPrefix == "" {
+			// Load server certificate. This is needs to be the same certificate for all nodes in a cluster.
+			cert, err := util.LoadCert(n.state.OS.VarDir)
+			if err != nil {
+				return err
+			}
+
+			seedPrefix = cert.Fingerprint()
+		}
+
+		seed := fmt.Sprintf("%s.%d.%d", seedPrefix, seedNodeID, n.ID())
 		r, err := util.GetStableRandomGenerator(seed)
 		if err != nil {
 			return errors.Wrapf(err, "Failed generating stable random bridge MAC")
@@ -750,17 +1354,46 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 	}
 
-	// Enable VLAN filtering for Linux bridges.
+	// Enable VLAN filtering for Linux bridges (unless disabled so that instances can push their own tags).
 	if n.config["bridge.driver"] != "openvswitch" {
-		err = BridgeVLANFilterSetStatus(n.name, "1")
-		if err != nil {
-			n.logger.Warn(fmt.Sprintf("%v", err))
-		}
+		if n.config["bridge.vlan_filtering"] == "" || shared.IsTrue(n.config["bridge.vlan_filtering"]) {
+			// Set the default PVID for new ports (1 unless overridden). The kernel only allows changing it
+			// while VLAN filtering is disabled, so turn filtering off first if the PVID needs changing.
+			pvid := n.config["bridge.pvid"]
+			if pvid == "" {
+				pvid = "1"
+			}
 
-		// Set the default PVID for new ports to 1.
-		err = BridgeVLANSetDefaultPVID(n.name, "1")
-		if err != nil {
-			n.logger.Warn(fmt.Sprintf("%v", err))
+			currentPVID, _ := BridgeVLANDefaultPVID(n.name)
+			if currentPVID != pvid {
+				err = BridgeVLANFilterSetStatus(n.name, "0")
+				if err == nil {
+					err = BridgeVLANSetDefaultPVID(n.name, pvid)
+				}
+
+				if err != nil {
+					// Only fail if a PVID was explicitly requested, as VLAN filtering is best effort.
+					if n.config["bridge.pvid"] != "" {
+						return err
+					}
+
+					n.logger.Warn(fmt.Sprintf("%v", err))
+				}
+			}
+
+			err = BridgeVLANFilterSetStatus(n.name, "1")
+			if err != nil {
+				if n.config["bridge.pvid"] != "" {
+					return err
+				}
+
+				n.logger.Warn(fmt.Sprintf("%v", err))
+			}
+		} else {
+			err = BridgeVLANFilterSetStatus(n.name, "0")
+			if err != nil {
+				n.logger.Warn(fmt.Sprintf("%v", err))
+			}
 		}
 	}
 
@@ -803,6 +1436,31 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 	}
 
+	// Stop ARP and NDP requests for an anycast gateway address from being flooded out of the external interfaces,
+	// so that instances are only answered by the bridge on their own member.
+	if n.config["bridge.driver"] != "openvswitch" && (bridgeAnycast(n.config) || bridgeAnycast(oldConfig)) {
+		for _, entry := range util.SplitNTrimSpace(n.config["bridge.external_interfaces"], ",", -1, true) {
+			if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s/brif/%s", n.name, entry)) {
+				continue // Skip missing external interfaces.
+			}
+
+			link := &ip.Link{Name: entry}
+			err = link.BridgeLinkSetNeighSuppress(bridgeAnycast(n.config))
+			if err != nil {
+				return errors.Wrapf(err, "Failed setting neighbour suppression on %q", entry)
+			}
+		}
+	}
+
+	// Apply port isolation changes to the instance NICs already connected to the bridge.
+	// NICs started later apply the setting themselves.
+	if shared.IsTrue(n.config["bridge.port_isolation"]) != shared.IsTrue(oldConfig["bridge.port_isolation"]) {
+		err = n.setupPortIsolation()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Remove any existing firewall rules.
 	fwClearIPVersions := []uint{}
 
@@ -837,6 +1495,13 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		fwOpts.ACL = true
 	}
 
+	if n.config["dns.port"] != "" {
+		fwOpts.DNSPort, err = strconv.ParseUint(n.config["dns.port"], 10, 16)
+		if err != nil {
+			return errors.Wrapf(err, "Failed parsing %q", "dns.port")
+		}
+	}
+
 	// Snapshot container specific IPv4 routes (added with boot proto) before removing IPv4 addresses.
 	// This is because the kernel removes any static routes on an interface when all addresses removed.
 	ctRoutes, err := n.bootRoutesV4()
@@ -873,9 +1538,34 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 
 		// Allow forwarding.
 		if n.config["bridge.mode"] == "fan" || n.config["ipv4.routing"] == "" || shared.IsTrue(n.config["ipv4.routing"]) {
-			err = util.SysctlSet("net/ipv4/ip_forward", "1")
-			if err != nil {
-				return err
+			// Enable forwarding host wide unless restricted to the bridge interface.
+			if n.config["ipv4.routing.global"] == "" || shared.IsTrue(n.config["ipv4.routing.global"]) {
+				err = util.SysctlSet("net/ipv4/ip_forward", "1")
+				if err != nil {
+					return err
+				}
+			} else {
+				// Enable forwarding on the bridge and on the interface of the default route, which the
+				// traffic of the bridge is routed out of. Other uplinks are left to the operator.
+				ifaceNames := []string{n.name}
+
+				uplink, err := defaultGatewayInterfaceV4()
+				if err != nil {
+					return err
+				}
+
+				if uplink == "" {
+					n.logger.Warn("No IPv4 default route to enable forwarding on")
+				} else if uplink != n.name {
+					ifaceNames = append(ifaceNames, uplink)
+				}
+
+				for _, ifaceName := range ifaceNames {
+					err = util.SysctlSet(fmt.Sprintf("net/ipv4/conf/%s/forwarding", ifaceName), "1")
+					if err != nil {
+						return err
+					}
+				}
 			}
 
 			if n.hasIPv4Firewall() {
@@ -903,7 +1593,9 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		dnsmasqCmd = append(dnsmasqCmd, "--dhcp-rapid-commit")
 	}
 
-	if !daemon.Debug {
+	dnsLogging := n.config["dns.logging"]
+
+	if !daemon.Debug && !shared.StringInSlice(dnsLogging, []string{"dhcp", "all"}) {
 		// --quiet options are only supported on >2.67.
 		minVer, _ := version.NewDottedVersion("2.67")
 
@@ -912,6 +1604,19 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 	}
 
+	// Enable the requested debug logging, sent to stderr so that it ends up in the network's dnsmasq log.
+	if dnsLogging != "" && dnsLogging != "off" {
+		dnsmasqCmd = append(dnsmasqCmd, "--log-facility=-")
+
+		if dnsLogging == "queries" || dnsLogging == "all" {
+			dnsmasqCmd = append(dnsmasqCmd, "--log-queries")
+		}
+
+		if dnsLogging == "dhcp" || dnsLogging == "all" {
+			dnsmasqCmd = append(dnsmasqCmd, "--log-dhcp")
+		}
+	}
+
 	// Configure IPv4.
 	if !shared.StringInSlice(n.config["ipv4.address"], []string{"", "none"}) {
 		// Parse the subnet.
@@ -924,7 +1629,7 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--listen-address=%s", ipAddress.String()))
 		if n.DHCPv4Subnet() != nil {
 			if !shared.StringInSlice("--dhcp-no-override", dnsmasqCmd) {
-				dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-no-override", "--dhcp-authoritative", fmt.Sprintf("--dhcp-leasefile=%s", shared.VarPath("networks", n.name, "dnsmasq.leases")), fmt.Sprintf("--dhcp-hostsfile=%s", shared.VarPath("networks", n.name, "dnsmasq.hosts"))}...)
+				dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-no-override", "--dhcp-authoritative", fmt.Sprintf("--dhcp-leasefile=%s", n.leasesPath()), fmt.Sprintf("--dhcp-hostsfile=%s", shared.VarPath("networks", n.name, "dnsmasq.hosts"))}...)
 			}
 
 			if n.config["ipv4.dhcp.gateway"] != "" {
@@ -945,13 +1650,35 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 				expiry = n.config["ipv4.dhcp.expiry"]
 			}
 
+			if n.config["ipv4.dhcp.limit"] != "" {
+				dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--dhcp-lease-max=%s", n.config["ipv4.dhcp.limit"]))
+			}
+
+			// A limit of a single lease per MAC address can be enforced by ignoring client identifiers.
+			// --dhcp-ignore-clid option is only supported on >=2.81.
+			if n.config["ipv4.dhcp.max_leases_per_mac"] == "1" {
+				minVer, _ := version.NewDottedVersion("2.81")
+				if dnsmasqVersion.Compare(minVer) >= 0 {
+					dnsmasqCmd = append(dnsmasqCmd, "--dhcp-ignore-clid")
+				} else {
+					n.logger.Warn("Unable to enforce DHCP lease limit with dnsmasq older than 2.81", log.Ctx{"version": dnsmasqVersion})
+				}
+			}
+
 			if n.config["ipv4.dhcp.ranges"] != "" {
 				for _, dhcpRange := range strings.Split(n.config["ipv4.dhcp.ranges"], ",") {
 					dhcpRange = strings.TrimSpace(dhcpRange)
 					dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-range", fmt.Sprintf("%s,%s", strings.Replace(dhcpRange, "-", ",", -1), expiry)}...)
 				}
 			} else {
-				dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-range", fmt.Sprintf("%s,%s,%s", dhcpalloc.GetIP(subnet, 2).String(), dhcpalloc.GetIP(subnet, -2).String(), expiry)}...)
+				dhcpRanges, err := n.dhcpDefaultRanges("ipv4", subnet, dhcpalloc.GetIP(subnet, 2), dhcpalloc.GetIP(subnet, -2))
+				if err != nil {
+					return err
+				}
+
+				for _, dhcpRange := range dhcpRanges {
+					dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-range", fmt.Sprintf("%s,%s,%s", dhcpRange.Start.String(), dhcpRange.End.String(), expiry)}...)
+				}
 			}
 		}
 
@@ -1064,6 +1791,21 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 
 		// Update the dnsmasq config.
 		dnsmasqCmd = append(dnsmasqCmd, []string{fmt.Sprintf("--listen-address=%s", ipAddress.String()), "--enable-ra"}...)
+
+		// Advertise DNS servers (RDNSS) and search domains (DNSSL) in router advertisements.
+		if n.config["ipv6.ra.dns"] != "" {
+			var dnsServers []string
+			for _, dnsServer := range util.SplitNTrimSpace(n.config["ipv6.ra.dns"], ",", -1, true) {
+				dnsServers = append(dnsServers, fmt.Sprintf("[%s]", dnsServer))
+			}
+
+			dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--dhcp-option=option6:dns-server,%s", strings.Join(dnsServers, ",")))
+		}
+
+		if n.config["ipv6.ra.search"] != "" {
+			dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--dhcp-option=option6:domain-search,%s", strings.Join(util.SplitNTrimSpace(n.config["ipv6.ra.search"], ",", -1, true), ",")))
+		}
+
 		if n.DHCPv6Subnet() != nil {
 			if n.hasIPv6Firewall() {
 				fwOpts.FeaturesV6.ICMPDHCPDNSAccess = true
@@ -1071,7 +1813,7 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 
 			// Build DHCP configuration.
 			if !shared.StringInSlice("--dhcp-no-override", dnsmasqCmd) {
-				dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-no-override", "--dhcp-authoritative", fmt.Sprintf("--dhcp-leasefile=%s", shared.VarPath("networks", n.name, "dnsmasq.leases")), fmt.Sprintf("--dhcp-hostsfile=%s", shared.VarPath("networks", n.name, "dnsmasq.hosts"))}...)
+				dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-no-override", "--dhcp-authoritative", fmt.Sprintf("--dhcp-leasefile=%s", n.leasesPath()), fmt.Sprintf("--dhcp-hostsfile=%s", shared.VarPath("networks", n.name, "dnsmasq.hosts"))}...)
 			}
 
 			expiry := "1h"
@@ -1086,7 +1828,14 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 						dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-range", fmt.Sprintf("%s,%d,%s", strings.Replace(dhcpRange, "-", ",", -1), subnetSize, expiry)}...)
 					}
 				} else {
-					dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-range", fmt.Sprintf("%s,%s,%d,%s", dhcpalloc.GetIP(subnet, 2), dhcpalloc.GetIP(subnet, -1), subnetSize, expiry)}...)
+					dhcpRanges, err := n.dhcpDefaultRanges("ipv6", subnet, dhcpalloc.GetIP(subnet, 2), dhcpalloc.GetIP(subnet, -1))
+					if err != nil {
+						return err
+					}
+
+					for _, dhcpRange := range dhcpRanges {
+						dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-range", fmt.Sprintf("%s,%s,%d,%s", dhcpRange.Start, dhcpRange.End, subnetSize, expiry)}...)
+					}
 				}
 			} else {
 				dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-range", fmt.Sprintf("::,constructor:%s,ra-stateless,ra-names", n.name)}...)
@@ -1097,28 +1846,39 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 
 		// Allow forwarding.
 		if n.config["ipv6.routing"] == "" || shared.IsTrue(n.config["ipv6.routing"]) {
-			// Get a list of proc entries.
-			entries, err := ioutil.ReadDir("/proc/sys/net/ipv6/conf/")
-			if err != nil {
-				return err
+			var ifaceNames []string
+			if n.config["ipv6.routing.global"] == "" || shared.IsTrue(n.config["ipv6.routing.global"]) {
+				// Get a list of proc entries.
+				entries, err := ioutil.ReadDir("/proc/sys/net/ipv6/conf/")
+				if err != nil {
+					return err
+				}
+
+				for _, entry := range entries {
+					ifaceNames = append(ifaceNames, entry.Name())
+				}
+			} else {
+				// Only configure the bridge and its ports. IPv6 forwarding is controlled host wide, so it
+				// is then up to the operator to enable it.
+				ifaceNames = n.bridgeAndPortNames()
 			}
 
 			// First set accept_ra to 2 for everything.
-			for _, entry := range entries {
-				content, err := ioutil.ReadFile(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/accept_ra", entry.Name()))
+			for _, ifaceName := range ifaceNames {
+				content, err := ioutil.ReadFile(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/accept_ra", ifaceName))
 				if err == nil && string(content) != "1\n" {
 					continue
 				}
 
-				err = util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/accept_ra", entry.Name()), "2")
+				err = util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/accept_ra", ifaceName), "2")
 				if err != nil && !os.IsNotExist(err) {
 					return err
 				}
 			}
 
 			// Then set forwarding for all of them.
-			for _, entry := range entries {
-				err = util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/forwarding", entry.Name()), "1")
+			for _, ifaceName := range ifaceNames {
+				err = util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/forwarding", ifaceName), "1")
 				if err != nil && !os.IsNotExist(err) {
 					return err
 				}
@@ -1135,6 +1895,12 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			Address: n.config["ipv6.address"],
 			Family:  ip.FamilyV6,
 		}
+
+		// Don't run DAD for an anycast gateway address, the other members present it too.
+		if shared.IsTrue(n.config["ipv6.address.anycast"]) {
+			addr.Flags = []string{"nodad"}
+		}
+
 		err = addr.Add()
 		if err != nil {
 			return err
@@ -1205,7 +1971,7 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 
 		// Get the address.
-		fanAddress, devName, devAddr, err := n.fanAddress(underlaySubnet, overlaySubnet)
+		fanAddress, devName, devAddr, err := n.fanAddress(underlaySubnet, overlaySubnet, n.config["fan.underlay_interface"])
 		if err != nil {
 			return err
 		}
@@ -1270,7 +2036,7 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		dnsmasqCmd = append(dnsmasqCmd, []string{
 			fmt.Sprintf("--listen-address=%s", addr[0]),
 			"--dhcp-no-override", "--dhcp-authoritative",
-			fmt.Sprintf("--dhcp-leasefile=%s", shared.VarPath("networks", n.name, "dnsmasq.leases")),
+			fmt.Sprintf("--dhcp-leasefile=%s", n.leasesPath()),
 			fmt.Sprintf("--dhcp-hostsfile=%s", shared.VarPath("networks", n.name, "dnsmasq.hosts")),
 			"--dhcp-range", fmt.Sprintf("%s,%s,%s", dhcpalloc.GetIP(hostSubnet, 2).String(), dhcpalloc.GetIP(hostSubnet, -2).String(), expiry)}...)
 
@@ -1371,6 +2137,7 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 	}
 
 	// Configure tunnels.
+	defaultPortTunnels := []string{}
 	for _, tunnel := range tunnels {
 		getConfig := func(key string) string {
 			return n.config[fmt.Sprintf("tunnel.%s.%s", tunnel, key)]
@@ -1429,9 +2196,12 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 				vxlan.DevName = devName
 			}
 
+			// Port 0 makes the kernel use its own default port (8472) rather than the IANA assigned port
+			// (4789). This is kept as the default for compatibility with existing tunnels.
 			tunPort := getConfig("port")
 			if tunPort == "" {
 				tunPort = "0"
+				defaultPortTunnels = append(defaultPortTunnels, tunnel)
 			}
 			vxlan.DstPort = tunPort
 
@@ -1451,6 +2221,29 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			if err != nil {
 				return err
 			}
+		} else if tunProtocol == "geneve" {
+			// Skip partial configs.
+			if tunRemote == "" {
+				continue
+			}
+
+			tunID := getConfig("id")
+			if tunID == "" {
+				tunID = "1"
+			}
+
+			geneve := &ip.Geneve{
+				Link:    ip.Link{Name: tunName},
+				ID:      tunID,
+				Remote:  tunRemote,
+				DstPort: getConfig("port"),
+				TTL:     getConfig("ttl"),
+			}
+
+			err := geneve.Add()
+			if err != nil {
+				return err
+			}
 		}
 
 		// Bridge it and bring up.
@@ -1459,8 +2252,14 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			return err
 		}
 
+		// Use the tunnel specific MTU if set, otherwise the bridge MTU.
+		tunMTU := getConfig("mtu")
+		if tunMTU == "" {
+			tunMTU = mtu
+		}
+
 		tunLink := &ip.Link{Name: tunName}
-		err = tunLink.SetMTU(mtu)
+		err = tunLink.SetMTU(tunMTU)
 		if err != nil {
 			return err
 		}
@@ -1478,18 +2277,29 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 	}
 
-	// Generate and load apparmor profiles.
-	err = apparmor.NetworkLoad(n.state, n)
-	if err != nil {
-		return err
+	// Raise a warning (rather than logging on every setup) while VXLAN tunnels use the kernel default port.
+	if len(defaultPortTunnels) > 0 {
+		msg := fmt.Sprintf("VXLAN tunnels %s use the kernel default destination port 8472 rather than the IANA assigned port 4789, set tunnel.NAME.port explicitly to avoid this", strings.Join(defaultPortTunnels, ", "))
+		err = n.state.Cluster.UpsertWarningLocalNode(n.project, dbCluster.TypeNetwork, int(n.id), db.WarningNetworkVXLANDefaultPort, msg)
+		if err != nil {
+			n.logger.Warn("Failed to create warning", log.Ctx{"err": err})
+		}
+	} else {
+		err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(n.state.Cluster, n.project, db.WarningNetworkVXLANDefaultPort, dbCluster.TypeNetwork, int(n.id))
+		if err != nil {
+			n.logger.Warn("Failed to resolve warning", log.Ctx{"err": err})
+		}
 	}
 
-	// Kill any existing dnsmasq and forkdns daemon for this network.
-	err = dnsmasq.Kill(n.name, false)
-	if err != nil {
-		return err
+	// Generate and load apparmor profiles.
+	if n.apparmorEnabled() {
+		err = apparmor.NetworkLoad(n.state, n)
+		if err != nil {
+			return err
+		}
 	}
 
+	// Kill any existing forkdns daemon for this network (dnsmasq is restarted or reloaded below).
 	err = n.killForkDNS()
 	if err != nil {
 		return err
@@ -1503,9 +2313,42 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			dnsDomain = "lxd"
 		}
 
+		// Bind DNS to a custom port (DHCP isn't affected).
+		if n.config["dns.port"] != "" {
+			dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--port=%s", n.config["dns.port"]))
+		}
+
+		// Set the TTL of the records served from local data (instances and gateway).
+		if n.config["dns.ttl"] != "" {
+			dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--local-ttl=%s", n.config["dns.ttl"]))
+		}
+
+		// Validate upstream DNS answers.
+		if shared.IsTrue(n.config["dns.dnssec"]) {
+			supported, err := dnsmasq.SupportsDNSSEC()
+			if err != nil {
+				return err
+			}
+
+			if !supported {
+				return fmt.Errorf("The installed dnsmasq doesn't support DNSSEC")
+			}
+
+			dnsmasqCmd = append(dnsmasqCmd, "--dnssec", fmt.Sprintf("--trust-anchor=%s", dnsmasq.DNSSECRootTrustAnchor))
+		}
+
 		if n.config["dns.mode"] != "none" {
 			dnsmasqCmd = append(dnsmasqCmd, "-s", dnsDomain)
-			dnsmasqCmd = append(dnsmasqCmd, "--interface-name", fmt.Sprintf("_gateway.%s,%s", dnsDomain, n.name))
+
+			// Publish the gateway record unless disabled.
+			gatewayRecord := n.config["dns.gateway_record"]
+			if gatewayRecord == "" {
+				gatewayRecord = "_gateway"
+			}
+
+			if gatewayRecord != "none" {
+				dnsmasqCmd = append(dnsmasqCmd, "--interface-name", fmt.Sprintf("%s.%s,%s", gatewayRecord, dnsDomain, n.name))
+			}
 
 			if dnsClustered {
 				dnsmasqCmd = append(dnsmasqCmd, "-S", fmt.Sprintf("/%s/%s#1053", dnsDomain, dnsClusteredAddress))
@@ -1530,6 +2373,12 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			dnsmasqCmd = append(dnsmasqCmd, []string{"-g", n.state.OS.UnprivGroup}...)
 		}
 
+		// Point the leases file at its configured location.
+		err = n.setupLeasesFile()
+		if err != nil {
+			return err
+		}
+
 		// Create DHCP hosts directory.
 		if !shared.PathExists(shared.VarPath("networks", n.name, "dnsmasq.hosts")) {
 			err = os.MkdirAll(shared.VarPath("networks", n.name, "dnsmasq.hosts"), 0755)
@@ -1538,6 +2387,11 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			}
 		}
 
+		// Only serve clients with an existing lease or a static entry while the network is drained.
+		if n.isDrained() && shared.StringInSlice("--dhcp-no-override", dnsmasqCmd) {
+			dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--dhcp-hostsfile=%s", n.drainedPath()), "--dhcp-ignore=tag:!known")
+		}
+
 		// Check for dnsmasq.
 		_, err := exec.LookPath("dnsmasq")
 		if err != nil {
@@ -1558,7 +2412,14 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 
 		// Apply AppArmor confinement.
-		if n.config["raw.dnsmasq"] == "" {
+		if !n.apparmorEnabled() {
+			n.logger.Debug("Skipping AppArmor for dnsmasq due to security.apparmor being disabled")
+
+			err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(n.state.Cluster, n.project, db.WarningAppArmorDisabledDueToRawDnsmasq, dbCluster.TypeNetwork, int(n.id))
+			if err != nil {
+				n.logger.Warn("Failed to resolve warning", log.Ctx{"err": err})
+			}
+		} else if n.config["raw.dnsmasq"] == "" {
 			p.SetApparmor(apparmor.DnsmasqProfileName(n))
 
 			err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(n.state.Cluster, n.project, db.WarningAppArmorDisabledDueToRawDnsmasq, dbCluster.TypeNetwork, int(n.id))
@@ -1574,36 +2435,65 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			}
 		}
 
-		// Start dnsmasq.
-		err = p.Start()
-		if err != nil {
-			return fmt.Errorf("Failed to run: %s %s: %v", command, strings.Join(dnsmasqCmd, " "), err)
+		// If only the static host entries may have changed, reload the running dnsmasq rather than
+		// restarting it so that its in-memory lease state is kept. A restart is needed whenever its
+		// command line or raw config (which isn't re-read on reload) changes.
+		reloaded := false
+		if oldConfig != nil && oldConfig["raw.dnsmasq"] == n.config["raw.dnsmasq"] {
+			reloaded, err = dnsmasq.ReloadIfUnchanged(n.name, p)
+			if err != nil {
+				return err
+			}
 		}
 
-		// Check dnsmasq started OK.
-		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(time.Millisecond*time.Duration(500)))
-		_, err = p.Wait(ctx)
-		if errors.Cause(err) != context.DeadlineExceeded {
-			stderr, _ := ioutil.ReadFile(dnsmasqLogPath)
-
-			// Just log an error if dnsmasq has exited, and still proceed with normal setup so we
-			// don't leave the firewall in an inconsistent state.
-			n.logger.Error("The dnsmasq process exited prematurely", log.Ctx{"err": err, "stderr": strings.TrimSpace(string(stderr))})
-		}
-		cancel()
+		if !reloaded {
+			// Kill any existing dnsmasq daemon for this network.
+			err = dnsmasq.Kill(n.name, false)
+			if err != nil {
+				return err
+			}
 
-		err = p.Save(shared.VarPath("networks", n.name, "dnsmasq.pid"))
-		if err != nil {
-			// Kill Process if started, but could not save the file.
-			err2 := p.Stop()
+			// Start dnsmasq.
+			err = p.Start()
 			if err != nil {
-				return fmt.Errorf("Could not kill subprocess while handling saving error: %s: %s", err, err2)
+				return fmt.Errorf("Failed to run: %s %s: %v", command, strings.Join(dnsmasqCmd, " "), err)
 			}
 
-			return fmt.Errorf("Failed to save subprocess details: %s", err)
-		}
+			// Check dnsmasq started OK.
+			ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(time.Millisecond*time.Duration(500)))
+			_, err = p.Wait(ctx)
+			if errors.Cause(err) != context.DeadlineExceeded {
+				stderr, _ := ioutil.ReadFile(dnsmasqLogPath)
 
-		// Spawn DNS forwarder if needed (backgrounded to avoid deadlocks during cluster boot).
+				// Just log an error if dnsmasq has exited, and still proceed with normal setup so we
+				// don't leave the firewall in an inconsistent state.
+				n.logger.Error("The dnsmasq process exited prematurely", log.Ctx{"err": err, "stderr": strings.TrimSpace(string(stderr))})
+
+				err = n.state.Cluster.UpsertWarningLocalNode(n.project, dbCluster.TypeNetwork, int(n.id), db.WarningNetworkDNSMasqStartupFailure, dnsmasqStartupFailure(string(stderr)))
+				if err != nil {
+					n.logger.Warn("Failed to create warning", log.Ctx{"err": err})
+				}
+			} else {
+				err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(n.state.Cluster, n.project, db.WarningNetworkDNSMasqStartupFailure, dbCluster.TypeNetwork, int(n.id))
+				if err != nil {
+					n.logger.Warn("Failed to resolve warning", log.Ctx{"err": err})
+				}
+			}
+			cancel()
+
+			err = p.Save(shared.VarPath("networks", n.name, "dnsmasq.pid"))
+			if err != nil {
+				// Kill Process if started, but could not save the file.
+				err2 := p.Stop()
+				if err != nil {
+					return fmt.Errorf("Could not kill subprocess while handling saving error: %s: %s", err, err2)
+				}
+
+				return fmt.Errorf("Failed to save subprocess details: %s", err)
+			}
+		}
+
+		// Spawn DNS forwarder if needed (backgrounded to avoid deadlocks during cluster boot).
 		if dnsClustered {
 			// Create forkdns servers directory.
 			if !shared.PathExists(shared.VarPath("networks", n.name, ForkdnsServersListPath)) {
@@ -1626,10 +2516,18 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			}
 		}
 	} else {
+		// Kill any existing dnsmasq daemon for this network.
+		err = dnsmasq.Kill(n.name, false)
+		if err != nil {
+			return err
+		}
+
 		// Clean up old dnsmasq config if exists and we are not starting dnsmasq.
+		// If the leases file was relocated with dns.leasefile only the link to it is removed.
 		leasesPath := shared.VarPath("networks", n.name, "dnsmasq.leases")
-		if shared.PathExists(leasesPath) {
-			err := os.Remove(leasesPath)
+		_, err = os.Lstat(leasesPath)
+		if err == nil {
+			err = os.Remove(leasesPath)
 			if err != nil {
 				return errors.Wrapf(err, "Failed to remove old dnsmasq leases file %q", leasesPath)
 			}
@@ -1645,25 +2543,27 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 	}
 
-	// Setup firewall.
-	n.logger.Debug("Setting up firewall")
-	err = n.state.Firewall.NetworkSetup(n.name, fwOpts)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to setup firewall")
-	}
-
-	if fwOpts.ACL {
-		aclNet := acl.NetworkACLUsage{
-			Name:   n.Name(),
-			Type:   n.Type(),
-			ID:     n.ID(),
-			Config: n.Config(),
+	// Setup firewall (unless managed by the operator).
+	if firewallManaged(n.config) {
+		n.logger.Debug("Setting up firewall")
+		err = n.state.Firewall.NetworkSetup(n.name, fwOpts)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to setup firewall")
 		}
 
-		n.logger.Debug("Applying up firewall ACLs")
-		err = acl.FirewallApplyACLRules(n.state, n.logger, n.Project(), aclNet)
-		if err != nil {
-			return err
+		if fwOpts.ACL {
+			aclNet := acl.NetworkACLUsage{
+				Name:   n.Name(),
+				Type:   n.Type(),
+				ID:     n.ID(),
+				Config: n.Config(),
+			}
+
+			n.logger.Debug("Applying up firewall ACLs")
+			err = acl.FirewallApplyACLRules(n.state, n.logger, n.Project(), aclNet)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -1673,7 +2573,8 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		return err
 	}
 
-	// Setup BGP.
+	// Setup BGP (replacing the prefixes held since a previous stop, if any).
+	n.bgpCancelGracefulShutdown()
 	err = n.bgpSetup(oldConfig)
 	if err != nil {
 		return err
@@ -1683,6 +2584,118 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 	return nil
 }
 
+// BGPGracefulShutdown re-advertises the network's BGP prefixes with the GRACEFUL_SHUTDOWN community ahead of the
+// network being stopped for good, and returns how long they should be held before Stop withdraws them.
+// Returns 0 if bgp.graceful_shutdown_delay isn't set or no prefixes are advertised. Once announced, further calls
+// return the remainder of the hold.
+func (n *bridge) BGPGracefulShutdown() (time.Duration, error) {
+	if n.config["bgp.graceful_shutdown_delay"] == "" || !n.isRunning() {
+		return 0, nil
+	}
+
+	bgpGracefulShutdownsLock.Lock()
+	shutdown, found := bgpGracefulShutdowns[n.id]
+	bgpGracefulShutdownsLock.Unlock()
+
+	if found {
+		hold := time.Until(shutdown.until)
+		if hold < 0 {
+			return 0, nil
+		}
+
+		return hold, nil
+	}
+
+	delay, err := strconv.ParseUint(n.config["bgp.graceful_shutdown_delay"], 10, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed parsing %q", "bgp.graceful_shutdown_delay")
+	}
+
+	if delay == 0 {
+		return 0, nil
+	}
+
+	advertised, err := n.bgpGracefulShutdown()
+	if err != nil {
+		return 0, err
+	}
+
+	if advertised == 0 {
+		return 0, nil
+	}
+
+	hold := time.Duration(delay) * time.Second
+	n.logger.Debug("Holding BGP prefixes before withdrawal", log.Ctx{"prefixes": advertised, "hold": hold})
+
+	bgpGracefulShutdownsLock.Lock()
+	bgpGracefulShutdowns[n.id] = &bgpGracefulShutdown{until: time.Now().Add(hold)}
+	bgpGracefulShutdownsLock.Unlock()
+
+	return hold, nil
+}
+
+// bgpStop withdraws the network's BGP prefixes and peers. If a graceful shutdown is being held, the withdrawal is
+// scheduled for the end of the hold rather than waited for, and is cancelled if the network is started again.
+func (n *bridge) bgpStop() error {
+	hold, err := n.BGPGracefulShutdown()
+	if err != nil {
+		n.logger.Warn("Failed announcing BGP graceful shutdown", log.Ctx{"err": err})
+	}
+
+	if hold <= 0 {
+		bgpGracefulShutdownsLock.Lock()
+		delete(bgpGracefulShutdowns, n.id)
+		bgpGracefulShutdownsLock.Unlock()
+
+		return n.bgpClear(n.config)
+	}
+
+	bgpGracefulShutdownsLock.Lock()
+	defer bgpGracefulShutdownsLock.Unlock()
+
+	shutdown := bgpGracefulShutdowns[n.id]
+	shutdown.config = n.config
+	shutdown.withdrawal = time.AfterFunc(hold, func() {
+		bgpGracefulShutdownsLock.Lock()
+		if bgpGracefulShutdowns[n.id] != shutdown {
+			// Cancelled by a restart of the network.
+			bgpGracefulShutdownsLock.Unlock()
+			return
+		}
+
+		delete(bgpGracefulShutdowns, n.id)
+		bgpGracefulShutdownsLock.Unlock()
+
+		err := n.bgpClear(shutdown.config)
+		if err != nil {
+			n.logger.Error("Failed withdrawing BGP prefixes", log.Ctx{"err": err})
+		}
+	})
+
+	return nil
+}
+
+// bgpCancelGracefulShutdown forgets the network's BGP graceful shutdown. If the network was stopped and its
+// prefixes are still being held, their withdrawal is cancelled and they are cleared right away, so that they can be
+// advertised again without the GRACEFUL_SHUTDOWN community.
+func (n *bridge) bgpCancelGracefulShutdown() {
+	bgpGracefulShutdownsLock.Lock()
+	shutdown, found := bgpGracefulShutdowns[n.id]
+	delete(bgpGracefulShutdowns, n.id)
+	bgpGracefulShutdownsLock.Unlock()
+
+	if !found || shutdown.withdrawal == nil {
+		return
+	}
+
+	shutdown.withdrawal.Stop()
+
+	err := n.bgpClear(shutdown.config)
+	if err != nil {
+		n.logger.Warn("Failed removing held BGP prefixes", log.Ctx{"err": err})
+	}
+}
+
 // Stop stops the network.
 func (n *bridge) Stop() error {
 	n.logger.Debug("Stop")
@@ -1691,8 +2704,8 @@ func (n *bridge) Stop() error {
 		return nil
 	}
 
-	// Clear BGP.
-	err := n.bgpClear(n.config)
+	// Clear BGP (once the graceful shutdown hold, if any, expired).
+	err := n.bgpStop()
 	if err != nil {
 		return err
 	}
@@ -1787,6 +2800,30 @@ func (n *bridge) Update(newNetwork api.NetworkPut, targetNode string, clientType
 		return nil // Nothing changed.
 	}
 
+	// Check that the static addresses of the instance NICs remain valid with the new subnets.
+	if clientType == request.ClientTypeNormal && (shared.StringInSlice("ipv4.address", changedKeys) || shared.StringInSlice("ipv6.address", changedKeys)) {
+		err = n.checkStaticNICAddresses(newNetwork.Config)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check that changed DHCP ranges are usable.
+	if clientType == request.ClientTypeNormal && (shared.StringInSlice("ipv4.dhcp.ranges", changedKeys) || shared.StringInSlice("ipv4.address", changedKeys)) {
+		err = n.checkDHCPRanges(newNetwork.Config)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check that changed routes don't overlap with those used by other networks and NICs.
+	if clientType == request.ClientTypeNormal && (shared.StringInSlice("ipv4.routes", changedKeys) || shared.StringInSlice("ipv6.routes", changedKeys)) {
+		err = n.checkRoutesOverlap(newNetwork.Config)
+		if err != nil {
+			return err
+		}
+	}
+
 	// If the network as a whole has not had any previous creation attempts, or the node itself is still
 	// pending, then don't apply the new settings to the node, just to the database record (ready for the
 	// actual global create request to be initiated).
@@ -1883,7 +2920,9 @@ func (n *bridge) spawnForkDNS(listenAddress string) error {
 	p.SetCreds(n.state.OS.UnprivUID, n.state.OS.UnprivGID)
 
 	// Apply AppArmor profile.
-	p.SetApparmor(apparmor.ForkdnsProfileName(n))
+	if n.apparmorEnabled() {
+		p.SetApparmor(apparmor.ForkdnsProfileName(n))
+	}
 
 	err = p.Start()
 	if err != nil {
@@ -1907,7 +2946,6 @@ func (n *bridge) spawnForkDNS(listenAddress string) error {
 // HandleHeartbeat refreshes forkdns servers. Retrieves the IPv4 address of each cluster node (excluding ourselves)
 // for this network. It then updates the forkdns server list file if there are changes.
 func (n *bridge) HandleHeartbeat(heartbeatData *cluster.APIHeartbeat) error {
-	addresses := []string{}
 	localAddress, err := node.HTTPSAddress(n.state.Node)
 	if err != nil {
 		return err
@@ -1915,36 +2953,80 @@ func (n *bridge) HandleHeartbeat(heartbeatData *cluster.APIHeartbeat) error {
 
 	n.logger.Info("Refreshing forkdns peers")
 
-	networkCert := n.state.Endpoints.NetworkCert()
+	excludedMembers := util.SplitNTrimSpace(n.config["dns.forkdns.exclude"], ",", -1, true)
+
+	// Sort the members so that the resulting address list is in a stable order.
+	members := make([]cluster.APIHeartbeatMember, 0, len(heartbeatData.Members))
 	for _, node := range heartbeatData.Members {
 		if node.Address == localAddress {
 			// No need to query ourselves.
 			continue
 		}
 
+		if shared.StringInSlice(node.Name, excludedMembers) {
+			// Keep DNS forwarding away from members excluded from peering (such as ones in a remote site).
+			continue
+		}
+
 		if !node.Online {
 			n.logger.Warn("Excluding offline member from DNS peers refresh", log.Ctx{"address": node.Address, "ID": node.ID, "raftID": node.RaftID, "lastHeartbeat": node.LastHeartbeat})
 			continue
 		}
 
-		client, err := cluster.Connect(node.Address, networkCert, n.state.ServerCert(), nil, true)
-		if err != nil {
-			return err
-		}
+		members = append(members, node)
+	}
 
-		state, err := client.GetNetworkState(n.name)
-		if err != nil {
-			return err
-		}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
 
-		for _, addr := range state.Addresses {
-			// Only get IPv4 addresses of nodes on network.
-			if addr.Family != "inet" || addr.Scope != "global" {
-				continue
+	// Query the members concurrently, with a bounded number of queries in flight.
+	networkCert := n.state.Endpoints.NetworkCert()
+	memberAddresses := make([]string, len(members))
+	workers := make(chan struct{}, bridgeForkdnsRefreshWorkers)
+	wg := sync.WaitGroup{}
+
+	for i := range members {
+		wg.Add(1)
+		workers <- struct{}{}
+
+		go func(i int, node cluster.APIHeartbeatMember) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			// Skip members that can't be queried rather than failing the whole refresh, so that a
+			// transient issue with one member doesn't prevent the peer list being refreshed from the
+			// reachable ones.
+			client, err := cluster.Connect(node.Address, networkCert, n.state.ServerCert(), nil, true)
+			if err != nil {
+				n.logger.Warn("Excluding unreachable member from DNS peers refresh", log.Ctx{"address": node.Address, "ID": node.ID, "err": err})
+				return
 			}
 
-			addresses = append(addresses, addr.Address)
-			break
+			state, err := client.GetNetworkState(n.name)
+			if err != nil {
+				n.logger.Warn("Excluding member from DNS peers refresh after failing to get network state", log.Ctx{"address": node.Address, "ID": node.ID, "err": err})
+				return
+			}
+
+			for _, addr := range state.Addresses {
+				// Only get IPv4 addresses of nodes on network.
+				if addr.Family != "inet" || addr.Scope != "global" {
+					continue
+				}
+
+				memberAddresses[i] = addr.Address
+				break
+			}
+		}(i, members[i])
+	}
+
+	wg.Wait()
+
+	addresses := []string{}
+	for _, address := range memberAddresses {
+		if address != "" {
+			addresses = append(addresses, address)
 		}
 	}
 
@@ -2046,7 +3128,7 @@ func (n *bridge) applyBootRoutesV6(routes []string) {
 	}
 }
 
-func (n *bridge) fanAddress(underlay *net.IPNet, overlay *net.IPNet) (string, string, string, error) {
+func (n *bridge) fanAddress(underlay *net.IPNet, overlay *net.IPNet, underlayInterface string) (string, string, string, error) {
 	// Quick checks.
 	underlaySize, _ := underlay.Mask.Size()
 	if underlaySize != 16 && underlaySize != 24 {
@@ -2063,7 +3145,7 @@ func (n *bridge) fanAddress(underlay *net.IPNet, overlay *net.IPNet) (string, st
 	}
 
 	// Get the IP
-	ip, dev, err := n.addressForSubnet(underlay)
+	ip, dev, err := n.addressForSubnet(underlay, underlayInterface)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -2093,13 +3175,19 @@ func (n *bridge) fanAddress(underlay *net.IPNet, overlay *net.IPNet) (string, st
 	return fmt.Sprintf("%s/%d", ipBytes.String(), overlaySize), dev, ipStr, err
 }
 
-func (n *bridge) addressForSubnet(subnet *net.IPNet) (net.IP, string, error) {
+// addressForSubnet returns the first address (and its interface) within the subnet. If ifaceName is specified then
+// only that interface is considered.
+func (n *bridge) addressForSubnet(subnet *net.IPNet, ifaceName string) (net.IP, string, error) {
 	ifaces, err := net.Interfaces()
 	if err != nil {
 		return net.IP{}, "", err
 	}
 
 	for _, iface := range ifaces {
+		if ifaceName != "" && iface.Name != ifaceName {
+			continue
+		}
+
 		// Skip addresses on lo interface in case VIPs are being used on that interface that are part of
 		// the underlay subnet as is unlikely to be the actual intended underlay subnet interface.
 		if iface.Name == "lo" {
@@ -2123,6 +3211,10 @@ func (n *bridge) addressForSubnet(subnet *net.IPNet) (net.IP, string, error) {
 		}
 	}
 
+	if ifaceName != "" {
+		return net.IP{}, "", fmt.Errorf("No address found in subnet on interface %q", ifaceName)
+	}
+
 	return net.IP{}, "", fmt.Errorf("No address found in subnet")
 }
 
@@ -2148,6 +3240,24 @@ func (n *bridge) killForkDNS() error {
 	return nil
 }
 
+// ForkdnsStatus returns the cluster members the local forkdns process forwards DNS queries to and whether it is
+// running. Returns nil if the network doesn't use forkdns on this member.
+func (n *bridge) ForkdnsStatus() (*api.NetworkStateForkdns, error) {
+	if !shared.PathExists(shared.VarPath("networks", n.name, ForkdnsServersListPath)) {
+		return nil, nil
+	}
+
+	servers, err := ForkdnsServersList(n.name)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "Failed reading forkdns servers list")
+	}
+
+	return &api.NetworkStateForkdns{
+		Running: n.healthProcess("forkdns") == nil,
+		Servers: servers,
+	}, nil
+}
+
 // updateForkdnsServersFile takes a list of node addresses and writes them atomically to
 // the forkdns.servers file ready for forkdns to notice and re-apply its config.
 func (n *bridge) updateForkdnsServersFile(addresses []string) error {
@@ -2180,6 +3290,8 @@ func (n *bridge) updateForkdnsServersFile(addresses []string) error {
 		return err
 	}
 
+	forkdnsServersChanges[n.name]++
+
 	return nil
 }
 
@@ -2294,18 +3406,95 @@ func (n *bridge) forwardConvertToFirewallForwards(listenAddress net.IP, defaultT
 	}
 
 	for _, portMap := range portMaps {
-		vips = append(vips, firewallDrivers.AddressForward{
-			ListenAddress: listenAddress,
-			Protocol:      portMap.protocol,
-			TargetAddress: portMap.targetAddress,
-			ListenPorts:   portMap.listenPorts,
-			TargetPorts:   portMap.targetPorts,
-		})
+		for _, targetAddress := range n.forwardHealthyTargets(portMap.targetAddresses) {
+			vips = append(vips, firewallDrivers.AddressForward{
+				ListenAddress: listenAddress,
+				Protocol:      portMap.protocol,
+				TargetAddress: targetAddress,
+				ListenPorts:   portMap.listenPorts,
+				TargetPorts:   portMap.targetPorts,
+			})
+		}
 	}
 
 	return vips
 }
 
+// forwardHealthyTargets returns the target addresses that are reachable from the bridge according to its
+// neighbour table, in order of preference. If there is a single target address or none of them are reachable,
+// only the first (preferred) target address is returned.
+// A target address is considered unreachable once its neighbour entry failed, and stays so until the entry is
+// confirmed reachable again. Addresses without a conclusive neighbour entry keep their previous state (reachable
+// initially). The target addresses are then probed, so that the next check (by ForwardTargetsRefresh) sees the
+// outcome of a fresh resolution.
+func (n *bridge) forwardHealthyTargets(targetAddresses []net.IP) []net.IP {
+	if len(targetAddresses) <= 1 {
+		return targetAddresses
+	}
+
+	defer n.forwardProbeTargets(targetAddresses)
+
+	neigh := &ip.Neigh{DevName: n.name}
+	neighbours, err := neigh.Show()
+	if err != nil {
+		n.logger.Warn("Failed getting neighbours to check the forward target addresses", log.Ctx{"err": err})
+		return targetAddresses[:1]
+	}
+
+	forwardTargetsLock.Lock()
+	defer forwardTargetsLock.Unlock()
+
+	failed := forwardTargetsFailed[n.name]
+	if failed == nil {
+		failed = map[string]struct{}{}
+		forwardTargetsFailed[n.name] = failed
+	}
+
+	healthy := make([]net.IP, 0, len(targetAddresses))
+	for _, targetAddress := range targetAddresses {
+		for _, neighbour := range neighbours {
+			if !neighbour.Addr.Equal(targetAddress) {
+				continue
+			}
+
+			switch neighbour.State {
+			case ip.NeighbourIPStateFailed, ip.NeighbourIPStateIncomplete:
+				failed[targetAddress.String()] = struct{}{}
+			case ip.NeighbourIPStateReachable, ip.NeighbourIPStatePermanent, ip.NeighbourIPStateNoARP:
+				delete(failed, targetAddress.String())
+			}
+
+			break
+		}
+
+		_, isFailed := failed[targetAddress.String()]
+		if !isFailed {
+			healthy = append(healthy, targetAddress)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return targetAddresses[:1]
+	}
+
+	return healthy
+}
+
+// forwardProbeTargets sends a datagram (to the discard port) to each of the target addresses, making the kernel
+// resolve their neighbour entries on the bridge, or re-validate them if they are stale.
+func (n *bridge) forwardProbeTargets(targetAddresses []net.IP) {
+	for _, targetAddress := range targetAddresses {
+		conn, err := net.Dial("udp", net.JoinHostPort(targetAddress.String(), "9"))
+		if err != nil {
+			n.logger.Debug("Failed probing forward target address", log.Ctx{"address": targetAddress.String(), "err": err})
+			continue
+		}
+
+		_, _ = conn.Write([]byte{0})
+		conn.Close()
+	}
+}
+
 // bridgeProjectNetworks takes a map of all networks in all projects and returns a filtered map of bridge networks.
 func (n *bridge) bridgeProjectNetworks(projectNetworks map[string]map[int64]api.Network) map[string][]*api.Network {
 	bridgeProjectNetworks := make(map[string][]*api.Network)
@@ -2453,6 +3642,30 @@ func (n *bridge) bridgedNICExternalRoutes(bridgeProjectNetworks map[string][]*ap
 	return externalRoutes, nil
 }
 
+// ExternalSubnetsInUse returns information about usage of external subnets by bridge networks, NICs connected to
+// them and network forwards on this member. Intended for planning address allocations.
+func (n *bridge) ExternalSubnetsInUse() ([]api.NetworkExternalSubnet, error) {
+	externalSubnetsInUse, err := n.getExternalSubnetInUse()
+	if err != nil {
+		return nil, err
+	}
+
+	externalSubnets := make([]api.NetworkExternalSubnet, 0, len(externalSubnetsInUse))
+	for _, externalSubnetUser := range externalSubnetsInUse {
+		externalSubnets = append(externalSubnets, api.NetworkExternalSubnet{
+			Subnet:          externalSubnetUser.subnet.String(),
+			NetworkProject:  externalSubnetUser.networkProject,
+			NetworkName:     externalSubnetUser.networkName,
+			NetworkSNAT:     externalSubnetUser.networkSNAT,
+			InstanceProject: externalSubnetUser.instanceProject,
+			InstanceName:    externalSubnetUser.instanceName,
+			InstanceDevice:  externalSubnetUser.instanceDevice,
+		})
+	}
+
+	return externalSubnets, nil
+}
+
 // getExternalSubnetInUse returns information about usage of external subnets by bridge networks (and NICs
 // connected to them) on this member.
 func (n *bridge) getExternalSubnetInUse() ([]externalSubnetUsage, error) {
@@ -2781,22 +3994,25 @@ func (n *bridge) ForwardDelete(listenAddress string, clientType request.ClientTy
 	return nil
 }
 
-// forwardSetupFirewall applies all network address forwards defined for this network and this member.
-func (n *bridge) forwardSetupFirewall() error {
+// forwardFirewallForwards returns the firewall address forwards for all network address forwards defined for this
+// network and this member, along with the IP versions in use and whether any port specification has multiple
+// target addresses.
+func (n *bridge) forwardFirewallForwards() ([]firewallDrivers.AddressForward, map[uint]struct{}, bool, error) {
 	memberSpecific := true // Get all forwards for this cluster member.
 	forwards, err := n.state.Cluster.GetNetworkForwards(n.ID(), memberSpecific)
 	if err != nil {
-		return fmt.Errorf("Failed loading network forwards: %w", err)
+		return nil, nil, false, fmt.Errorf("Failed loading network forwards: %w", err)
 	}
 
 	var fwForwards []firewallDrivers.AddressForward
 	ipVersions := make(map[uint]struct{})
+	multipleTargets := false
 
 	for _, forward := range forwards {
 		// Convert listen address to subnet so we can check its valid and can be used.
 		listenAddressNet, err := ParseIPToNet(forward.ListenAddress)
 		if err != nil {
-			return errors.Wrapf(err, "Failed parsing address forward listen address %q", forward.ListenAddress)
+			return nil, nil, false, errors.Wrapf(err, "Failed parsing address forward listen address %q", forward.ListenAddress)
 		}
 
 		// Track which IP versions we are using.
@@ -2808,13 +4024,34 @@ func (n *bridge) forwardSetupFirewall() error {
 
 		portMaps, err := n.forwardValidate(listenAddressNet.IP, &forward.NetworkForwardPut)
 		if err != nil {
-			return fmt.Errorf("Failed validating firewall address forward for listen address %q: %w", forward.ListenAddress, err)
+			return nil, nil, false, fmt.Errorf("Failed validating firewall address forward for listen address %q: %w", forward.ListenAddress, err)
+		}
+
+		for _, portMap := range portMaps {
+			if len(portMap.targetAddresses) > 1 {
+				multipleTargets = true
+			}
 		}
 
 		fwForwards = append(fwForwards, n.forwardConvertToFirewallForwards(listenAddressNet.IP, net.ParseIP(forward.Config["target_address"]), portMaps)...)
 	}
 
-	if len(forwards) > 0 {
+	return fwForwards, ipVersions, multipleTargets, nil
+}
+
+// forwardSetupFirewall applies all network address forwards defined for this network and this member.
+func (n *bridge) forwardSetupFirewall() error {
+	// Leave the firewall untouched if managed by the operator.
+	if !firewallManaged(n.config) {
+		return nil
+	}
+
+	fwForwards, ipVersions, multipleTargets, err := n.forwardFirewallForwards()
+	if err != nil {
+		return err
+	}
+
+	if len(ipVersions) > 0 {
 		// Check if br_netfilter is enabled to, and warn if not.
 		brNetfilterWarning := false
 		for ipVersion := range ipVersions {
@@ -2843,132 +4080,809 @@ func (n *bridge) forwardSetupFirewall() error {
 		return fmt.Errorf("Failed applying firewall address forwards: %w", err)
 	}
 
+	// Record the applied forwards so that ForwardTargetsRefresh can detect target reachability changes.
+	forwardTargetsLock.Lock()
+	if multipleTargets {
+		forwardTargets[n.name] = fmt.Sprintf("%v", fwForwards)
+	} else {
+		delete(forwardTargets, n.name)
+		delete(forwardTargetsFailed, n.name)
+	}
+	forwardTargetsLock.Unlock()
+
 	return nil
 }
 
-// Leases returns a list of leases for the bridged network. It will reach out to other cluster members as needed.
-// The projectName passed here refers to the initial project from the API request which may differ from the network's project.
-func (n *bridge) Leases(projectName string, clientType request.ClientType) ([]api.NetworkLease, error) {
-	leases := []api.NetworkLease{}
-	projectMacs := []string{}
-
-	// Get all static leases.
-	if clientType == request.ClientTypeNormal {
-		// Get the downstream networks.
-		if n.project == project.Default {
-			var err error
+// ForwardTargetsRefresh re-applies the network's address forwards if the reachability of the target addresses of
+// the forward port specifications having multiple target addresses has changed since they were last applied.
+func (n *bridge) ForwardTargetsRefresh() error {
+	forwardTargetsLock.Lock()
+	applied, found := forwardTargets[n.name]
+	forwardTargetsLock.Unlock()
 
-			// Load all the networks.
-			var projectNetworks map[string]map[int64]api.Network
-			err = n.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
-				projectNetworks, err = tx.GetCreatedNetworks()
-				return err
-			})
-			if err != nil {
-				return nil, err
-			}
+	if !found || !firewallManaged(n.config) || !n.isRunning() {
+		return nil
+	}
 
-			// Look for networks using the current network as an uplink.
-			for projectName, networks := range projectNetworks {
-				for _, network := range networks {
-					if network.Config["network"] != n.name {
-						continue
-					}
+	fwForwards, _, _, err := n.forwardFirewallForwards()
+	if err != nil {
+		return err
+	}
 
-					// Found a network, add leases.
-					for _, k := range []string{"volatile.network.ipv4.address", "volatile.network.ipv6.address"} {
-						v := network.Config[k]
-						if v != "" {
-							leases = append(leases, api.NetworkLease{
-								Hostname: fmt.Sprintf("%s-%s.uplink", projectName, network.Name),
-								Address:  v,
-								Type:     "uplink",
-							})
-						}
-					}
-				}
-			}
-		}
+	if fmt.Sprintf("%v", fwForwards) == applied {
+		return nil
+	}
 
-		// Get all the instances.
-		instances, err := instance.LoadByProject(n.state, projectName)
-		if err != nil {
-			return nil, err
-		}
+	n.logger.Debug("Applying address forwards after target reachability change")
+	return n.forwardSetupFirewall()
+}
 
-		for _, inst := range instances {
-			// Go through all its devices (including profiles).
-			for k, dev := range inst.ExpandedDevices() {
-				// Skip uninteresting entries.
-				if dev["type"] != "nic" {
-					continue
-				}
+// State returns the state of the bridge interface, including the counters of the firewall rules generated for
+// the network's ACLs and address forwards (if any are in use).
+func (n *bridge) State() (*api.NetworkState, error) {
+	state, err := n.common.State()
+	if err != nil {
+		return nil, err
+	}
 
-				nicType, err := nictype.NICType(n.state, inst.Project(), dev)
-				if err != nil || nicType != "bridged" {
-					continue
-				}
+	state.Forkdns, err = n.ForkdnsStatus()
+	if err != nil {
+		return nil, err
+	}
 
-				// Temporarily populate parent from network setting if used.
-				if dev["network"] != "" {
-					dev["parent"] = dev["network"]
-				}
+	health := n.Health()
+	state.Health = &api.NetworkStateHealth{
+		Healthy:    health.Healthy(),
+		Subsystems: make(map[string]string, len(health)),
+	}
 
-				if dev["parent"] != n.name {
-					continue
-				}
+	for subsystem, healthErr := range health {
+		state.Health.Subsystems[subsystem] = ""
+		if healthErr != nil {
+			state.Health.Subsystems[subsystem] = healthErr.Error()
+		}
+	}
 
-				// Fill in the hwaddr from volatile.
-				if dev["hwaddr"] == "" {
-					dev["hwaddr"] = inst.LocalConfig()[fmt.Sprintf("volatile.%s.hwaddr", k)]
-				}
+	if state.Bridge != nil && state.Bridge.VLANFiltering {
+		state.Bridge.VLANs, err = n.VLANs()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-				// Record the MAC.
-				if dev["hwaddr"] != "" {
-					projectMacs = append(projectMacs, dev["hwaddr"])
-				}
+	forwardListenAddresses, err := n.state.Cluster.GetNetworkForwardListenAddresses(n.ID(), true)
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading network forwards: %w", err)
+	}
 
-				// Add the lease.
-				if dev["ipv4.address"] != "" {
-					leases = append(leases, api.NetworkLease{
-						Hostname: inst.Name(),
-						Address:  dev["ipv4.address"],
-						Hwaddr:   dev["hwaddr"],
-						Type:     "static",
-						Location: inst.Location(),
-					})
-				}
+	if !firewallManaged(n.config) || (n.config["security.acls"] == "" && len(forwardListenAddresses) == 0) {
+		return state, nil
+	}
+
+	counters, err := n.state.Firewall.NetworkCounters(n.name)
+	if err != nil {
+		return nil, err
+	}
+
+	state.Firewall = &api.NetworkStateFirewall{
+		ACLPackets:     int64(counters.ACLPackets),
+		ACLBytes:       int64(counters.ACLBytes),
+		ForwardPackets: int64(counters.ForwardPackets),
+		ForwardBytes:   int64(counters.ForwardBytes),
+	}
+
+	return state, nil
+}
+
+// VLANs returns the VLAN memberships of the bridge interface and of its ports, keyed by interface name.
+// Returns nil for openvswitch bridges, which don't use the kernel's bridge VLAN filtering.
+func (n *bridge) VLANs() (map[string][]api.NetworkStateBridgeVLAN, error) {
+	if n.config["bridge.driver"] == "openvswitch" {
+		return nil, nil
+	}
+
+	vlans := map[string][]api.NetworkStateBridgeVLAN{}
+
+	for _, ifaceName := range n.bridgeAndPortNames() {
+		// Skip external interfaces that don't currently exist.
+		if !InterfaceExists(ifaceName) {
+			continue
+		}
+
+		link := &ip.Link{Name: ifaceName}
+		ifaceVLANs, err := link.BridgeVLANs()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed getting VLANs of interface %q", ifaceName)
+		}
+
+		vlans[ifaceName] = make([]api.NetworkStateBridgeVLAN, 0, len(ifaceVLANs))
+		for _, vlan := range ifaceVLANs {
+			vlans[ifaceName] = append(vlans[ifaceName], api.NetworkStateBridgeVLAN{
+				VID:      vlan.VID,
+				PVID:     vlan.PVID,
+				Untagged: vlan.Untagged,
+			})
+		}
+	}
+
+	return vlans, nil
+}
+
+// bridgeAndPortNames returns the name of the bridge interface and of the interfaces connected to it.
+func (n *bridge) bridgeAndPortNames() []string {
+	ifaceNames := []string{n.name}
+
+	// Native bridges list their ports in sysfs, openvswitch bridges only have the external interfaces.
+	entries, _ := ioutil.ReadDir(fmt.Sprintf("/sys/class/net/%s/brif", n.name))
+	for _, entry := range entries {
+		ifaceNames = append(ifaceNames, entry.Name())
+	}
+
+	for _, ifaceName := range util.SplitNTrimSpace(n.config["bridge.external_interfaces"], ",", -1, true) {
+		if !shared.StringInSlice(ifaceName, ifaceNames) {
+			ifaceNames = append(ifaceNames, ifaceName)
+		}
+	}
+
+	return ifaceNames
+}
+
+// Health checks the bridge interface is up with its expected addresses and that its dnsmasq and forkdns
+// processes (when in use) are running.
+func (n *bridge) Health() BridgeHealth {
+	health := BridgeHealth{}
+
+	ips, isUp, err := InterfaceStatus(n.name)
+	if err == nil && !isUp {
+		err = fmt.Errorf("Interface %q is down", n.name)
+	}
+
+	health["interface"] = err
+	if err == nil {
+		health["addresses"] = n.healthAddresses(ips)
+	}
+
+	if n.UsesDNSMasq() {
+		health["dnsmasq"] = n.healthProcess("dnsmasq")
+	}
+
+	if n.config["bridge.mode"] == "fan" {
+		clusterAddress, err := node.ClusterAddress(n.state.Node)
+		if err != nil {
+			health["forkdns"] = err
+		} else if clusterAddress != "" {
+			health["forkdns"] = n.healthProcess("forkdns")
+		}
+	}
+
+	return health
+}
+
+// healthAddresses checks the configured IPv4 and IPv6 gateway addresses are present in the given list.
+func (n *bridge) healthAddresses(ips []net.IP) error {
+	for _, key := range []string{"ipv4.address", "ipv6.address"} {
+		if shared.StringInSlice(n.config[key], []string{"", "none"}) {
+			continue
+		}
+
+		expectedIP, _, err := net.ParseCIDR(n.config[key])
+		if err != nil {
+			return errors.Wrapf(err, "Failed parsing %q", key)
+		}
+
+		found := false
+		for _, ip := range ips {
+			if ip.Equal(expectedIP) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Address %q missing from interface %q", expectedIP.String(), n.name)
+		}
+	}
+
+	return nil
+}
+
+// healthProcess checks the named process is running based on its PID file.
+func (n *bridge) healthProcess(name string) error {
+	pidPath := shared.VarPath("networks", n.name, fmt.Sprintf("%s.pid", name))
+	if !shared.PathExists(pidPath) {
+		return fmt.Errorf("The %s process isn't running", name)
+	}
+
+	p, err := subprocess.ImportProcess(pidPath)
+	if err != nil {
+		return fmt.Errorf("Could not read pid file: %s", err)
+	}
+
+	_, err = p.GetPid()
+	if err != nil {
+		return fmt.Errorf("The %s process isn't running", name)
+	}
+
+	return nil
+}
+
+// ACLEvaluate evaluates whether a packet would be allowed by the network's ACLs (and default ACL rules) and which
+// rule it would match. This only uses the ACL config and doesn't check against the live firewall.
+func (n *bridge) ACLEvaluate(packet acl.FirewallPacket) (*acl.FirewallEvaluation, error) {
+	// Without any ACLs no ACL rules (including the default rules) are applied.
+	if n.config["security.acls"] == "" {
+		return &acl.FirewallEvaluation{Allowed: true, Action: "allow"}, nil
+	}
+
+	aclNet := acl.NetworkACLUsage{
+		Name:   n.Name(),
+		Type:   n.Type(),
+		ID:     n.ID(),
+		Config: n.Config(),
+	}
+
+	return acl.FirewallEvaluateACLRules(n.state, n.Project(), aclNet, packet)
+}
+
+// ACLLog returns the most recent kernel log entries (up to limit, or all if limit is 0) of traffic matched by the
+// network's logged ACL rules (including the default rules when security.acls.default.*.logged is set).
+func (n *bridge) ACLLog(limit int) ([]string, error) {
+	output, err := shared.RunCommand("dmesg", "--time-format", "iso")
+	if err != nil {
+		return nil, fmt.Errorf("Failed reading kernel log: %w", err)
+	}
+
+	// Logged rules are prefixed with "<network>-<direction>" (see acl.FirewallApplyACLRules).
+	prefixes := []string{fmt.Sprintf(" %s-ingress", n.name), fmt.Sprintf(" %s-egress", n.name)}
+
+	entries := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		for _, prefix := range prefixes {
+			if strings.Contains(line, prefix) {
+				entries = append(entries, line)
+				break
+			}
+		}
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	return entries, nil
+}
+
+// dhcpDefaultRanges returns the default DHCP range between start and end for the IP family (ipv4 or ipv6).
+// When the bridge is used as an uplink, the ranges reserved in ipv4.ovn.ranges or ipv6.ovn.ranges for the
+// child OVN network routers are excluded from it, so that dnsmasq doesn't hand those addresses out to instances.
+func (n *bridge) dhcpDefaultRanges(keyPrefix string, subnet *net.IPNet, start net.IP, end net.IP) ([]*shared.IPRange, error) {
+	dhcpRanges := []*shared.IPRange{{Start: start, End: end}}
+
+	ovnRangesKey := fmt.Sprintf("%s.ovn.ranges", keyPrefix)
+	if n.config[ovnRangesKey] == "" {
+		return dhcpRanges, nil
+	}
+
+	ovnRanges, err := parseIPRanges(n.config[ovnRangesKey], subnet)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed parsing %s", ovnRangesKey)
+	}
+
+	return excludeIPRanges(dhcpRanges, ovnRanges), nil
+}
+
+// DHCPv4Ranges returns the DHCPv4 ranges of the network. Without ipv4.dhcp.ranges but with ipv4.ovn.ranges set,
+// these are the default ranges handed out by dnsmasq (excluding the OVN ranges), so that static allocations also
+// stay clear of the addresses reserved for the OVN routers.
+func (n *bridge) DHCPv4Ranges() []shared.IPRange {
+	if n.config["ipv4.dhcp.ranges"] != "" || n.config["ipv4.ovn.ranges"] == "" {
+		return n.common.DHCPv4Ranges()
+	}
+
+	_, subnet, err := net.ParseCIDR(n.config["ipv4.address"])
+	if err != nil {
+		return n.common.DHCPv4Ranges()
+	}
+
+	dhcpRanges, err := n.dhcpDefaultRanges("ipv4", subnet, dhcpalloc.GetIP(subnet, 2), dhcpalloc.GetIP(subnet, -2))
+	if err != nil {
+		return n.common.DHCPv4Ranges()
+	}
+
+	ranges := make([]shared.IPRange, 0, len(dhcpRanges))
+	for _, dhcpRange := range dhcpRanges {
+		ranges = append(ranges, shared.IPRange{Start: dhcpRange.Start.To4(), End: dhcpRange.End.To4()})
+	}
+
+	return ranges
+}
+
+// DHCPv6Ranges returns the DHCPv6 ranges of the network. Without ipv6.dhcp.ranges but with ipv6.ovn.ranges set,
+// these are the default ranges handed out by dnsmasq (excluding the OVN ranges), so that static allocations also
+// stay clear of the addresses reserved for the OVN routers.
+func (n *bridge) DHCPv6Ranges() []shared.IPRange {
+	if n.config["ipv6.dhcp.ranges"] != "" || n.config["ipv6.ovn.ranges"] == "" {
+		return n.common.DHCPv6Ranges()
+	}
+
+	_, subnet, err := net.ParseCIDR(n.config["ipv6.address"])
+	if err != nil {
+		return n.common.DHCPv6Ranges()
+	}
+
+	dhcpRanges, err := n.dhcpDefaultRanges("ipv6", subnet, dhcpalloc.GetIP(subnet, 2), dhcpalloc.GetIP(subnet, -1))
+	if err != nil {
+		return n.common.DHCPv6Ranges()
+	}
+
+	ranges := make([]shared.IPRange, 0, len(dhcpRanges))
+	for _, dhcpRange := range dhcpRanges {
+		ranges = append(ranges, shared.IPRange{Start: dhcpRange.Start.To16(), End: dhcpRange.End.To16()})
+	}
+
+	return ranges
+}
+
+// ACLScheduleRefresh re-applies the network's firewall ACL rules if any of its scheduled ACL rules has become
+// active or inactive since the specified time.
+func (n *bridge) ACLScheduleRefresh(since time.Time) error {
+	if n.config["security.acls"] == "" || !firewallManaged(n.config) || !n.isRunning() {
+		return nil
+	}
 
-				if dev["ipv6.address"] != "" {
+	aclNet := acl.NetworkACLUsage{
+		Name:   n.Name(),
+		Type:   n.Type(),
+		ID:     n.ID(),
+		Config: n.Config(),
+	}
+
+	changed, err := acl.FirewallACLScheduleChanged(n.state, n.Project(), aclNet, since, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	n.logger.Debug("Applying scheduled firewall ACL changes")
+	return acl.FirewallApplyACLRules(n.state, n.logger, n.Project(), aclNet)
+}
+
+// setupPortIsolation applies the bridge.port_isolation setting to the host side interfaces of the instance NICs
+// connected to the bridge on this member. NICs with security.port_isolation enabled are always left isolated.
+// Other bridge ports (such as external interfaces, tunnels and the fan) are never isolated.
+func (n *bridge) setupPortIsolation() error {
+	isolated := shared.IsTrue(n.config["bridge.port_isolation"])
+
+	return usedByInstanceDevices(n.state, n.project, n.name, func(inst db.Instance, nicName string, nicConfig map[string]string) error {
+		hostName := inst.Config[fmt.Sprintf("volatile.%s.host_name", nicName)]
+		if hostName == "" || !shared.PathExists(fmt.Sprintf("/sys/class/net/%s/brif/%s", n.name, hostName)) {
+			return nil // NIC isn't connected to the bridge on this member.
+		}
+
+		link := &ip.Link{Name: hostName}
+		err := link.BridgeLinkSetIsolated(isolated || shared.IsTrue(nicConfig["security.port_isolation"]))
+		if err != nil {
+			return errors.Wrapf(err, "Failed setting isolation on bridge port %q", hostName)
+		}
+
+		return nil
+	})
+}
+
+// leasesPath returns the path of the dnsmasq leases file, which can be overridden with dns.leasefile.
+// With dns.mode set to external, dns.leasefile is the dnsmasq format leases file of the external DHCP server.
+func (n *bridge) leasesPath() string {
+	if dnsmasq.IsLeasesPath(n.config["dns.leasefile"]) {
+		return n.config["dns.leasefile"]
+	}
+
+	return shared.VarPath("networks", n.name, "dnsmasq.leases")
+}
+
+// setupLeasesFile replaces the default leases file with a symlink to dns.leasefile when set, so that the leases
+// are also found by everything looking for them at the default location. Existing leases are moved over.
+func (n *bridge) setupLeasesFile() error {
+	defaultPath := shared.VarPath("networks", n.name, "dnsmasq.leases")
+	leasesPath := n.leasesPath()
+
+	fi, err := os.Lstat(defaultPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		target, _ := os.Readlink(defaultPath)
+		if target == leasesPath {
+			return nil // Already setup.
+		}
+
+		// Remove the link to the old location, the leases there are left in place.
+		err = os.Remove(defaultPath)
+		if err != nil {
+			return errors.Wrapf(err, "Failed removing leases file link %q", defaultPath)
+		}
+	} else if err == nil && leasesPath != defaultPath {
+		// Move the existing leases to the new location unless there already are leases there.
+		if shared.PathExists(leasesPath) {
+			err = os.Remove(defaultPath)
+		} else {
+			err = shared.FileMove(defaultPath, leasesPath)
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "Failed moving leases file to %q", leasesPath)
+		}
+	}
+
+	if leasesPath == defaultPath {
+		return nil
+	}
+
+	err = os.Symlink(leasesPath, defaultPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed linking leases file to %q", leasesPath)
+	}
+
+	return nil
+}
+
+// checkStaticNICAddresses checks that the static IP addresses of the instance NICs using the network are within
+// the subnets of the supplied network config. Otherwise dnsmasq would refuse to hand them out.
+func (n *bridge) checkStaticNICAddresses(config map[string]string) error {
+	return usedByInstanceDevices(n.state, n.project, n.name, func(inst db.Instance, nicName string, nicConfig map[string]string) error {
+		for _, keyPrefix := range []string{"ipv4", "ipv6"} {
+			key := fmt.Sprintf("%s.address", keyPrefix)
+			if nicConfig[key] == "" || nicConfig[key] == "none" || shared.StringInSlice(config[key], []string{"", "none"}) {
+				continue
+			}
+
+			_, subnet, err := net.ParseCIDR(config[key])
+			if err != nil {
+				return errors.Wrapf(err, "Invalid %q", key)
+			}
+
+			if !subnet.Contains(net.ParseIP(nicConfig[key])) {
+				return fmt.Errorf("Device %q of instance %q in project %q has %s %q which is not within the new subnet %q", nicName, inst.Name, inst.Project, key, nicConfig[key], subnet.String())
+			}
+		}
+
+		return nil
+	})
+}
+
+// DHCPv4LeaseUsage returns the number of addresses in the DHCPv4 pool currently leased out by the local dnsmasq
+// along with the size of the pool. The pool is made of the ranges dnsmasq hands out addresses from (see
+// DHCPv4Ranges), so excludes the OVN ranges. Both are zero if DHCPv4 is disabled.
+func (n *bridge) DHCPv4LeaseUsage() (uint64, uint64, error) {
+	dhcpSubnet := n.DHCPv4Subnet()
+	if dhcpSubnet == nil {
+		return 0, 0, nil
+	}
+
+	dhcpRanges := n.DHCPv4Ranges()
+	if len(dhcpRanges) == 0 {
+		dhcpRanges = []shared.IPRange{{Start: dhcpalloc.GetIP(dhcpSubnet, 2).To4(), End: dhcpalloc.GetIP(dhcpSubnet, -2).To4()}}
+	}
+
+	var size uint64
+	for _, dhcpRange := range dhcpRanges {
+		if dhcpRange.Start.To4() == nil || dhcpRange.End.To4() == nil {
+			continue
+		}
+
+		start := binary.BigEndian.Uint32(dhcpRange.Start.To4())
+		end := binary.BigEndian.Uint32(dhcpRange.End.To4())
+		if end >= start {
+			size += uint64(end-start) + 1
+		}
+	}
+
+	leaseFile := n.leasesPath()
+	if !shared.PathExists(leaseFile) {
+		return 0, size, nil
+	}
+
+	content, err := ioutil.ReadFile(leaseFile)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var used uint64
+	for _, lease := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(lease)
+		if len(fields) < 5 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[2])
+		if ip == nil || ip.To4() == nil {
+			continue
+		}
+
+		for _, dhcpRange := range dhcpRanges {
+			if dhcpRange.ContainsIP(ip) {
+				used++
+				break
+			}
+		}
+	}
+
+	return used, size, nil
+}
+
+// Reservations returns the addresses allocated by configuration to the instances of the given project that are
+// connected to the network. These are the static addresses of their NICs and the EUI64 derived IPv6 addresses.
+// Unlike Leases, it doesn't report the leases currently held, so that external IPAM tools can compare them.
+func (n *bridge) Reservations(projectName string) ([]api.NetworkLease, error) {
+	leases, _, err := n.instanceReservations(projectName, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return leases, nil
+}
+
+// instanceReservations returns the static and EUI64 leases of the instances of the given project connected to
+// the network, along with the MAC addresses of their NICs. The EUI64 leases are skipped unless eui64Leases.
+func (n *bridge) instanceReservations(projectName string, eui64Leases bool) ([]api.NetworkLease, []string, error) {
+	leases := []api.NetworkLease{}
+	projectMacs := []string{}
+
+	// Get all the instances.
+	instances, err := instance.LoadByProject(n.state, projectName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, inst := range instances {
+		instLeases, instMacs := n.instanceNICReservations(inst, eui64Leases)
+		leases = append(leases, instLeases...)
+		projectMacs = append(projectMacs, instMacs...)
+	}
+
+	return leases, projectMacs, nil
+}
+
+// instanceNICReservations returns the static and EUI64 leases of the NICs of an instance connected to the
+// network, along with their MAC addresses. The EUI64 leases are skipped unless eui64Leases.
+func (n *bridge) instanceNICReservations(inst instance.Instance, eui64Leases bool) ([]api.NetworkLease, []string) {
+	leases := []api.NetworkLease{}
+	macs := []string{}
+
+	// Go through all its devices (including profiles).
+	for k, dev := range inst.ExpandedDevices() {
+		// Skip uninteresting entries.
+		if dev["type"] != "nic" {
+			continue
+		}
+
+		nicType, err := nictype.NICType(n.state, inst.Project(), dev)
+		if err != nil || nicType != "bridged" {
+			continue
+		}
+
+		// Temporarily populate parent from network setting if used.
+		if dev["network"] != "" {
+			dev["parent"] = dev["network"]
+		}
+
+		if dev["parent"] != n.name {
+			continue
+		}
+
+		// Fill in the hwaddr from volatile.
+		if dev["hwaddr"] == "" {
+			dev["hwaddr"] = inst.LocalConfig()[fmt.Sprintf("volatile.%s.hwaddr", k)]
+		}
+
+		// Record the MAC.
+		if dev["hwaddr"] != "" {
+			macs = append(macs, dev["hwaddr"])
+		}
+
+		// Use the custom DNS name if set.
+		hostname := inst.Name()
+		if dev["dns.name"] != "" {
+			hostname = dev["dns.name"]
+		}
+
+		// Add the lease.
+		if dev["ipv4.address"] != "" {
+			leases = append(leases, api.NetworkLease{
+				Hostname: hostname,
+				Address:  dev["ipv4.address"],
+				Hwaddr:   dev["hwaddr"],
+				Type:     "static",
+				Location: inst.Location(),
+			})
+		}
+
+		if dev["ipv6.address"] != "" {
+			leases = append(leases, api.NetworkLease{
+				Hostname: hostname,
+				Address:  dev["ipv6.address"],
+				Hwaddr:   dev["hwaddr"],
+				Type:     "static",
+				Location: inst.Location(),
+			})
+		}
+
+		// Add EUI64 records.
+		ipv6Address := n.config["ipv6.address"]
+		if eui64Leases && ipv6Address != "" && ipv6Address != "none" && !shared.IsTrue(n.config["ipv6.dhcp.stateful"]) {
+			_, netAddress, _ := net.ParseCIDR(ipv6Address)
+			hwAddr, _ := net.ParseMAC(dev["hwaddr"])
+			if netAddress != nil && hwAddr != nil {
+				ipv6, err := eui64.ParseMAC(netAddress.IP, hwAddr)
+				if err == nil {
 					leases = append(leases, api.NetworkLease{
-						Hostname: inst.Name(),
-						Address:  dev["ipv6.address"],
+						Hostname: hostname,
+						Address:  ipv6.String(),
 						Hwaddr:   dev["hwaddr"],
-						Type:     "static",
+						Type:     "dynamic",
 						Location: inst.Location(),
 					})
 				}
+			}
+		}
+	}
 
-				// Add EUI64 records.
-				ipv6Address := n.config["ipv6.address"]
-				if ipv6Address != "" && ipv6Address != "none" && !shared.IsTrue(n.config["ipv6.dhcp.stateful"]) {
-					_, netAddress, _ := net.ParseCIDR(ipv6Address)
-					hwAddr, _ := net.ParseMAC(dev["hwaddr"])
-					if netAddress != nil && hwAddr != nil {
-						ipv6, err := eui64.ParseMAC(netAddress.IP, hwAddr)
-						if err == nil {
+	return leases, macs
+}
+
+// LeasesForInstance returns the leases of a single instance connected to the network. Unlike Leases, only that
+// instance is loaded and its dynamic leases are only requested from the cluster member it is running on.
+func (n *bridge) LeasesForInstance(projectName string, instanceName string) ([]api.NetworkLease, error) {
+	inst, err := instance.LoadByProjectAndName(n.state, projectName, instanceName)
+	if err != nil {
+		return nil, err
+	}
+
+	leases, macs := n.instanceNICReservations(inst, true)
+	if len(macs) == 0 {
+		return leases, nil
+	}
+
+	var serverName string
+	var memberAddress string
+	err = n.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		serverName, err = tx.GetLocalNodeName()
+		if err != nil {
+			return err
+		}
+
+		if inst.Location() == serverName {
+			return nil
+		}
+
+		member, err := tx.GetNodeByName(inst.Location())
+		if err != nil {
+			return err
+		}
+
+		memberAddress = member.Address
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the dynamic leases held on the member the instance is running on.
+	var dynamicLeases []api.NetworkLease
+	if memberAddress == "" {
+		dynamicLeases, err = n.Leases(projectName, request.ClientTypeNotifier)
+	} else {
+		var client lxd.InstanceServer
+		client, err = cluster.Connect(memberAddress, n.state.Endpoints.NetworkCert(), n.state.ServerCert(), nil, true)
+		if err == nil {
+			dynamicLeases, err = client.GetNetworkLeases(n.name)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	activeMacs := make(map[string]bool)
+	activeAddresses := make(map[string]bool)
+	for _, lease := range dynamicLeases {
+		// DHCPv6 leases have no MAC, so the static IPv6 leases are matched on their address instead.
+		if lease.Hwaddr == "" {
+			activeAddresses[lease.Address] = true
+			continue
+		}
+
+		if !shared.StringInSlice(lease.Hwaddr, macs) {
+			continue
+		}
+
+		activeMacs[lease.Hwaddr] = true
+
+		// Skip the leases of the static addresses that were already added.
+		found := false
+		for _, entry := range leases {
+			if entry.Hwaddr == lease.Hwaddr && entry.Address == lease.Address {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			leases = append(leases, lease)
+		}
+	}
+
+	markActiveStaticLeases(leases, activeMacs, activeAddresses)
+
+	return leases, nil
+}
+
+// Leases returns a list of leases for the bridged network. It will reach out to other cluster members as needed.
+// The projectName passed here refers to the initial project from the API request which may differ from the network's project.
+func (n *bridge) Leases(projectName string, clientType request.ClientType) ([]api.NetworkLease, error) {
+	return n.leases(projectName, clientType, true)
+}
+
+// LeasesWithoutEUI64 returns the same leases as Leases except for the EUI64 derived IPv6 addresses of the
+// instances, saving their computation for callers only interested in the actual leases.
+func (n *bridge) LeasesWithoutEUI64(projectName string, clientType request.ClientType) ([]api.NetworkLease, error) {
+	return n.leases(projectName, clientType, false)
+}
+
+// leases returns the leases of the network, including the EUI64 derived addresses of the instances if eui64Leases.
+func (n *bridge) leases(projectName string, clientType request.ClientType, eui64Leases bool) ([]api.NetworkLease, error) {
+	leases := []api.NetworkLease{}
+	projectMacs := []string{}
+
+	// Get all static leases.
+	if clientType == request.ClientTypeNormal {
+		// Get the downstream networks.
+		if n.project == project.Default {
+			var err error
+
+			// Load all the networks.
+			var projectNetworks map[string]map[int64]api.Network
+			err = n.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+				projectNetworks, err = tx.GetCreatedNetworks()
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			// Look for networks using the current network as an uplink.
+			for projectName, networks := range projectNetworks {
+				for _, network := range networks {
+					if network.Config["network"] != n.name {
+						continue
+					}
+
+					// Found a network, add leases.
+					for _, k := range []string{"volatile.network.ipv4.address", "volatile.network.ipv6.address"} {
+						v := network.Config[k]
+						if v != "" {
 							leases = append(leases, api.NetworkLease{
-								Hostname: inst.Name(),
-								Address:  ipv6.String(),
-								Hwaddr:   dev["hwaddr"],
-								Type:     "dynamic",
-								Location: inst.Location(),
+								Hostname: fmt.Sprintf("%s-%s.uplink", projectName, network.Name),
+								Address:  v,
+								Type:     "uplink",
 							})
 						}
 					}
 				}
 			}
 		}
+
+		// Get the static and EUI64 leases of the instances.
+		instLeases, instMacs, err := n.instanceReservations(projectName, eui64Leases)
+		if err != nil {
+			return nil, err
+		}
+
+		leases = append(leases, instLeases...)
+		projectMacs = append(projectMacs, instMacs...)
 	}
 
 	// Local server name.
@@ -2983,7 +4897,7 @@ func (n *bridge) Leases(projectName string, clientType request.ClientType) ([]ap
 	}
 
 	// Get dynamic leases.
-	leaseFile := shared.VarPath("networks", n.name, "dnsmasq.leases")
+	leaseFile := n.leasesPath()
 	if !shared.PathExists(leaseFile) {
 		return leases, nil
 	}
@@ -2993,6 +4907,13 @@ func (n *bridge) Leases(projectName string, clientType request.ClientType) ([]ap
 		return nil, err
 	}
 
+	// Number of DHCPv4 leases held by each MAC address.
+	macLeases := make(map[string]int)
+
+	// MAC addresses (DHCPv4) and addresses (DHCPv6) currently leased, used to tell which static leases are in use.
+	activeMacs := make(map[string]bool)
+	activeAddresses := make(map[string]bool)
+
 	for _, lease := range strings.Split(string(content), "\n") {
 		fields := strings.Fields(lease)
 		if len(fields) >= 5 {
@@ -3004,6 +4925,13 @@ func (n *bridge) Leases(projectName string, clientType request.ClientType) ([]ap
 				macStr = fields[4][len(fields[4])-17:]
 			}
 
+			if !strings.Contains(fields[2], ":") {
+				macLeases[macStr]++
+				activeMacs[macStr] = true
+			} else {
+				activeAddresses[fields[2]] = true
+			}
+
 			// Look for an existing static entry.
 			found := false
 			for _, entry := range leases {
@@ -3041,6 +4969,8 @@ func (n *bridge) Leases(projectName string, clientType request.ClientType) ([]ap
 		}
 	}
 
+	n.checkDHCPLeaseLimit(macLeases)
+
 	// Collect leases from other servers.
 	if clientType == request.ClientTypeNormal {
 		notifier, err := cluster.NewNotifier(n.state, n.state.Endpoints.NetworkCert(), n.state.ServerCert(), cluster.NotifyAll)
@@ -3056,8 +4986,13 @@ func (n *bridge) Leases(projectName string, clientType request.ClientType) ([]ap
 
 			// Add local leases from other members, filtering them for MACs that belong to the project.
 			for _, lease := range memberLeases {
+				if lease.Hwaddr == "" {
+					activeAddresses[lease.Address] = true
+				}
+
 				if lease.Hwaddr != "" && shared.StringInSlice(lease.Hwaddr, projectMacs) {
 					leases = append(leases, lease)
+					activeMacs[lease.Hwaddr] = true
 				}
 			}
 
@@ -3068,10 +5003,65 @@ func (n *bridge) Leases(projectName string, clientType request.ClientType) ([]ap
 		}
 	}
 
+	markActiveStaticLeases(leases, activeMacs, activeAddresses)
+
 	return leases, nil
 }
 
+// markActiveStaticLeases flags the static leases whose MAC address currently holds a DHCPv4 lease, or whose
+// address is currently leased over DHCPv6 (as DHCPv6 leases can't be tracked down to a MAC).
+func markActiveStaticLeases(leases []api.NetworkLease, activeMacs map[string]bool, activeAddresses map[string]bool) {
+	for i := range leases {
+		if leases[i].Type != "static" {
+			continue
+		}
+
+		if activeMacs[leases[i].Hwaddr] || activeAddresses[leases[i].Address] {
+			leases[i].Active = true
+		}
+	}
+}
+
+// checkDHCPLeaseLimit flags any MAC address holding more DHCPv4 leases than allowed by the
+// ipv4.dhcp.max_leases_per_mac setting, which can indicate an instance exhausting the pool using client identifiers.
+func (n *bridge) checkDHCPLeaseLimit(macLeases map[string]int) {
+	if n.config["ipv4.dhcp.max_leases_per_mac"] == "" {
+		return
+	}
+
+	maxLeases, err := strconv.Atoi(n.config["ipv4.dhcp.max_leases_per_mac"])
+	if err != nil {
+		return
+	}
+
+	exceeded := []string{}
+	for mac, count := range macLeases {
+		if count > maxLeases {
+			n.logger.Warn("MAC address exceeds DHCP lease limit", log.Ctx{"hwaddr": mac, "leases": count, "limit": maxLeases})
+			exceeded = append(exceeded, fmt.Sprintf("%s (%d leases)", mac, count))
+		}
+	}
+
+	if len(exceeded) > 0 {
+		sort.Strings(exceeded)
+		err = n.state.Cluster.UpsertWarningLocalNode(n.project, dbCluster.TypeNetwork, int(n.id), db.WarningNetworkDHCPLeaseLimitExceeded, strings.Join(exceeded, ", "))
+		if err != nil {
+			n.logger.Warn("Failed to create warning", log.Ctx{"err": err})
+		}
+	} else {
+		err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(n.state.Cluster, n.project, db.WarningNetworkDHCPLeaseLimitExceeded, dbCluster.TypeNetwork, int(n.id))
+		if err != nil {
+			n.logger.Warn("Failed to resolve warning", log.Ctx{"err": err})
+		}
+	}
+}
+
 // UsesDNSMasq indicates if network's config indicates if it needs to use dnsmasq.
+// Networks whose DHCP and DNS are provided externally (dns.mode set to external) never use it.
 func (n *bridge) UsesDNSMasq() bool {
+	if n.config["dns.mode"] == "external" {
+		return false
+	}
+
 	return n.config["bridge.mode"] == "fan" || !shared.StringInSlice(n.config["ipv4.address"], []string{"", "none"}) || !shared.StringInSlice(n.config["ipv6.address"], []string{"", "none"})
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -11,11 +12,13 @@ import (
 	log "gopkg.in/inconshreveable/log15.v2"
 
 	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/bgp"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/cluster/request"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/network/acl"
 	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
@@ -32,14 +35,22 @@ type Info struct {
 	NodeSpecificConfig bool // Whether driver has cluster node specific config as a prerequisite for creation.
 	AddressForwards    bool // Indicates if driver supports address forwards.
 	Peering            bool // Indicates if the driver supports network peering.
+	ACLs               bool // Indicates if the driver supports network ACLs.
+	BGP                bool // Indicates if the driver supports advertising its subnets and forwards via BGP.
+	Fan                bool // Indicates if the driver supports the fan overlay mode.
+	Tunnels            bool // Indicates if the driver supports tunnels to remote hosts.
+
+	// ConfigKeys is the sorted list of config keys supported by the driver.
+	// Keys containing a user chosen name (such as a tunnel or BGP peer name) use "NAME" in its place.
+	ConfigKeys []string
 }
 
 // forwardPortMap represents a mapping of listen port(s) to target port(s) for a protocol/target address pair.
 type forwardPortMap struct {
-	listenPorts   []uint64
-	targetPorts   []uint64
-	targetAddress net.IP
-	protocol      string
+	listenPorts     []uint64
+	targetPorts     []uint64
+	targetAddresses []net.IP // In order of preference, with the failover targets after the first.
+	protocol        string
 }
 
 // externalSubnetUsage represents usage of a subnet by a network or NIC.
@@ -86,6 +97,20 @@ func (n *common) FillConfig(config map[string]string) error {
 	return nil
 }
 
+// configKeys returns the sorted list of config keys from the supplied keys and driver rules.
+func configKeys(keys []string, rules ...map[string]func(value string) error) []string {
+	keys = append([]string{}, keys...)
+	for _, driverRules := range rules {
+		for k := range driverRules {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
 // validationRules returns a map of config rules common to all drivers.
 func (n *common) validationRules() map[string]func(string) error {
 	return map[string]func(string) error{}
@@ -269,6 +294,8 @@ func (n *common) DHCPv6Ranges() []shared.IPRange {
 
 // update the internal config variables, and if not cluster notification, notifies all nodes and updates database.
 func (n *common) update(applyNetwork api.NetworkPut, targetNode string, clientType request.ClientType) error {
+	oldConfig := n.config
+
 	// Update internal config before database has been updated (so that if update is a notification we apply
 	// the config being supplied and not that in the database).
 	n.description = applyNetwork.Description
@@ -308,11 +335,33 @@ func (n *common) update(applyNetwork api.NetworkPut, targetNode string, clientTy
 		if err != nil {
 			return err
 		}
+
+		n.notifyZones(oldConfig, applyNetwork.Config)
 	}
 
 	return nil
 }
 
+// notifyZones sends DNS NOTIFY messages for the zones whose content may have changed between the old and new
+// network config, so that secondaries pick up the change without waiting for the zone refresh interval.
+func (n *common) notifyZones(oldConfig map[string]string, newConfig map[string]string) {
+	zones := []string{}
+	for _, config := range []map[string]string{oldConfig, newConfig} {
+		for _, key := range []string{"dns.zone.forward", "dns.zone.reverse.ipv4", "dns.zone.reverse.ipv6"} {
+			if config[key] != "" && !shared.StringInSlice(config[key], zones) {
+				zones = append(zones, config[key])
+			}
+		}
+	}
+
+	for _, zoneName := range zones {
+		err := n.state.DNS.NotifyZone(zoneName)
+		if err != nil {
+			n.logger.Warn("Failed notifying DNS peers of zone change", log.Ctx{"zone": zoneName, "err": err})
+		}
+	}
+}
+
 // configChanged compares supplied new config with existing config. Returns a boolean indicating if differences in
 // the config or description were found (and the database record needs updating), and a list of non-user config
 // keys that have changed, and a copy of the current internal network config that can be used to revert if needed.
@@ -464,9 +513,10 @@ func (n *common) handleDependencyChange(netName string, netConfig map[string]str
 // bgpValidate
 func (n *common) bgpValidationRules(config map[string]string) (map[string]func(value string) error, error) {
 	rules := map[string]func(value string) error{}
+
 	for k := range config {
-		// BGP keys have the peer name in their name, extract the suffix.
-		if !strings.HasPrefix(k, "bgp.") {
+		// BGP peer keys have the peer name in their name, extract the suffix.
+		if !strings.HasPrefix(k, "bgp.peers.") {
 			continue
 		}
 
@@ -536,6 +586,22 @@ func (n *common) bgpClear(config map[string]string) error {
 	return nil
 }
 
+// bgpGracefulShutdown re-advertises the network's BGP prefixes with the GRACEFUL_SHUTDOWN community, so that
+// peers can reroute traffic before the prefixes are withdrawn, and returns the number of prefixes re-advertised.
+func (n *common) bgpGracefulShutdown() (int, error) {
+	advertised := 0
+	for _, bgpOwner := range []string{fmt.Sprintf("network_%d", n.id), fmt.Sprintf("network_%d_forward", n.id)} {
+		count, err := n.state.BGP.GracefulShutdownByOwner(bgpOwner)
+		if err != nil {
+			return 0, fmt.Errorf("Failed advertising BGP graceful shutdown: %w", err)
+		}
+
+		advertised += count
+	}
+
+	return advertised, nil
+}
+
 // bgpClearPeers removes all BGP peers on the network.
 func (n *common) bgpClearPeers(config map[string]string) error {
 	peers := n.bgpGetPeers(config)
@@ -611,6 +677,33 @@ func (n *common) bgpNextHopAddress(ipVersion uint) net.IP {
 	return nextHopAddr
 }
 
+// bgpPrefixAttributes parses the community and local preference configuration and returns the path attributes
+// to use for BGP routes of the given IP version.
+func (n *common) bgpPrefixAttributes(ipVersion uint) (bgp.PrefixAttributes, error) {
+	attrs := bgp.PrefixAttributes{}
+
+	communityKey := fmt.Sprintf("bgp.ipv%d.community", ipVersion)
+	if n.config[communityKey] != "" {
+		communities, err := bgp.ParseCommunities(n.config[communityKey])
+		if err != nil {
+			return attrs, fmt.Errorf("Failed parsing %q: %w", communityKey, err)
+		}
+
+		attrs.Communities = communities
+	}
+
+	if n.config["bgp.local_pref"] != "" {
+		localPref, err := strconv.ParseUint(n.config["bgp.local_pref"], 10, 32)
+		if err != nil {
+			return attrs, fmt.Errorf("Failed parsing %q: %w", "bgp.local_pref", err)
+		}
+
+		attrs.LocalPref = uint32(localPref)
+	}
+
+	return attrs, nil
+}
+
 // bgpSetupPrefixes refreshes the prefix list for the network.
 func (n *common) bgpSetupPrefixes(oldConfig map[string]string) error {
 	// Clear existing prefixes.
@@ -625,6 +718,10 @@ func (n *common) bgpSetupPrefixes(oldConfig map[string]string) error {
 	// Add the new prefixes.
 	for _, ipVersion := range []uint{4, 6} {
 		nextHopAddr := n.bgpNextHopAddress(ipVersion)
+		attrs, err := n.bgpPrefixAttributes(ipVersion)
+		if err != nil {
+			return err
+		}
 
 		// If network has NAT enabled, then export network's NAT address if specified.
 		if shared.IsTrue(n.config[fmt.Sprintf("ipv%d.nat", ipVersion)]) {
@@ -640,7 +737,7 @@ func (n *common) bgpSetupPrefixes(oldConfig map[string]string) error {
 					return err
 				}
 
-				err = n.state.BGP.AddPrefix(*subnet, nextHopAddr, bgpOwner)
+				err = n.state.BGP.AddPrefixWithAttributes(*subnet, nextHopAddr, bgpOwner, attrs)
 				if err != nil {
 					return err
 				}
@@ -653,7 +750,7 @@ func (n *common) bgpSetupPrefixes(oldConfig map[string]string) error {
 				return fmt.Errorf("Failed parsing network address %q: %w", netAddress, err)
 			}
 
-			err = n.state.BGP.AddPrefix(*subnet, nextHopAddr, bgpOwner)
+			err = n.state.BGP.AddPrefixWithAttributes(*subnet, nextHopAddr, bgpOwner, attrs)
 			if err != nil {
 				return err
 			}
@@ -720,7 +817,7 @@ func (n *common) forwardValidate(listenAddress net.IP, forward *api.NetworkForwa
 
 	// Look for any unknown config fields.
 	for k := range forward.Config {
-		if k == "target_address" {
+		if k == "target_address" || k == "bgp.advertise" {
 			continue
 		}
 
@@ -732,6 +829,11 @@ func (n *common) forwardValidate(listenAddress net.IP, forward *api.NetworkForwa
 		return nil, fmt.Errorf("Invalid option option %q", k)
 	}
 
+	err = validate.Optional(validate.IsBool)(forward.Config["bgp.advertise"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid value for option %q", "bgp.advertise")
+	}
+
 	// Validate default target address.
 	defaultTargetAddress := net.ParseIP(forward.Config["target_address"])
 
@@ -768,23 +870,40 @@ func (n *common) forwardValidate(listenAddress net.IP, forward *api.NetworkForwa
 			return nil, fmt.Errorf("Invalid port protocol in port specification %d, protocol must be one of: %s", portSpecID, strings.Join(validPortProcols, ", "))
 		}
 
-		targetAddress := net.ParseIP(portSpec.TargetAddress)
-		if targetAddress == nil {
+		// Multiple target addresses can be specified for failover.
+		targetAddressList := util.SplitNTrimSpace(portSpec.TargetAddress, ",", -1, true)
+		if len(targetAddressList) <= 0 {
 			return nil, fmt.Errorf("Invalid target address in port specification %d", portSpecID)
 		}
 
-		if targetAddress.Equal(defaultTargetAddress) {
-			return nil, fmt.Errorf("Target address is same as default target address in port specification %d", portSpecID)
-		}
+		targetAddresses := make([]net.IP, 0, len(targetAddressList))
+		for _, targetAddressStr := range targetAddressList {
+			targetAddress := net.ParseIP(targetAddressStr)
+			if targetAddress == nil {
+				return nil, fmt.Errorf("Invalid target address in port specification %d", portSpecID)
+			}
 
-		targetIsIP4 := targetAddress.To4() != nil
-		if listenIsIP4 != targetIsIP4 {
-			return nil, fmt.Errorf("Cannot mix IP versions in listen address and port specification %d target address", portSpecID)
-		}
+			if targetAddress.Equal(defaultTargetAddress) {
+				return nil, fmt.Errorf("Target address is same as default target address in port specification %d", portSpecID)
+			}
+
+			targetIsIP4 := targetAddress.To4() != nil
+			if listenIsIP4 != targetIsIP4 {
+				return nil, fmt.Errorf("Cannot mix IP versions in listen address and port specification %d target address", portSpecID)
+			}
+
+			// Check target address is within network's subnet.
+			if netSubnet != nil && !SubnetContainsIP(netSubnet, targetAddress) {
+				return nil, fmt.Errorf("Target address is not within the network subnet in port specification %d", portSpecID)
+			}
+
+			for _, existing := range targetAddresses {
+				if existing.Equal(targetAddress) {
+					return nil, fmt.Errorf("Duplicate target address %q in port specification %d", targetAddress.String(), portSpecID)
+				}
+			}
 
-		// Check target address is within network's subnet.
-		if netSubnet != nil && !SubnetContainsIP(netSubnet, targetAddress) {
-			return nil, fmt.Errorf("Target address is not within the network subnet in port specification %d", portSpecID)
+			targetAddresses = append(targetAddresses, targetAddress)
 		}
 
 		// Check valid listen port(s) supplied.
@@ -794,9 +913,9 @@ func (n *common) forwardValidate(listenAddress net.IP, forward *api.NetworkForwa
 		}
 
 		portMap := forwardPortMap{
-			listenPorts:   make([]uint64, 0),
-			targetAddress: targetAddress,
-			protocol:      portSpec.Protocol,
+			listenPorts:     make([]uint64, 0),
+			targetAddresses: targetAddresses,
+			protocol:        portSpec.Protocol,
 		}
 
 		for _, pr := range listenPortRanges {
@@ -872,7 +991,7 @@ func (n *common) ForwardDelete(listenAddress string, clientType request.ClientTy
 // forwardBGPSetupPrefixes exports external forward addresses as prefixes.
 func (n *common) forwardBGPSetupPrefixes() error {
 	// Retrieve network forwards before clearing existing prefixes, and separate them by IP family.
-	fwdListenAddresses, err := n.state.Cluster.GetNetworkForwardListenAddresses(n.ID(), true)
+	forwards, err := n.state.Cluster.GetNetworkForwards(n.ID(), true)
 	if err != nil {
 		return fmt.Errorf("Failed loading network forwards: %w", err)
 	}
@@ -882,7 +1001,13 @@ func (n *common) forwardBGPSetupPrefixes() error {
 		6: make([]string, 0),
 	}
 
-	for _, fwdListenAddress := range fwdListenAddresses {
+	for _, forward := range forwards {
+		// Skip forwards that have opted out of being advertised.
+		if forward.Config["bgp.advertise"] != "" && !shared.IsTrue(forward.Config["bgp.advertise"]) {
+			continue
+		}
+
+		fwdListenAddress := forward.ListenAddress
 		if strings.Contains(fwdListenAddress, ":") {
 			fwdListenAddressesByFamily[6] = append(fwdListenAddressesByFamily[6], fwdListenAddress)
 		} else {
@@ -903,6 +1028,10 @@ func (n *common) forwardBGPSetupPrefixes() error {
 	// Add the new prefixes.
 	for _, ipVersion := range []uint{4, 6} {
 		nextHopAddr := n.bgpNextHopAddress(ipVersion)
+		attrs, err := n.bgpPrefixAttributes(ipVersion)
+		if err != nil {
+			return err
+		}
 		natEnabled := shared.IsTrue(n.config[fmt.Sprintf("ipv%d.nat", ipVersion)])
 		_, netSubnet, _ := net.ParseCIDR(n.config[fmt.Sprintf("ipv%d.address", ipVersion)])
 
@@ -925,7 +1054,7 @@ func (n *common) forwardBGPSetupPrefixes() error {
 				return err
 			}
 
-			err = n.state.BGP.AddPrefix(*ipRouteSubnet, nextHopAddr, bgpOwner)
+			err = n.state.BGP.AddPrefixWithAttributes(*ipRouteSubnet, nextHopAddr, bgpOwner, attrs)
 			if err != nil {
 				return err
 			}
@@ -935,6 +1064,11 @@ func (n *common) forwardBGPSetupPrefixes() error {
 	return nil
 }
 
+// State returns the state of the network's interface.
+func (n *common) State() (*api.NetworkState, error) {
+	return resources.GetNetworkState(n.name)
+}
+
 // Leases returns ErrNotImplemented for drivers that don't support address leases.
 func (n *common) Leases(projectName string, clientType request.ClientType) ([]api.NetworkLease, error) {
 	return nil, ErrNotImplemented
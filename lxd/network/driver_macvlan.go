@@ -25,9 +25,17 @@ func (n *macvlan) DBType() db.NetworkType {
 	return db.NetworkTypeMacvlan
 }
 
-// Validate network config.
-func (n *macvlan) Validate(config map[string]string) error {
-	rules := map[string]func(value string) error{
+// Info returns the network driver info.
+func (n *macvlan) Info() Info {
+	info := n.common.Info()
+	info.ConfigKeys = configKeys(nil, n.configRules())
+
+	return info
+}
+
+// configRules returns the validation rules of the network config.
+func (n *macvlan) configRules() map[string]func(value string) error {
+	return map[string]func(value string) error{
 		"parent":           validate.Required(validate.IsNotEmpty, validate.IsInterfaceName),
 		"mtu":              validate.Optional(validate.IsNetworkMTU),
 		"vlan":             validate.Optional(validate.IsNetworkVLAN),
@@ -35,8 +43,11 @@ func (n *macvlan) Validate(config map[string]string) error {
 		"maas.subnet.ipv4": validate.IsAny,
 		"maas.subnet.ipv6": validate.IsAny,
 	}
+}
 
-	err := n.validate(config, rules)
+// Validate network config.
+func (n *macvlan) Validate(config map[string]string) error {
+	err := n.validate(config, n.configRules())
 	if err != nil {
 		return err
 	}
@@ -103,6 +103,9 @@ func (n *ovn) Info() Info {
 	info.NodeSpecificConfig = false
 	info.AddressForwards = true
 	info.Peering = true
+	info.ACLs = true
+	info.BGP = true
+	info.ConfigKeys = configKeys(nil, n.configRules())
 
 	return info
 }
@@ -263,9 +266,9 @@ func (n *ovn) getExternalSubnetInUse(uplinkNetworkName string) ([]externalSubnet
 	return externalSubnets, nil
 }
 
-// Validate network config.
-func (n *ovn) Validate(config map[string]string) error {
-	rules := map[string]func(value string) error{
+// configRules returns the validation rules of the network config.
+func (n *ovn) configRules() map[string]func(value string) error {
+	return map[string]func(value string) error{
 		"network":       validate.IsAny,
 		"bridge.hwaddr": validate.Optional(validate.IsNetworkMAC),
 		"bridge.mtu":    validate.Optional(validate.IsNetworkMTU),
@@ -305,8 +308,11 @@ func (n *ovn) Validate(config map[string]string) error {
 		ovnVolatileUplinkIPv4: validate.Optional(validate.IsNetworkAddressV4),
 		ovnVolatileUplinkIPv6: validate.Optional(validate.IsNetworkAddressV6),
 	}
+}
 
-	err := n.validate(config, rules)
+// Validate network config.
+func (n *ovn) Validate(config map[string]string) error {
+	err := n.validate(config, n.configRules())
 	if err != nil {
 		return err
 	}
@@ -499,6 +505,7 @@ func (n *ovn) Validate(config map[string]string) error {
 		}
 
 		for _, port := range forward.Ports {
+			// OVN forwards only have a single target address.
 			targetIP := net.ParseIP(port.TargetAddress)
 
 			netSubnet := netSubnets["ipv4.address"]
@@ -3874,7 +3881,7 @@ func (n *ovn) forwardFlattenVIPs(listenAddress net.IP, defaultTargetAddress net.
 			vips = append(vips, openvswitch.OVNLoadBalancerVIP{
 				ListenAddress: listenAddress,
 				Protocol:      portMap.protocol,
-				TargetAddress: portMap.targetAddress,
+				TargetAddress: portMap.targetAddresses[0],
 				ListenPort:    lp,
 				TargetPort:    targetPort,
 			})
@@ -3884,6 +3891,18 @@ func (n *ovn) forwardFlattenVIPs(listenAddress net.IP, defaultTargetAddress net.
 	return vips
 }
 
+// forwardCheckSingleTargets checks that the port maps don't use failover target addresses, which OVN load
+// balancers don't support.
+func (n *ovn) forwardCheckSingleTargets(portMaps []*forwardPortMap) error {
+	for _, portMap := range portMaps {
+		if len(portMap.targetAddresses) > 1 {
+			return api.StatusErrorf(http.StatusBadRequest, "Multiple target addresses aren't supported by OVN network forwards")
+		}
+	}
+
+	return nil
+}
+
 // ForwardCreate creates a network forward.
 func (n *ovn) ForwardCreate(forward api.NetworkForwardsPost, clientType request.ClientType) error {
 	revert := revert.New()
@@ -3909,6 +3928,11 @@ func (n *ovn) ForwardCreate(forward api.NetworkForwardsPost, clientType request.
 			return err
 		}
 
+		err = n.forwardCheckSingleTargets(portMaps)
+		if err != nil {
+			return err
+		}
+
 		// Load the project to get uplink network restrictions.
 		p, err := n.state.Cluster.GetProject(n.project)
 		if err != nil {
@@ -4028,6 +4052,11 @@ func (n *ovn) ForwardUpdate(listenAddress string, req api.NetworkForwardPut, cli
 			return err
 		}
 
+		err = n.forwardCheckSingleTargets(portMaps)
+		if err != nil {
+			return err
+		}
+
 		curForwardEtagHash, err := util.EtagHash(curForward.Etag())
 		if err != nil {
 			return err
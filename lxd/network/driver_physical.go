@@ -34,9 +34,25 @@ func (n *physical) DBType() db.NetworkType {
 	return db.NetworkTypePhysical
 }
 
-// Validate network config.
-func (n *physical) Validate(config map[string]string) error {
-	rules := map[string]func(value string) error{
+// Info returns the network driver info.
+func (n *physical) Info() Info {
+	info := n.common.Info()
+	info.BGP = true
+
+	// Include the keys validated dynamically, using "NAME" in place of the BGP peer name.
+	bgpRules, _ := n.bgpValidationRules(nil)
+	info.ConfigKeys = configKeys([]string{
+		"bgp.peers.NAME.address",
+		"bgp.peers.NAME.asn",
+		"bgp.peers.NAME.password",
+	}, n.configRules(), bgpRules)
+
+	return info
+}
+
+// configRules returns the static validation rules of the network config.
+func (n *physical) configRules() map[string]func(value string) error {
+	return map[string]func(value string) error{
 		"parent":                      validate.Required(validate.IsNotEmpty, validate.IsInterfaceName),
 		"mtu":                         validate.Optional(validate.IsNetworkMTU),
 		"vlan":                        validate.Optional(validate.IsNetworkVLAN),
@@ -55,6 +71,11 @@ func (n *physical) Validate(config map[string]string) error {
 		"ovn.ingress_mode":            validate.Optional(validate.IsOneOf("l2proxy", "routed")),
 		"volatile.last_state.created": validate.Optional(validate.IsBool),
 	}
+}
+
+// Validate network config.
+func (n *physical) Validate(config map[string]string) error {
+	rules := n.configRules()
 
 	// Add the BGP validation rules.
 	bgpRules, err := n.bgpValidationRules(config)
@@ -25,17 +25,28 @@ func (n *sriov) DBType() db.NetworkType {
 	return db.NetworkTypeSriov
 }
 
-// Validate network config.
-func (n *sriov) Validate(config map[string]string) error {
-	rules := map[string]func(value string) error{
+// Info returns the network driver info.
+func (n *sriov) Info() Info {
+	info := n.common.Info()
+	info.ConfigKeys = configKeys(nil, n.configRules())
+
+	return info
+}
+
+// configRules returns the validation rules of the network config.
+func (n *sriov) configRules() map[string]func(value string) error {
+	return map[string]func(value string) error{
 		"parent":           validate.Required(validate.IsNotEmpty, validate.IsInterfaceName),
 		"mtu":              validate.Optional(validate.IsNetworkMTU),
 		"vlan":             validate.Optional(validate.IsNetworkVLAN),
 		"maas.subnet.ipv4": validate.IsAny,
 		"maas.subnet.ipv6": validate.IsAny,
 	}
+}
 
-	err := n.validate(config, rules)
+// Validate network config.
+func (n *sriov) Validate(config map[string]string) error {
+	err := n.validate(config, n.configRules())
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,8 @@
 package network
 
 import (
+	"sort"
+
 	"github.com/lxc/lxd/lxd/state"
 )
 
@@ -24,6 +26,18 @@ func LoadByType(driverType string) (Type, error) {
 	return n, nil
 }
 
+// SupportedDrivers returns the names of the supported network drivers, sorted by name.
+func SupportedDrivers() []string {
+	driverNames := make([]string, 0, len(drivers))
+	for driverName := range drivers {
+		driverNames = append(driverNames, driverName)
+	}
+
+	sort.Strings(driverNames)
+
+	return driverNames
+}
+
 // LoadByName loads an instantiated network from the database by project and name.
 func LoadByName(s *state.State, projectName string, name string) (Network, error) {
 	id, netInfo, netNodes, err := s.Cluster.GetNetworkInAnyState(projectName, name)
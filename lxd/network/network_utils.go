@@ -17,6 +17,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/lxc/lxd/lxd/bgp"
 	"github.com/lxc/lxd/lxd/db"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/device/nictype"
@@ -51,6 +52,48 @@ func networkValidPort(value string) error {
 	return nil
 }
 
+// validateLeasesPath checks that a leases file path is within the directory reserved for relocated leases files,
+// so that LXD never moves, links or exposes arbitrary host files.
+func validateLeasesPath(value string) error {
+	if !dnsmasq.IsLeasesPath(value) {
+		return fmt.Errorf("Leases file must be directly inside %q", dnsmasq.LeasesDir())
+	}
+
+	return nil
+}
+
+// validBGPCommunities checks that the value is a comma separated list of BGP communities.
+func validBGPCommunities(value string) error {
+	_, err := bgp.ParseCommunities(value)
+	return err
+}
+
+// validDNSDomain checks that the value is a valid DNS domain name (optionally fully qualified with a trailing dot).
+func validDNSDomain(value string) error {
+	name := strings.TrimSuffix(value, ".")
+	if name == "" || len(name) > 253 {
+		return fmt.Errorf("Domain name must be 1-253 characters long")
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return fmt.Errorf("Domain name labels must be 1-63 characters long")
+		}
+
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf(`Domain name labels must not start or end with "-" character`)
+		}
+
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' {
+				return fmt.Errorf("Invalid character %q in domain name", r)
+			}
+		}
+	}
+
+	return nil
+}
+
 // RandomDevName returns a random device name with prefix.
 // If the random string combined with the prefix exceeds 13 characters then empty string is returned.
 // This is to ensure we support buggy dhclient applications: https://bugs.debian.org/cgi-bin/bugreport.cgi?bug=858580
@@ -257,16 +300,14 @@ func GetDevMTU(devName string) (uint32, error) {
 	return uint32(mtu), nil
 }
 
-// DefaultGatewaySubnetV4 returns subnet of default gateway interface.
-func DefaultGatewaySubnetV4() (*net.IPNet, string, error) {
+// defaultGatewayInterfaceV4 returns the name of the interface of the IPv4 default route (empty if there is none).
+func defaultGatewayInterfaceV4() (string, error) {
 	file, err := os.Open("/proc/net/route")
 	if err != nil {
-		return nil, "", err
+		return "", err
 	}
 	defer file.Close()
 
-	ifaceName := ""
-
 	scanner := bufio.NewReader(file)
 	for {
 		line, _, err := scanner.ReadLine()
@@ -277,11 +318,20 @@ func DefaultGatewaySubnetV4() (*net.IPNet, string, error) {
 		fields := strings.Fields(string(line))
 
 		if fields[1] == "00000000" && fields[7] == "00000000" {
-			ifaceName = fields[0]
-			break
+			return fields[0], nil
 		}
 	}
 
+	return "", nil
+}
+
+// DefaultGatewaySubnetV4 returns subnet of default gateway interface.
+func DefaultGatewaySubnetV4() (*net.IPNet, string, error) {
+	ifaceName, err := defaultGatewayInterfaceV4()
+	if err != nil {
+		return nil, "", err
+	}
+
 	if ifaceName == "" {
 		return nil, "", fmt.Errorf("No default gateway for IPv4")
 	}
@@ -400,7 +450,7 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 				}
 			}
 
-			entries[d["parent"]] = append(entries[d["parent"]], []string{d["hwaddr"], inst.Project(), inst.Name(), d["ipv4.address"], d["ipv6.address"]})
+			entries[d["parent"]] = append(entries[d["parent"]], []string{d["hwaddr"], inst.Project(), inst.Name(), d["ipv4.address"], d["ipv6.address"], d["dns.name"]})
 		}
 	}
 
@@ -441,6 +491,7 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 			cName := entry[2]
 			ipv4Address := entry[3]
 			ipv6Address := entry[4]
+			dnsName := entry[5]
 			line := hwaddr
 
 			// Look for duplicates.
@@ -477,7 +528,7 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 			}
 
 			// Generate the dhcp-host line.
-			err := dnsmasq.UpdateStaticEntry(network, projectName, cName, config, hwaddr, ipv4Address, ipv6Address)
+			err := dnsmasq.UpdateStaticEntry(network, projectName, cName, dnsName, config, hwaddr, ipv4Address, ipv6Address)
 			if err != nil {
 				return err
 			}
@@ -539,8 +590,13 @@ func randomSubnetV4() (string, error) {
 }
 
 func randomSubnetV6() (string, error) {
+	return generateSubnetV6(rand.Intn)
+}
+
+// generateSubnetV6 returns an unused IPv6 ULA subnet using the given random number generator function.
+func generateSubnetV6(intn func(n int) int) (string, error) {
 	for i := 0; i < 100; i++ {
-		cidr := fmt.Sprintf("fd42:%x:%x:%x::1/64", rand.Intn(65535), rand.Intn(65535), rand.Intn(65535))
+		cidr := fmt.Sprintf("fd42:%x:%x:%x::1/64", intn(65535), intn(65535), intn(65535))
 		_, subnet, err := net.ParseCIDR(cidr)
 		if err != nil {
 			continue
@@ -835,12 +891,21 @@ func GetMACSlice(hwaddr string) []string {
 	return buf
 }
 
+// firewallManaged returns whether LXD manages the firewall rules of the network (rather than the operator).
+func firewallManaged(netConfig map[string]string) bool {
+	return netConfig["security.firewall"] == "" || shared.IsTrue(netConfig["security.firewall"])
+}
+
 // usesIPv4Firewall returns whether network config will need to use the IPv4 firewall.
 func usesIPv4Firewall(netConfig map[string]string) bool {
 	if netConfig == nil {
 		return false
 	}
 
+	if !firewallManaged(netConfig) {
+		return false
+	}
+
 	if netConfig["ipv4.firewall"] == "" || shared.IsTrue(netConfig["ipv4.firewall"]) {
 		return true
 	}
@@ -858,6 +923,10 @@ func usesIPv6Firewall(netConfig map[string]string) bool {
 		return false
 	}
 
+	if !firewallManaged(netConfig) {
+		return false
+	}
+
 	if netConfig["ipv6.firewall"] == "" || shared.IsTrue(netConfig["ipv6.firewall"]) {
 		return true
 	}
@@ -1176,6 +1245,55 @@ func IPRangesOverlap(r1, r2 *shared.IPRange) bool {
 	return r1.ContainsIP(r2.Start) || r1.ContainsIP(r2.End)
 }
 
+// excludeIPRanges returns the IP ranges with the addresses in excludeRanges removed.
+// Ranges are split when an excluded range falls in the middle of them.
+func excludeIPRanges(ipRanges []*shared.IPRange, excludeRanges []*shared.IPRange) []*shared.IPRange {
+	// ipOffset returns the IP offset by delta, in its 16 byte representation.
+	ipOffset := func(ip net.IP, delta int64) net.IP {
+		bigIP := big.NewInt(0).SetBytes(ip.To16())
+		bigIP.Add(bigIP, big.NewInt(delta))
+
+		return net.IP(bigIP.FillBytes(make([]byte, net.IPv6len)))
+	}
+
+	// rangeBounds returns the start and end IPs of a range, in their 16 byte representations.
+	rangeBounds := func(r *shared.IPRange) (net.IP, net.IP) {
+		if r.End == nil {
+			return r.Start.To16(), r.Start.To16()
+		}
+
+		return r.Start.To16(), r.End.To16()
+	}
+
+	for _, excludeRange := range excludeRanges {
+		excludeStart, excludeEnd := rangeBounds(excludeRange)
+		remainingRanges := make([]*shared.IPRange, 0, len(ipRanges))
+
+		for _, ipRange := range ipRanges {
+			start, end := rangeBounds(ipRange)
+
+			// Keep ranges that don't overlap with the excluded range as-is.
+			if bytes.Compare(excludeEnd, start) < 0 || bytes.Compare(excludeStart, end) > 0 {
+				remainingRanges = append(remainingRanges, &shared.IPRange{Start: start, End: end})
+				continue
+			}
+
+			// Keep the parts before and after the excluded range.
+			if bytes.Compare(excludeStart, start) > 0 {
+				remainingRanges = append(remainingRanges, &shared.IPRange{Start: start, End: ipOffset(excludeStart, -1)})
+			}
+
+			if bytes.Compare(excludeEnd, end) < 0 {
+				remainingRanges = append(remainingRanges, &shared.IPRange{Start: ipOffset(excludeEnd, 1), End: end})
+			}
+		}
+
+		ipRanges = remainingRanges
+	}
+
+	return ipRanges
+}
+
 // InterfaceStatus returns the global unicast IP addresses configured on an interface and whether it is up or not.
 func InterfaceStatus(nicName string) ([]net.IP, bool, error) {
 	iface, err := net.InterfaceByName(nicName)
@@ -1298,3 +1416,29 @@ func BridgeNetfilterEnabled(ipVersion uint) error {
 
 	return nil
 }
+
+// dnsmasqStartupFailure returns a description of why dnsmasq failed to start based on its log output.
+func dnsmasqStartupFailure(output string) string {
+	// The fatal error is the last line logged before exiting.
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	lastLine := strings.TrimSpace(lines[len(lines)-1])
+	lastLineLower := strings.ToLower(lastLine)
+
+	var reason string
+	switch {
+	case strings.Contains(lastLineLower, "address already in use"):
+		reason = "Address already in use"
+	case strings.Contains(lastLineLower, "permission denied"):
+		reason = "Permission denied"
+	case strings.Contains(lastLineLower, "bad ") || strings.Contains(lastLineLower, "error at line") || strings.Contains(lastLineLower, "unrecognized option"):
+		reason = "Invalid configuration"
+	default:
+		reason = "Unknown failure"
+	}
+
+	if lastLine == "" {
+		return reason
+	}
+
+	return fmt.Sprintf("%s: %s", reason, lastLine)
+}
@@ -52,6 +52,84 @@ func BridgeVLANSetDefaultPVID(interfaceName string, vlanID string) error {
 	return nil
 }
 
+// BridgeSetSTP enables or disables the spanning tree protocol on a native bridge interface.
+func BridgeSetSTP(interfaceName string, enabled bool) error {
+	status := "0"
+	if enabled {
+		status = "1"
+	}
+
+	err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/stp_state", interfaceName), []byte(status), 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting STP state on bridge %q", interfaceName)
+	}
+
+	return nil
+}
+
+// BridgeSetForwardDelay sets the STP forward delay (in seconds) of a native bridge interface.
+func BridgeSetForwardDelay(interfaceName string, delay uint64) error {
+	// The kernel expects the delay in hundredths of a second.
+	err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/forward_delay", interfaceName), []byte(fmt.Sprintf("%d", delay*100)), 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting forward delay on bridge %q", interfaceName)
+	}
+
+	return nil
+}
+
+// BridgeSetAgeingTime sets the MAC address table ageing time (in seconds) of a native bridge interface.
+func BridgeSetAgeingTime(interfaceName string, ageingTime uint64) error {
+	// The kernel expects the ageing time in hundredths of a second.
+	err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/ageing_time", interfaceName), []byte(fmt.Sprintf("%d", ageingTime*100)), 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting ageing time on bridge %q", interfaceName)
+	}
+
+	return nil
+}
+
+// BridgeSetGroupForwardMask sets which reserved link-local group addresses (01:80:C2:00:00:0X) a native bridge
+// interface forwards, as a bitmask of their last nibble.
+func BridgeSetGroupForwardMask(interfaceName string, mask uint16) error {
+	err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/group_fwd_mask", interfaceName), []byte(fmt.Sprintf("%d", mask)), 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting group forward mask on bridge %q", interfaceName)
+	}
+
+	return nil
+}
+
+// BridgeSetMulticastSnooping enables or disables IGMP/MLD snooping on a native bridge interface.
+func BridgeSetMulticastSnooping(interfaceName string, enabled bool) error {
+	status := "0"
+	if enabled {
+		status = "1"
+	}
+
+	err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/multicast_snooping", interfaceName), []byte(status), 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting multicast snooping on bridge %q", interfaceName)
+	}
+
+	return nil
+}
+
+// BridgeSetMulticastQuerier enables or disables the multicast querier of a native bridge interface.
+func BridgeSetMulticastQuerier(interfaceName string, enabled bool) error {
+	status := "0"
+	if enabled {
+		status = "1"
+	}
+
+	err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/multicast_querier", interfaceName), []byte(status), 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting multicast querier on bridge %q", interfaceName)
+	}
+
+	return nil
+}
+
 // IsNativeBridge returns whether the bridge name specified is a Linux native bridge.
 func IsNativeBridge(bridgeName string) bool {
 	return shared.PathExists(fmt.Sprintf("/sys/class/net/%s/bridge", bridgeName))
@@ -123,43 +123,7 @@ func SRIOVFindFreeVirtualFunction(s *state.State, parentDev string) (string, int
 		return "", -1, errors.Wrapf(err, "Failed getting in use device list")
 	}
 
-	sriovNumVFsFile := fmt.Sprintf("/sys/class/net/%s/device/sriov_numvfs", parentDev)
-	sriovTotalVFsFile := fmt.Sprintf("/sys/class/net/%s/device/sriov_totalvfs", parentDev)
-
-	// Verify that this is indeed a SR-IOV enabled device.
-	if !shared.PathExists(sriovNumVFsFile) {
-		return "", -1, fmt.Errorf("Parent device %q doesn't support SR-IOV", parentDev)
-	}
-
-	// Get parent dev_port and dev_id values.
-	pfDevPort, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/dev_port", parentDev))
-	if err != nil {
-		return "", -1, err
-	}
-
-	pfDevID, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/dev_id", parentDev))
-	if err != nil {
-		return "", -1, err
-	}
-
-	// Get number of currently enabled VFs.
-	sriovNumVFsBuf, err := ioutil.ReadFile(sriovNumVFsFile)
-	if err != nil {
-		return "", -1, err
-	}
-
-	sriovNumVFs, err := strconv.Atoi(strings.TrimSpace(string(sriovNumVFsBuf)))
-	if err != nil {
-		return "", -1, err
-	}
-
-	// Get number of possible VFs.
-	sriovTotalVFsBuf, err := ioutil.ReadFile(sriovTotalVFsFile)
-	if err != nil {
-		return "", -1, err
-	}
-
-	sriovTotalVFs, err := strconv.Atoi(strings.TrimSpace(string(sriovTotalVFsBuf)))
+	pfDevID, pfDevPort, sriovNumVFs, sriovTotalVFs, err := sriovParentInfo(parentDev)
 	if err != nil {
 		return "", -1, err
 	}
@@ -184,6 +148,7 @@ func SRIOVFindFreeVirtualFunction(s *state.State, parentDev string) (string, int
 		logger.Debugf("Attempting to grow available VFs from %d to %d on device %q", sriovNumVFs, sriovTotalVFs, parentDev)
 
 		// Bump the number of VFs to the maximum if not there yet.
+		sriovNumVFsFile := fmt.Sprintf("/sys/class/net/%s/device/sriov_numvfs", parentDev)
 		err = ioutil.WriteFile(sriovNumVFsFile, []byte(fmt.Sprintf("%d", sriovTotalVFs)), 0644)
 		if err != nil {
 			return "", -1, errors.Wrapf(err, "Failed growing available VFs from %d to %d on device %q", sriovNumVFs, sriovTotalVFs, parentDev)
@@ -206,11 +171,103 @@ func SRIOVFindFreeVirtualFunction(s *state.State, parentDev string) (string, int
 	return "", -1, fmt.Errorf("All virtual functions on parent device %q are already in use", parentDev)
 }
 
+// SRIOVCountFreeVirtualFunctions returns the number of virtual functions on the specified parent device that
+// could be used by a new device. This includes the VFs that are not enabled yet but can be created on demand.
+// Unlike SRIOVFindFreeVirtualFunction it doesn't modify the parent device.
+func SRIOVCountFreeVirtualFunctions(s *state.State, parentDev string) (int, error) {
+	reservedDevices, err := SRIOVGetHostDevicesInUse(s)
+	if err != nil {
+		return -1, errors.Wrapf(err, "Failed getting in use device list")
+	}
+
+	pfDevID, pfDevPort, sriovNumVFs, sriovTotalVFs, err := sriovParentInfo(parentDev)
+	if err != nil {
+		return -1, err
+	}
+
+	count := sriovTotalVFs - sriovNumVFs
+	err = sriovWalkFreeVFInterfaces(reservedDevices, parentDev, sriovNumVFs, 0, pfDevID, pfDevPort, func(vfID int, nicName string) bool {
+		count++
+		return true
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return count, nil
+}
+
+// sriovParentInfo returns the dev_id and dev_port values of the parent device along with its number of enabled
+// and possible VFs.
+func sriovParentInfo(parentDev string) ([]byte, []byte, int, int, error) {
+	sriovNumVFsFile := fmt.Sprintf("/sys/class/net/%s/device/sriov_numvfs", parentDev)
+	sriovTotalVFsFile := fmt.Sprintf("/sys/class/net/%s/device/sriov_totalvfs", parentDev)
+
+	// Verify that this is indeed a SR-IOV enabled device.
+	if !shared.PathExists(sriovNumVFsFile) {
+		return nil, nil, -1, -1, fmt.Errorf("Parent device %q doesn't support SR-IOV", parentDev)
+	}
+
+	// Get parent dev_port and dev_id values.
+	pfDevPort, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/dev_port", parentDev))
+	if err != nil {
+		return nil, nil, -1, -1, err
+	}
+
+	pfDevID, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/dev_id", parentDev))
+	if err != nil {
+		return nil, nil, -1, -1, err
+	}
+
+	// Get number of currently enabled VFs.
+	sriovNumVFsBuf, err := ioutil.ReadFile(sriovNumVFsFile)
+	if err != nil {
+		return nil, nil, -1, -1, err
+	}
+
+	sriovNumVFs, err := strconv.Atoi(strings.TrimSpace(string(sriovNumVFsBuf)))
+	if err != nil {
+		return nil, nil, -1, -1, err
+	}
+
+	// Get number of possible VFs.
+	sriovTotalVFsBuf, err := ioutil.ReadFile(sriovTotalVFsFile)
+	if err != nil {
+		return nil, nil, -1, -1, err
+	}
+
+	sriovTotalVFs, err := strconv.Atoi(strings.TrimSpace(string(sriovTotalVFsBuf)))
+	if err != nil {
+		return nil, nil, -1, -1, err
+	}
+
+	return pfDevID, pfDevPort, sriovNumVFs, sriovTotalVFs, nil
+}
+
 // sriovGetFreeVFInterface checks the system for a free VF interface that belongs to the same device and port as
 // the parent device starting from the startVFID to the vfCount-1. Returns VF ID and VF interface name if found or
 // -1 and empty string if no free interface found. A free interface is one that is bound on the host, not in the
 // reservedDevices map, is down and has no global IPs defined on it.
 func sriovGetFreeVFInterface(reservedDevices map[string]struct{}, parentDev string, vfCount int, startVFID int, pfDevID []byte, pfDevPort []byte) (int, string, error) {
+	freeVFID := -1
+	freeNICName := ""
+
+	err := sriovWalkFreeVFInterfaces(reservedDevices, parentDev, vfCount, startVFID, pfDevID, pfDevPort, func(vfID int, nicName string) bool {
+		freeVFID = vfID
+		freeNICName = nicName
+
+		return false
+	})
+	if err != nil {
+		return -1, "", err
+	}
+
+	return freeVFID, freeNICName, nil
+}
+
+// sriovWalkFreeVFInterfaces calls f for each free VF interface (as defined by sriovGetFreeVFInterface) from
+// startVFID to vfCount-1, stopping early if f returns false.
+func sriovWalkFreeVFInterfaces(reservedDevices map[string]struct{}, parentDev string, vfCount int, startVFID int, pfDevID []byte, pfDevPort []byte, f func(vfID int, nicName string) bool) error {
 	for vfID := startVFID; vfID < vfCount; vfID++ {
 		vfListPath := fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", parentDev, vfID)
 
@@ -220,7 +277,7 @@ func sriovGetFreeVFInterface(reservedDevices map[string]struct{}, parentDev stri
 
 		ents, err := ioutil.ReadDir(vfListPath)
 		if err != nil {
-			return -1, "", errors.Wrapf(err, "Failed reading VF interface directory %q", vfListPath)
+			return errors.Wrapf(err, "Failed reading VF interface directory %q", vfListPath)
 		}
 
 		for _, ent := range ents {
@@ -240,12 +297,12 @@ func sriovGetFreeVFInterface(reservedDevices map[string]struct{}, parentDev stri
 			// Get VF dev_port and dev_id values.
 			vfDevPort, err := ioutil.ReadFile(fmt.Sprintf("%s/%s/dev_port", vfListPath, nicName))
 			if err != nil {
-				return -1, "", err
+				return err
 			}
 
 			vfDevID, err := ioutil.ReadFile(fmt.Sprintf("%s/%s/dev_id", vfListPath, nicName))
 			if err != nil {
-				return -1, "", err
+				return err
 			}
 
 			// Skip VFs if they do not relate to the same device and port as the parent PF.
@@ -256,7 +313,7 @@ func sriovGetFreeVFInterface(reservedDevices map[string]struct{}, parentDev stri
 
 			addresses, isUp, err := InterfaceStatus(nicName)
 			if err != nil {
-				return -1, "", err
+				return err
 			}
 
 			// Ignore if interface is up or if interface has unicast IP addresses (may be in use by
@@ -266,11 +323,13 @@ func sriovGetFreeVFInterface(reservedDevices map[string]struct{}, parentDev stri
 			}
 
 			// Found a free VF.
-			return vfID, nicName, err
+			if !f(vfID, nicName) {
+				return nil
+			}
 		}
 	}
 
-	return -1, "", nil
+	return nil
 }
 
 // SRIOVGetVFDevicePCISlot returns the PCI slot name for a network virtual function device.
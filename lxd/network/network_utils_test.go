@@ -137,3 +137,76 @@ func Example_ipRangesOverlap() {
 	// Range1: 10.1.1.8-10.1.1.9, Range2: 10.1.1.4, overlapped: false
 
 }
+
+func Example_excludeIPRanges() {
+	rangePairs := [][2]string{
+		{"10.1.1.2-10.1.1.254", "10.1.1.100-10.1.1.199"},
+		{"10.1.1.2-10.1.1.254", "10.1.1.2-10.1.1.9"},
+		{"10.1.1.2-10.1.1.254", "10.1.1.250-10.1.2.10"},
+		{"10.1.1.2-10.1.1.254", "10.1.1.1-10.1.1.255"},
+		{"10.1.1.2-10.1.1.9", "10.1.1.10-10.1.1.20"},
+		{"fd22::2-fd22::ffff", "fd22::1000-fd22::1fff"},
+	}
+
+	for _, pair := range rangePairs {
+		ipRanges, _ := parseIPRanges(pair[0])
+		excludeRanges, _ := parseIPRanges(pair[1])
+		fmt.Printf("Range: %v, Exclude: %v, Result: %v\n", ipRanges[0], excludeRanges[0], excludeIPRanges(ipRanges, excludeRanges))
+	}
+
+	// Output:
+	// Range: 10.1.1.2-10.1.1.254, Exclude: 10.1.1.100-10.1.1.199, Result: [10.1.1.2-10.1.1.99 10.1.1.200-10.1.1.254]
+	// Range: 10.1.1.2-10.1.1.254, Exclude: 10.1.1.2-10.1.1.9, Result: [10.1.1.10-10.1.1.254]
+	// Range: 10.1.1.2-10.1.1.254, Exclude: 10.1.1.250-10.1.2.10, Result: [10.1.1.2-10.1.1.249]
+	// Range: 10.1.1.2-10.1.1.254, Exclude: 10.1.1.1-10.1.1.255, Result: []
+	// Range: 10.1.1.2-10.1.1.9, Exclude: 10.1.1.10-10.1.1.20, Result: [10.1.1.2-10.1.1.9]
+	// Range: fd22::2-fd22::ffff, Exclude: fd22::1000-fd22::1fff, Result: [fd22::2-fd22::fff fd22::2000-fd22::ffff]
+}
+
+func Example_dnsmasqStartupFailure() {
+	outputs := []string{
+		"dnsmasq: failed to create listening socket for 10.0.0.1: Address already in use\n",
+		"dnsmasq: started, version 2.80 cachesize 150\ndnsmasq: bad option at line 1 of /var/lib/lxd/networks/lxdbr0/dnsmasq.raw\n",
+		"dnsmasq: failed to open pidfile /run/dnsmasq.pid: Permission denied",
+		"dnsmasq: unknown interface lxdbr0",
+		"",
+	}
+
+	for _, output := range outputs {
+		fmt.Println(dnsmasqStartupFailure(output))
+	}
+
+	// Output: Address already in use: dnsmasq: failed to create listening socket for 10.0.0.1: Address already in use
+	// Invalid configuration: dnsmasq: bad option at line 1 of /var/lib/lxd/networks/lxdbr0/dnsmasq.raw
+	// Permission denied: dnsmasq: failed to open pidfile /run/dnsmasq.pid: Permission denied
+	// Unknown failure: dnsmasq: unknown interface lxdbr0
+	// Unknown failure
+}
+
+func Example_validDNSDomain() {
+	domains := []string{
+		"lxd",
+		"example.com",
+		"example.com.",
+		"1-example.net",
+		"",
+		"example..com",
+		"-example.com",
+		"example_com",
+		"example.com..",
+	}
+
+	for _, domain := range domains {
+		fmt.Printf("%q: %v\n", domain, validDNSDomain(domain))
+	}
+
+	// Output: "lxd": <nil>
+	// "example.com": <nil>
+	// "example.com.": <nil>
+	// "1-example.net": <nil>
+	// "": Domain name must be 1-253 characters long
+	// "example..com": Domain name labels must be 1-63 characters long
+	// "-example.com": Domain name labels must not start or end with "-" character
+	// "example_com": Invalid character '_' in domain name
+	// "example.com..": Domain name labels must be 1-63 characters long
+}
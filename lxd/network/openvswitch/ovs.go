@@ -81,6 +81,16 @@ func (o *OVS) BridgeAdd(bridgeName string, mayExist bool) error {
 	return nil
 }
 
+// BridgeSet sets bridge options.
+func (o *OVS) BridgeSet(bridgeName string, options ...string) error {
+	_, err := shared.RunCommand("ovs-vsctl", append([]string{"set", "bridge", bridgeName}, options...)...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // BridgeDelete deletes an OVS bridge.
 func (o *OVS) BridgeDelete(bridgeName string) error {
 	_, err := shared.RunCommand("ovs-vsctl", "del-br", bridgeName)
@@ -5,6 +5,7 @@ import (
 	"net"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/pkg/errors"
 	log "gopkg.in/inconshreveable/log15.v2"
@@ -163,9 +164,18 @@ func (d *zone) validateConfig(info *api.NetworkZonePut) error {
 		// Add the correct validation rule for the dynamic field based on last part of key.
 		switch peerKey {
 		case "address":
-			rules[k] = validate.Optional(validate.IsNetworkAddress)
+			rules[k] = validate.Optional(func(value string) error {
+				// Allow a whole subnet of servers to be matched.
+				if strings.Contains(value, "/") {
+					return validate.IsNetwork(value)
+				}
+
+				return validate.IsNetworkAddress(value)
+			})
 		case "key":
 			rules[k] = validate.Optional(validate.IsAny)
+		case "keys":
+			rules[k] = validate.Optional(d.validatePeerKeys)
 		}
 	}
 
@@ -177,6 +187,31 @@ func (d *zone) validateConfig(info *api.NetworkZonePut) error {
 	return nil
 }
 
+// validatePeerKeys checks a comma separated list of "NAME:SECRET" peer keys.
+func (d *zone) validatePeerKeys(value string) error {
+	names := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		fields := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+			return fmt.Errorf("Invalid key %q, must be in the form NAME:SECRET", entry)
+		}
+
+		for _, r := range fields[0] {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' {
+				return fmt.Errorf("Invalid key name %q, only letters, digits and hyphens are allowed", fields[0])
+			}
+		}
+
+		if shared.StringInSlice(fields[0], names) {
+			return fmt.Errorf("Duplicate key name %q", fields[0])
+		}
+
+		names = append(names, fields[0])
+	}
+
+	return nil
+}
+
 // validateConfigMap checks zone config map against rules.
 func (d *zone) validateConfigMap(config map[string]string, rules map[string]func(value string) error) error {
 	checkedFields := map[string]struct{}{}
@@ -258,6 +293,12 @@ func (d *zone) Update(config *api.NetworkZonePut, clientType request.ClientType)
 		return err
 	}
 
+	// Let the peers know the zone may have changed.
+	err = d.state.DNS.NotifyZone(d.info.Name)
+	if err != nil {
+		d.logger.Warn("Failed notifying DNS peers of zone change", log.Ctx{"err": err})
+	}
+
 	revert.Success()
 	return nil
 }
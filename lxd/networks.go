@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -9,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -21,13 +23,16 @@ import (
 	dbCluster "github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/lifecycle"
 	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/network/acl"
 	"github.com/lxc/lxd/lxd/network/openvswitch"
 	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/rbac"
 	"github.com/lxc/lxd/lxd/request"
 	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/lxd/warnings"
 	"github.com/lxc/lxd/shared"
@@ -56,6 +61,24 @@ var networkCmd = APIEndpoint{
 	Put:    APIEndpointAction{Handler: networkPut, AccessHandler: allowProjectPermission("networks", "manage-networks")},
 }
 
+var networkACLEvaluateCmd = APIEndpoint{
+	Path: "networks/{name}/acl-evaluate",
+
+	Post: APIEndpointAction{Handler: networkACLEvaluatePost, AccessHandler: allowProjectPermission("networks", "view")},
+}
+
+var networkACLLogCmd = APIEndpoint{
+	Path: "networks/{name}/acl-log",
+
+	Get: APIEndpointAction{Handler: networkACLLogGet, AccessHandler: allowProjectPermission("networks", "view")},
+}
+
+var networkExternalSubnetsCmd = APIEndpoint{
+	Path: "networks/{name}/external-subnets",
+
+	Get: APIEndpointAction{Handler: networkExternalSubnetsGet},
+}
+
 var networkLeasesCmd = APIEndpoint{
 	Path: "networks/{name}/leases",
 
@@ -65,7 +88,8 @@ var networkLeasesCmd = APIEndpoint{
 var networkStateCmd = APIEndpoint{
 	Path: "networks/{name}/state",
 
-	Get: APIEndpointAction{Handler: networkStateGet, AccessHandler: allowProjectPermission("networks", "view")},
+	Get:  APIEndpointAction{Handler: networkStateGet, AccessHandler: allowProjectPermission("networks", "view")},
+	Post: APIEndpointAction{Handler: networkStatePost, AccessHandler: allowProjectPermission("networks", "manage-networks")},
 }
 
 // API endpoints
@@ -324,6 +348,12 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	// Check the requestor can use any ACLs from other projects.
+	accessResp := networkACLsAccessCheck(r, projectName, nil, req.Config)
+	if accessResp != nil {
+		return accessResp
+	}
+
 	url := fmt.Sprintf("/%s/networks/%s", version.APIVersion, req.Name)
 	resp := response.SyncResponseLocation(true, nil, url)
 
@@ -691,6 +721,11 @@ func doNetworksCreate(d *Daemon, n network.Network, clientType clusterRequest.Cl
 //     description: Cluster member name
 //     type: string
 //     example: lxd01
+//   - in: query
+//     name: effective
+//     description: Whether to return the config with the auto values resolved to the values in effect
+//     type: boolean
+//     example: true
 // responses:
 //   "200":
 //     description: Network
@@ -718,7 +753,7 @@ func doNetworksCreate(d *Daemon, n network.Network, clientType clusterRequest.Cl
 //     $ref: "#/responses/InternalServerError"
 func networkGet(d *Daemon, r *http.Request) response.Response {
 	// If a target was specified, forward the request to the relevant node.
-	resp := forwardedResponseIfTargetIsRemote(d, r)
+	resp := forwardedResponseIfTargetIsRemoteTimeout(d, r)
 	if resp != nil {
 		return resp
 	}
@@ -741,6 +776,26 @@ func networkGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Return the config with its auto values resolved to the values in effect (without persisting them).
+	if shared.IsTrue(queryParam(r, "effective")) && n.Managed {
+		type effectiveConfiger interface {
+			EffectiveConfig() (map[string]string, error)
+		}
+
+		netInfo, err := network.LoadByName(d.State(), projectName, name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		configer, ok := netInfo.(effectiveConfiger)
+		if ok {
+			n.Config, err = configer.EffectiveConfig()
+			if err != nil {
+				return response.SmartError(err)
+			}
+		}
+	}
+
 	// If no target node is specified and the daemon is clustered, we omit the node-specific fields.
 	if targetNode == "" && clustered {
 		for _, key := range db.NodeSpecificNetworkConfig {
@@ -873,7 +928,7 @@ func networkDelete(d *Daemon, r *http.Request) response.Response {
 		}
 
 		if inUse {
-			return response.BadRequest(fmt.Errorf("The network is currently in use"))
+			return response.BadRequestWithCode(fmt.Errorf("The network is currently in use"), "network_in_use")
 		}
 	}
 
@@ -1016,7 +1071,7 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	if inUse {
-		return response.BadRequest(fmt.Errorf("Network is currently in use"))
+		return response.BadRequestWithCode(fmt.Errorf("Network is currently in use"), "network_in_use")
 	}
 
 	// Check that the name isn't already in used by an existing managed network.
@@ -1135,6 +1190,12 @@ func networkPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	// Check the requestor can use any ACLs from other projects.
+	resp = networkACLsAccessCheck(r, projectName, n.Config(), req.Config)
+	if resp != nil {
+		return resp
+	}
+
 	// In clustered mode, we differentiate between node specific and non-node specific config keys based on
 	// whether the user has specified a target to apply the config to.
 	if clustered {
@@ -1210,6 +1271,25 @@ func networkPatch(d *Daemon, r *http.Request) response.Response {
 	return networkPut(d, r)
 }
 
+// networkACLsAccessCheck checks that the requestor has access to the projects of any project qualified ACLs that
+// are newly referenced by the network's security.acls setting.
+func networkACLsAccessCheck(r *http.Request, projectName string, oldConfig map[string]string, newConfig map[string]string) response.Response {
+	oldACLRefs := util.SplitNTrimSpace(oldConfig["security.acls"], ",", -1, true)
+
+	for _, aclRef := range util.SplitNTrimSpace(newConfig["security.acls"], ",", -1, true) {
+		if shared.StringInSlice(aclRef, oldACLRefs) {
+			continue
+		}
+
+		aclProjectName, _ := acl.ParseName(projectName, aclRef)
+		if aclProjectName != projectName && !rbac.UserHasPermission(r, aclProjectName, "view") {
+			return response.Forbidden(fmt.Errorf("Not allowed to use network ACLs from project %q", aclProjectName))
+		}
+	}
+
+	return nil
+}
+
 // doNetworkUpdate loads the current local network config, merges with the requested network config, validates
 // and applies the changes. Will also notify other cluster nodes of non-node specific config if needed.
 func doNetworkUpdate(d *Daemon, projectName string, n network.Network, req api.NetworkPut, targetNode string, clientType clusterRequest.ClientType, httpMethod string, clustered bool) response.Response {
@@ -1274,6 +1354,21 @@ func doNetworkUpdate(d *Daemon, projectName string, n network.Network, req api.N
 //     description: Cluster member name
 //     type: string
 //     example: lxd01
+//   - in: query
+//     name: eui64
+//     description: Whether to include the EUI64 derived IPv6 addresses of the instances
+//     type: boolean
+//     example: false
+//   - in: query
+//     name: instance
+//     description: Only return the leases of this instance
+//     type: string
+//     example: c1
+//   - in: query
+//     name: reservations
+//     description: Only return the addresses allocated by configuration (static and EUI64), not the dynamic leases
+//     type: boolean
+//     example: true
 // responses:
 //   "200":
 //     description: API endpoints
@@ -1319,7 +1414,36 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 	}
 
 	clientType := clusterRequest.UserAgentClientType(r.Header.Get("User-Agent"))
-	leases, err := n.Leases(projectName, clientType)
+
+	// Skip the EUI64 derived addresses if not wanted and supported by the network.
+	type leasesWithoutEUI64er interface {
+		LeasesWithoutEUI64(projectName string, clientType clusterRequest.ClientType) ([]api.NetworkLease, error)
+	}
+
+	// Only get the leases of a single instance if requested and supported by the network.
+	type leasesForInstancer interface {
+		LeasesForInstance(projectName string, instanceName string) ([]api.NetworkLease, error)
+	}
+
+	// Only get the addresses allocated by configuration if requested and supported by the network.
+	type reservationsLister interface {
+		Reservations(projectName string) ([]api.NetworkLease, error)
+	}
+
+	var leases []api.NetworkLease
+	lister, ok := n.(leasesWithoutEUI64er)
+	instLister, instOk := n.(leasesForInstancer)
+	resLister, resOk := n.(reservationsLister)
+	if resOk && shared.IsTrue(queryParam(r, "reservations")) {
+		leases, err = resLister.Reservations(projectName)
+	} else if instOk && queryParam(r, "instance") != "" && clientType == clusterRequest.ClientTypeNormal {
+		leases, err = instLister.LeasesForInstance(projectName, queryParam(r, "instance"))
+	} else if ok && queryParam(r, "eui64") != "" && !shared.IsTrue(queryParam(r, "eui64")) {
+		leases, err = lister.LeasesWithoutEUI64(projectName, clientType)
+	} else {
+		leases, err = n.Leases(projectName, clientType)
+	}
+
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -1327,6 +1451,306 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, leases)
 }
 
+// swagger:operation GET /1.0/networks/{name}/external-subnets networks networks_external_subnets_get
+//
+// Get the external subnets in use
+//
+// Returns the external subnets in use by bridge networks, the instance NICs connected to them and network
+// forwards on the cluster member, across all projects. Intended for planning address allocations.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+//   - in: query
+//     name: target
+//     description: Cluster member name
+//     type: string
+//     example: lxd01
+// responses:
+//   "200":
+//     description: API endpoints
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           type: array
+//           description: List of external subnets
+//           items:
+//             $ref: "#/definitions/NetworkExternalSubnet"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func networkExternalSubnetsGet(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := forwardedResponseIfTargetIsRemoteTimeout(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, _, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	n, err := network.LoadByName(d.State(), projectName, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Only some network types track the external subnets in use.
+	type externalSubnetsLister interface {
+		ExternalSubnetsInUse() ([]api.NetworkExternalSubnet, error)
+	}
+
+	lister, ok := n.(externalSubnetsLister)
+	if !ok {
+		return response.BadRequest(fmt.Errorf("Network driver %q does not support listing external subnets", n.Type()))
+	}
+
+	externalSubnets, err := lister.ExternalSubnetsInUse()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, externalSubnets)
+}
+
+// swagger:operation GET /1.0/networks/{name}/acl-log networks network_acl_log_get
+//
+// Get the network ACL log
+//
+// Returns the most recent kernel log entries of traffic matched by the logged ACL rules of the network
+// (including its default rules) on the cluster member.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+//   - in: query
+//     name: target
+//     description: Cluster member name
+//     type: string
+//     example: lxd01
+//   - in: query
+//     name: limit
+//     description: Maximum number of entries to return (all if not set)
+//     type: integer
+//     example: 100
+// responses:
+//   "200":
+//     description: API endpoints
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           type: array
+//           description: List of log entries
+//           items:
+//             type: string
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func networkACLLogGet(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	limit := 0
+	if queryParam(r, "limit") != "" {
+		var err error
+		limit, err = strconv.Atoi(queryParam(r, "limit"))
+		if err != nil || limit < 0 {
+			return response.BadRequest(fmt.Errorf("Invalid limit %q", queryParam(r, "limit")))
+		}
+	}
+
+	projectName, _, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	n, err := network.LoadByName(d.State(), projectName, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Only some network types log ACL rule matches to the kernel log.
+	type aclLogger interface {
+		ACLLog(limit int) ([]string, error)
+	}
+
+	aclLog, ok := n.(aclLogger)
+	if !ok {
+		return response.BadRequest(fmt.Errorf("Network driver %q does not support retrieving the ACL log", n.Type()))
+	}
+
+	entries, err := aclLog.ACLLog(limit)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, entries)
+}
+
+// swagger:operation POST /1.0/networks/{name}/acl-evaluate networks network_acl_evaluate_post
+//
+// Evaluate a packet against the network ACLs
+//
+// Evaluates whether a packet would be allowed by the ACLs (and default ACL rules) of the network and which rule
+// it would match. This only uses the ACL configuration and doesn't check against the live firewall.
+//
+// ---
+// consumes:
+//   - application/json
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+//   - in: body
+//     name: packet
+//     description: Packet to evaluate
+//     required: true
+//     schema:
+//       $ref: "#/definitions/NetworkACLEvaluatePost"
+// responses:
+//   "200":
+//     description: Evaluation result
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/NetworkACLEvaluation"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func networkACLEvaluatePost(d *Daemon, r *http.Request) response.Response {
+	req := api.NetworkACLEvaluatePost{}
+
+	// Parse the request.
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	packet := acl.FirewallPacket{
+		Direction:       req.Direction,
+		Source:          net.ParseIP(req.Source),
+		Destination:     net.ParseIP(req.Destination),
+		Protocol:        req.Protocol,
+		SourcePort:      req.SourcePort,
+		DestinationPort: req.DestinationPort,
+	}
+
+	if !shared.StringInSlice(packet.Direction, []string{"ingress", "egress"}) {
+		return response.BadRequest(fmt.Errorf("Invalid direction %q", req.Direction))
+	}
+
+	if packet.Source == nil {
+		return response.BadRequest(fmt.Errorf("Invalid source address %q", req.Source))
+	}
+
+	if packet.Destination == nil {
+		return response.BadRequest(fmt.Errorf("Invalid destination address %q", req.Destination))
+	}
+
+	projectName, _, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	n, err := network.LoadByName(d.State(), projectName, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Only some network types support evaluating packets against their ACLs.
+	type aclEvaluator interface {
+		ACLEvaluate(packet acl.FirewallPacket) (*acl.FirewallEvaluation, error)
+	}
+
+	evaluator, ok := n.(aclEvaluator)
+	if !ok {
+		return response.BadRequest(fmt.Errorf("Network driver %q does not support evaluating ACLs", n.Type()))
+	}
+
+	evaluation, err := evaluator.ACLEvaluate(packet)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, api.NetworkACLEvaluation{
+		Allowed: evaluation.Allowed,
+		Action:  evaluation.Action,
+		ACL:     evaluation.ACLName,
+		Rule:    evaluation.Rule,
+	})
+}
+
 func networkStartup(s *state.State) error {
 	var err error
 
@@ -1398,7 +1822,8 @@ func networkStartup(s *state.State) error {
 	return nil
 }
 
-func networkShutdown(s *state.State) error {
+// networksLoadAll loads all the managed networks of all projects.
+func networksLoadAll(s *state.State) ([]network.Network, error) {
 	var err error
 
 	// Get a list of projects.
@@ -1409,33 +1834,186 @@ func networkShutdown(s *state.State) error {
 		return err
 	})
 	if err != nil {
-		return errors.Wrapf(err, "Failed to load projects")
+		return nil, errors.Wrapf(err, "Failed to load projects")
 	}
 
+	networks := []network.Network{}
 	for _, projectName := range projectNames {
 		// Get a list of managed networks.
-		networks, err := s.Cluster.GetNetworks(projectName)
+		networkNames, err := s.Cluster.GetNetworks(projectName)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to load networks for project %q", projectName)
+			return nil, errors.Wrapf(err, "Failed to load networks for project %q", projectName)
 		}
 
-		// Bring them all down.
-		for _, name := range networks {
+		for _, name := range networkNames {
 			n, err := network.LoadByName(s, projectName, name)
 			if err != nil {
-				return errors.Wrapf(err, "Failed to load network %q in project %q", name, projectName)
+				return nil, errors.Wrapf(err, "Failed to load network %q in project %q", name, projectName)
 			}
 
-			err = n.Stop()
-			if err != nil {
-				logger.Error("Failed to bring down network", log.Ctx{"err": err, "project": projectName, "name": name})
-			}
+			networks = append(networks, n)
+		}
+	}
+
+	return networks, nil
+}
+
+// networkBGPGracefulShutdown announces the graceful shutdown of the BGP prefixes of all networks, so that peers can
+// reroute traffic while LXD is shutting down, and returns until when the prefixes should be held.
+func networkBGPGracefulShutdown(s *state.State) (time.Time, error) {
+	// Networks able to announce their BGP withdrawal to peers ahead of being stopped.
+	type bgpGracefulShutdowner interface {
+		BGPGracefulShutdown() (time.Duration, error)
+	}
+
+	networks, err := networksLoadAll(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var hold time.Duration
+	for _, n := range networks {
+		shutdowner, ok := n.(bgpGracefulShutdowner)
+		if !ok {
+			continue
+		}
+
+		networkHold, err := shutdowner.BGPGracefulShutdown()
+		if err != nil {
+			logger.Error("Failed to announce BGP graceful shutdown", log.Ctx{"err": err, "project": n.Project(), "name": n.Name()})
+			continue
+		}
+
+		if networkHold > hold {
+			hold = networkHold
+		}
+	}
+
+	return time.Now().Add(hold), nil
+}
+
+// networkShutdown stops all networks, once the BGP graceful shutdown announced until bgpHoldUntil is over.
+func networkShutdown(s *state.State, bgpHoldUntil time.Time) error {
+	networks, err := networksLoadAll(s)
+	if err != nil {
+		return err
+	}
+
+	time.Sleep(time.Until(bgpHoldUntil))
+
+	// Bring them all down.
+	for _, n := range networks {
+		err = n.Stop()
+		if err != nil {
+			logger.Error("Failed to bring down network", log.Ctx{"err": err, "project": n.Project(), "name": n.Name()})
 		}
 	}
 
 	return nil
 }
 
+// networkACLScheduleTask re-applies the firewall ACL rules of local networks whose scheduled ACL rules have become
+// active or inactive since the previous run (minutely).
+func networkACLScheduleTask(d *Daemon) (task.Func, task.Schedule) {
+	type aclScheduleRefresher interface {
+		ACLScheduleRefresh(since time.Time) error
+	}
+
+	lastRun := time.Now()
+	f := func(ctx context.Context) {
+		since := lastRun
+		lastRun = time.Now()
+
+		s := d.State()
+
+		var projectNetworks map[string]map[int64]api.Network
+		err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			projectNetworks, err = tx.GetCreatedNetworks()
+			return err
+		})
+		if err != nil {
+			logger.Error("Failed loading networks for scheduled ACL rules", log.Ctx{"err": err})
+			return
+		}
+
+		for projectName, networks := range projectNetworks {
+			for _, netInfo := range networks {
+				if netInfo.Config["security.acls"] == "" {
+					continue
+				}
+
+				n, err := network.LoadByName(s, projectName, netInfo.Name)
+				if err != nil {
+					logger.Error("Failed loading network for scheduled ACL rules", log.Ctx{"err": err, "project": projectName, "name": netInfo.Name})
+					continue
+				}
+
+				refresher, ok := n.(aclScheduleRefresher)
+				if !ok {
+					continue
+				}
+
+				err = refresher.ACLScheduleRefresh(since)
+				if err != nil {
+					logger.Error("Failed applying scheduled ACL rules", log.Ctx{"err": err, "project": projectName, "name": netInfo.Name})
+				}
+			}
+		}
+	}
+
+	return f, task.Every(time.Minute)
+}
+
+// networkForwardTargetsTask re-applies the address forwards of local networks whose preferred reachable forward
+// target addresses have changed since they were applied (minutely).
+func networkForwardTargetsTask(d *Daemon) (task.Func, task.Schedule) {
+	type forwardTargetsRefresher interface {
+		ForwardTargetsRefresh() error
+	}
+
+	f := func(ctx context.Context) {
+		s := d.State()
+
+		var projectNetworks map[string]map[int64]api.Network
+		err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			projectNetworks, err = tx.GetCreatedNetworks()
+			return err
+		})
+		if err != nil {
+			logger.Error("Failed loading networks for address forward targets", log.Ctx{"err": err})
+			return
+		}
+
+		for projectName, networks := range projectNetworks {
+			for _, netInfo := range networks {
+				if netInfo.Type != "bridge" {
+					continue
+				}
+
+				n, err := network.LoadByName(s, projectName, netInfo.Name)
+				if err != nil {
+					logger.Error("Failed loading network for address forward targets", log.Ctx{"err": err, "project": projectName, "name": netInfo.Name})
+					continue
+				}
+
+				refresher, ok := n.(forwardTargetsRefresher)
+				if !ok {
+					continue
+				}
+
+				err = refresher.ForwardTargetsRefresh()
+				if err != nil {
+					logger.Error("Failed applying address forward targets", log.Ctx{"err": err, "project": projectName, "name": netInfo.Name})
+				}
+			}
+		}
+	}
+
+	return f, task.Every(time.Minute)
+}
+
 // swagger:operation GET /1.0/networks/{name}/state networks networks_state_get
 //
 // Get the network state
@@ -1483,17 +2061,124 @@ func networkShutdown(s *state.State) error {
 //     $ref: "#/responses/InternalServerError"
 func networkStateGet(d *Daemon, r *http.Request) response.Response {
 	// If a target was specified, forward the request to the relevant node.
-	resp := forwardedResponseIfTargetIsRemote(d, r)
+	resp := forwardedResponseIfTargetIsRemoteTimeout(d, r)
 	if resp != nil {
 		return resp
 	}
 
+	projectName, _, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	name := mux.Vars(r)["name"]
 
-	state, err := resources.GetNetworkState(name)
+	var state *api.NetworkState
+
+	// Managed networks can provide additional state information, otherwise use the interface state directly.
+	n, err := network.LoadByName(d.State(), projectName, name)
+	if err == nil {
+		state, err = n.State()
+	} else if err == db.ErrNoSuchObject {
+		state, err = resources.GetNetworkState(name)
+	}
+
 	if err != nil {
 		return response.SmartError(err)
 	}
 
 	return response.SyncResponse(true, state)
 }
+
+// swagger:operation POST /1.0/networks/{name}/state networks networks_state_post
+//
+// Drain or undrain the network
+//
+// Stops the network from handing out new DHCP leases on the cluster member (drain) or restores normal
+// DHCP service (undrain), leaving the network and existing leases in place.
+//
+// ---
+// consumes:
+//   - application/json
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+//   - in: query
+//     name: target
+//     description: Cluster member name
+//     type: string
+//     example: lxd01
+//   - in: body
+//     name: state
+//     description: Network state action
+//     required: true
+//     schema:
+//       $ref: "#/definitions/NetworkStatePost"
+// responses:
+//   "200":
+//     $ref: "#/responses/EmptySyncResponse"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func networkStatePost(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, _, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Parse the request.
+	req := api.NetworkStatePost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	n, err := network.LoadByName(d.State(), projectName, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Draining is only supported by some network types.
+	type drainer interface {
+		Drain() error
+		Undrain() error
+	}
+
+	networkDrainer, ok := n.(drainer)
+	if !ok {
+		return response.BadRequest(fmt.Errorf("Network driver %q does not support draining", n.Type()))
+	}
+
+	if n.LocalStatus() != api.NetworkStatusCreated {
+		return response.BadRequest(fmt.Errorf("Network is not created on this member"))
+	}
+
+	switch req.Action {
+	case "drain":
+		err = networkDrainer.Drain()
+	case "undrain":
+		err = networkDrainer.Undrain()
+	default:
+		return response.BadRequest(fmt.Errorf("Unknown action %q", req.Action))
+	}
+
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -81,6 +81,11 @@ func (c *Config) DNSAddress() string {
 	return c.m.GetString("core.dns_address")
 }
 
+// DNSUpstreams returns the upstream DNS resolvers that queries outside of the served zones are forwarded to.
+func (c *Config) DNSUpstreams() []string {
+	return util.SplitNTrimSpace(c.m.GetString("core.dns_upstreams"), ",", -1, true)
+}
+
 // MetricsAddress returns the address and port to setup the metrics listener on
 func (c *Config) MetricsAddress() string {
 	metricsAddress := c.m.GetString("core.metrics_address")
@@ -274,6 +279,9 @@ var ConfigSchema = config.Schema{
 	// Network address for the DNS server
 	"core.dns_address": {Validator: validate.Optional(validate.IsListenAddress(true, true, false))},
 
+	// Upstream DNS resolvers for queries outside of the served zones
+	"core.dns_upstreams": {Validator: validate.Optional(validate.IsListOf(validate.IsListenAddress(true, false, false)))},
+
 	// Network address for the debug server
 	"core.metrics_address": {Validator: validate.Optional(validate.IsListenAddress(true, true, false))},
 
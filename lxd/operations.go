@@ -203,7 +203,7 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	return response.ForwardedResponse(client, r)
+	return response.ForwardedResponseTimeout(client, r, forwardedResponseTimeout)
 }
 
 // swagger:operation DELETE /1.0/operations/{id} operations operation_delete
@@ -280,7 +280,7 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	return response.ForwardedResponse(client, r)
+	return response.ForwardedResponseTimeout(client, r, forwardedResponseTimeout)
 }
 
 // operationCancel cancels an operation that exists on any member.
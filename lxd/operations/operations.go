@@ -121,6 +121,10 @@ type Operation struct {
 
 	state  *state.State
 	events *events.Server
+
+	// Cancelled once the operation is done.
+	ctx       context.Context
+	ctxCancel context.CancelFunc
 }
 
 // OperationCreate creates a new operation and returns it. If it cannot be
@@ -146,6 +150,7 @@ func OperationCreate(s *state.State, projectName string, opClass OperationClass,
 	op.resources = opResources
 	op.chanDone = make(chan error)
 	op.state = s
+	op.ctx, op.ctxCancel = context.WithCancel(context.Background())
 
 	if s != nil {
 		op.SetEventServer(s.Events)
@@ -231,6 +236,10 @@ func (op *Operation) done() {
 	close(op.chanDone)
 	op.lock.Unlock()
 
+	if op.ctxCancel != nil {
+		op.ctxCancel()
+	}
+
 	time.AfterFunc(time.Second*5, func() {
 		operationsLock.Lock()
 		_, ok := operations[op.id]
@@ -601,6 +610,15 @@ func (op *Operation) Resources() map[string][]string {
 	return op.resources
 }
 
+// Context returns a context that is cancelled once the operation is done (including when it is cancelled).
+func (op *Operation) Context() context.Context {
+	if op.ctx == nil {
+		return context.Background()
+	}
+
+	return op.ctx
+}
+
 // SetCanceler sets a canceler.
 func (op *Operation) SetCanceler(canceler *cancel.Canceler) {
 	op.canceler = canceler
@@ -2,13 +2,24 @@ package main
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/response"
 )
 
+// forwardedResponseTimeout is how long to wait for another node to start responding to the short, non-streaming
+// requests forwarded to it.
+const forwardedResponseTimeout = 30 * time.Second
+
 func forwardedResponseToNode(d *Daemon, r *http.Request, node string) response.Response {
+	return forwardedResponseToNodeTimeout(d, r, node, 0)
+}
+
+// forwardedResponseToNodeTimeout is like forwardedResponseToNode, but fails with a 504 error if the target node
+// doesn't start responding within the given timeout (unlimited if zero).
+func forwardedResponseToNodeTimeout(d *Daemon, r *http.Request, node string, timeout time.Duration) response.Response {
 	// Figure out the address of the target node (which is possibly
 	// this very same node).
 	address, err := cluster.ResolveTarget(d.cluster, node)
@@ -22,7 +33,7 @@ func forwardedResponseToNode(d *Daemon, r *http.Request, node string) response.R
 		if err != nil {
 			return response.SmartError(err)
 		}
-		return response.ForwardedResponse(client, r)
+		return response.ForwardedResponseTimeout(client, r, timeout)
 	}
 
 	return nil
@@ -39,6 +50,18 @@ func forwardedResponseIfTargetIsRemote(d *Daemon, r *http.Request) response.Resp
 	return forwardedResponseToNode(d, r, targetNode)
 }
 
+// forwardedResponseIfTargetIsRemoteTimeout is like forwardedResponseIfTargetIsRemote, but fails with a 504 error
+// if the target node doesn't start responding within forwardedResponseTimeout. To be used for short, non-streaming
+// requests only.
+func forwardedResponseIfTargetIsRemoteTimeout(d *Daemon, r *http.Request) response.Response {
+	targetNode := queryParam(r, "target")
+	if targetNode == "" {
+		return nil
+	}
+
+	return forwardedResponseToNodeTimeout(d, r, targetNode, forwardedResponseTimeout)
+}
+
 // forwardedResponseIfInstanceIsRemote redirects a request to the node running
 // the container with the given name. If the container is local, nothing gets
 // done and nil is returned.
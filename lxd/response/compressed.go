@@ -0,0 +1,132 @@
+package response
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type compressedResponse struct {
+	inner Response
+	req   *http.Request
+}
+
+// CompressedResponse wraps a response so that it is gzip compressed if the client accepts it.
+// If the client doesn't accept gzip, or the inner response sets its own Content-Encoding, the inner response is
+// rendered unchanged.
+func CompressedResponse(inner Response, r *http.Request) Response {
+	return &compressedResponse{inner: inner, req: r}
+}
+
+func (r *compressedResponse) Render(w http.ResponseWriter) error {
+	// Compressing partial content would make the ranges meaningless.
+	if !acceptsGzip(r.req) || r.req.Header.Get("Range") != "" {
+		return r.inner.Render(w)
+	}
+
+	gw := &gzipResponseWriter{ResponseWriter: w}
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	err := r.inner.Render(gw)
+	if err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+func (r *compressedResponse) String() string {
+	return fmt.Sprintf("compressed %s", r.inner.String())
+}
+
+// acceptsGzip returns whether the request's Accept-Encoding header allows a gzip encoded response.
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, encoding := range strings.Split(value, ",") {
+			fields := strings.Split(encoding, ";")
+			name := strings.TrimSpace(fields[0])
+			if name != "gzip" {
+				continue
+			}
+
+			// An encoding with a zero quality value is explicitly not acceptable.
+			acceptable := true
+			for _, param := range fields[1:] {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(param, "q=") {
+					continue
+				}
+
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err != nil || q == 0 {
+					acceptable = false
+				}
+			}
+
+			return acceptable
+		}
+	}
+
+	return false
+}
+
+// gzipResponseWriter compresses the body written to the wrapped http.ResponseWriter.
+// Whether to compress is decided when the header is written, so that responses which already set their own
+// Content-Encoding (such as forwarded ones) are passed through unchanged.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+
+	if w.Header().Get("Content-Encoding") == "" && code != http.StatusNoContent && code != http.StatusNotModified {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+
+	return w.gz.Write(b)
+}
+
+// Flush flushes any buffered compressed data to the client.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if ok {
+		flusher.Flush()
+	}
+}
+
+// Close writes any remaining compressed data.
+func (w *gzipResponseWriter) Close() error {
+	if w.gz == nil {
+		return nil
+	}
+
+	return w.gz.Close()
+}
@@ -0,0 +1,94 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SyncResponsePaginated returns a syncResponse containing a page of the items slice.
+// The page is selected by the "offset" and "limit" query parameters of the request, all items from offset
+// onwards are returned if no limit is given. The total number of items is sent in the X-LXD-Total-Count header
+// and links to the next and previous pages are sent in the Link header (RFC 5988).
+func SyncResponsePaginated(r *http.Request, items interface{}) Response {
+	value := reflect.ValueOf(items)
+	if value.Kind() != reflect.Slice {
+		return InternalError(fmt.Errorf("Invalid paginated response type %T", items))
+	}
+
+	total := value.Len()
+
+	offset, err := paginationParam(r, "offset", 0)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	limit, err := paginationParam(r, "limit", -1)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	start := offset
+	if start > total {
+		start = total
+	}
+
+	end := total
+	if limit >= 0 && start+limit < total {
+		end = start + limit
+	}
+
+	headers := map[string]string{"X-LXD-Total-Count": fmt.Sprintf("%d", total)}
+
+	if limit > 0 {
+		links := []string{}
+
+		if end < total {
+			links = append(links, fmt.Sprintf(`<%s>; rel="next"`, paginationURL(r, end, limit)))
+		}
+
+		if start > 0 {
+			prev := start - limit
+			if prev < 0 {
+				prev = 0
+			}
+
+			links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, paginationURL(r, prev, limit)))
+		}
+
+		if len(links) > 0 {
+			headers["Link"] = strings.Join(links, ", ")
+		}
+	}
+
+	return SyncResponseHeaders(true, value.Slice(start, end).Interface(), headers)
+}
+
+// paginationParam returns the value of a non-negative integer query parameter, or defaultValue if not set.
+func paginationParam(r *http.Request, name string, defaultValue int) (int, error) {
+	valueStr := r.FormValue(name)
+	if valueStr == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value < 0 {
+		return -1, fmt.Errorf("Invalid %q value %q", name, valueStr)
+	}
+
+	return value, nil
+}
+
+// paginationURL returns the request URL with its offset and limit query parameters replaced.
+func paginationURL(r *http.Request, offset int, limit int) string {
+	values := r.URL.Query()
+	values.Set("offset", fmt.Sprintf("%d", offset))
+	values.Set("limit", fmt.Sprintf("%d", limit))
+
+	u := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+
+	return u.String()
+}
@@ -1,13 +1,17 @@
 package response
 
 import (
+	"archive/tar"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"os"
+	"strings"
 	"time"
 
 	log "gopkg.in/inconshreveable/log15.v2"
@@ -109,8 +113,18 @@ func (r *syncResponse) Render(w http.ResponseWriter) error {
 	}
 
 	// Handle plain text headers.
+	var plaintext string
 	if r.plaintext {
+		if r.metadata != nil {
+			var ok bool
+			plaintext, ok = r.metadata.(string)
+			if !ok {
+				return fmt.Errorf("Invalid plain text response metadata type %T", r.metadata)
+			}
+		}
+
 		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(plaintext)))
 	}
 
 	// Write header and status code.
@@ -122,11 +136,9 @@ func (r *syncResponse) Render(w http.ResponseWriter) error {
 
 	// Handle plain text responses.
 	if r.plaintext {
-		if r.metadata != nil {
-			_, err := w.Write([]byte(r.metadata.(string)))
-			if err != nil {
-				return err
-			}
+		_, err := w.Write([]byte(plaintext))
+		if err != nil {
+			return err
 		}
 
 		return nil
@@ -158,18 +170,30 @@ func (r *syncResponse) String() string {
 
 // Error response
 type errorResponse struct {
-	code int    // Code to return in both the HTTP header and Code field of the response body.
-	msg  string // Message to return in the Error field of the response body.
+	code    int    // Code to return in both the HTTP header and Code field of the response body.
+	msg     string // Message to return in the Error field of the response body.
+	errorID string // Optional machine-readable identifier to return in the ErrorID field of the response body.
 }
 
 // ErrorResponse returns an error response with the given code and msg.
 func ErrorResponse(code int, msg string) Response {
-	return &errorResponse{code, msg}
+	return &errorResponse{code: code, msg: msg}
+}
+
+// ErrorResponseWithCode returns an error response with the given code, msg and machine-readable error identifier
+// (such as "network_in_use"). Unlike the message, the identifier is stable across versions.
+func ErrorResponseWithCode(code int, msg string, errorID string) Response {
+	return &errorResponse{code: code, msg: msg, errorID: errorID}
 }
 
 // BadRequest returns a bad request response (400) with the given error.
 func BadRequest(err error) Response {
-	return &errorResponse{http.StatusBadRequest, err.Error()}
+	return &errorResponse{code: http.StatusBadRequest, msg: err.Error()}
+}
+
+// BadRequestWithCode returns a bad request response (400) with the given error and machine-readable identifier.
+func BadRequestWithCode(err error, errorID string) Response {
+	return &errorResponse{code: http.StatusBadRequest, msg: err.Error(), errorID: errorID}
 }
 
 // Conflict returns a conflict response (409) with the given error.
@@ -179,7 +203,15 @@ func Conflict(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusConflict, message}
+	return &errorResponse{code: http.StatusConflict, msg: message}
+}
+
+// ConflictWithCode returns a conflict response (409) with the given error and machine-readable identifier.
+func ConflictWithCode(err error, errorID string) Response {
+	resp := Conflict(err).(*errorResponse)
+	resp.errorID = errorID
+
+	return resp
 }
 
 // Forbidden returns a forbidden response (403) with the given error.
@@ -189,12 +221,12 @@ func Forbidden(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusForbidden, message}
+	return &errorResponse{code: http.StatusForbidden, msg: message}
 }
 
 // InternalError returns an internal error response (500) with the given error.
 func InternalError(err error) Response {
-	return &errorResponse{http.StatusInternalServerError, err.Error()}
+	return &errorResponse{code: http.StatusInternalServerError, msg: err.Error()}
 }
 
 // NotFound returns a not found response (404) with the given error.
@@ -204,7 +236,7 @@ func NotFound(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusNotFound, message}
+	return &errorResponse{code: http.StatusNotFound, msg: message}
 }
 
 // NotImplemented returns a not implemented response (501) with the given error.
@@ -214,13 +246,13 @@ func NotImplemented(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusNotImplemented, message}
+	return &errorResponse{code: http.StatusNotImplemented, msg: message}
 }
 
 // PreconditionFailed returns a precondition failed response (412) with the
 // given error.
 func PreconditionFailed(err error) Response {
-	return &errorResponse{http.StatusPreconditionFailed, err.Error()}
+	return &errorResponse{code: http.StatusPreconditionFailed, msg: err.Error()}
 }
 
 // Unavailable return an unavailable response (503) with the given error.
@@ -230,7 +262,7 @@ func Unavailable(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusServiceUnavailable, message}
+	return &errorResponse{code: http.StatusServiceUnavailable, msg: message}
 }
 
 func (r *errorResponse) String() string {
@@ -249,9 +281,10 @@ func (r *errorResponse) Render(w http.ResponseWriter) error {
 	}
 
 	resp := api.ResponseRaw{
-		Type:  api.ErrorResponse,
-		Error: r.msg,
-		Code:  r.code, // Set the error code in the Code field of the response body.
+		Type:    api.ErrorResponse,
+		Error:   r.msg,
+		Code:    r.code, // Set the error code in the Code field of the response body.
+		ErrorID: r.errorID,
 	}
 
 	err := json.NewEncoder(output).Encode(resp)
@@ -281,18 +314,48 @@ type FileResponseEntry struct {
 	Path       string
 	Filename   string
 	Buffer     []byte /* either a path or a buffer must be provided */
+
+	// SHA256 is the hex encoded SHA-256 checksum of the file. For single file responses, it is sent in the
+	// X-LXD-Content-SHA256 header so that clients can verify the download.
+	SHA256 string
+
+	// ModTime is the modification time of the file. If not set, the modification time of the file at Path is
+	// used, or the current time for buffers.
+	ModTime time.Time
+}
+
+// modTime returns the modification time of the entry, falling back to the one in fi (if any) or the current time.
+func (e FileResponseEntry) modTime(fi os.FileInfo) time.Time {
+	if !e.ModTime.IsZero() {
+		return e.ModTime
+	}
+
+	if fi != nil {
+		return fi.ModTime()
+	}
+
+	return time.Now()
 }
 
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
 type fileResponse struct {
 	req              *http.Request
 	files            []FileResponseEntry
 	headers          map[string]string
 	removeAfterServe bool
+	tar              bool
 }
 
 // FileResponse returns a new file response.
 func FileResponse(r *http.Request, files []FileResponseEntry, headers map[string]string, removeAfterServe bool) Response {
-	return &fileResponse{r, files, headers, removeAfterServe}
+	return &fileResponse{req: r, files: files, headers: headers, removeAfterServe: removeAfterServe}
+}
+
+// FileTarResponse returns a new file response that streams all the files as a single tar archive, using each
+// entry's Filename as the name of its member.
+func FileTarResponse(r *http.Request, files []FileResponseEntry, headers map[string]string, removeAfterServe bool) Response {
+	return &fileResponse{req: r, files: files, headers: headers, removeAfterServe: removeAfterServe, tar: true}
 }
 
 func (r *fileResponse) Render(w http.ResponseWriter) error {
@@ -302,6 +365,10 @@ func (r *fileResponse) Render(w http.ResponseWriter) error {
 		}
 	}
 
+	if r.tar {
+		return r.renderTar(w)
+	}
+
 	// No file, well, it's easy then
 	if len(r.files) == 0 {
 		return nil
@@ -315,7 +382,7 @@ func (r *fileResponse) Render(w http.ResponseWriter) error {
 
 		if r.files[0].Path == "" {
 			rs = bytes.NewReader(r.files[0].Buffer)
-			mt = time.Now()
+			mt = r.files[0].modTime(nil)
 			sz = int64(len(r.files[0].Buffer))
 		} else {
 			f, err := os.Open(r.files[0].Path)
@@ -329,11 +396,16 @@ func (r *fileResponse) Render(w http.ResponseWriter) error {
 				return err
 			}
 
-			mt = fi.ModTime()
+			mt = r.files[0].modTime(fi)
 			sz = fi.Size()
 			rs = f
 		}
 
+		if r.files[0].SHA256 != "" {
+			// The checksum is of the whole file, even if a range was requested.
+			w.Header().Set("X-LXD-Content-SHA256", r.files[0].SHA256)
+		}
+
 		w.Header().Set("Content-Type", "application/octet-stream")
 		w.Header().Set("Content-Length", fmt.Sprintf("%d", sz))
 		w.Header().Set("Content-Disposition", fmt.Sprintf("inline;filename=%s", r.files[0].Filename))
@@ -358,6 +430,7 @@ func (r *fileResponse) Render(w http.ResponseWriter) error {
 
 	for _, entry := range r.files {
 		var rd io.Reader
+		var mt time.Time
 		if entry.Path != "" {
 			fd, err := os.Open(entry.Path)
 			if err != nil {
@@ -365,12 +438,25 @@ func (r *fileResponse) Render(w http.ResponseWriter) error {
 			}
 			defer fd.Close()
 
+			fi, err := fd.Stat()
+			if err != nil {
+				return err
+			}
+
+			mt = entry.modTime(fi)
 			rd = fd
 		} else {
+			mt = entry.modTime(nil)
 			rd = bytes.NewReader(entry.Buffer)
 		}
 
-		fw, err := mw.CreateFormFile(entry.Identifier, entry.Filename)
+		// Same as multipart.Writer.CreateFormFile but with the modification time of each part.
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(entry.Identifier), quoteEscaper.Replace(entry.Filename)))
+		h.Set("Content-Type", "application/octet-stream")
+		h.Set("Last-Modified", mt.UTC().Format(http.TimeFormat))
+
+		fw, err := mw.CreatePart(h)
 		if err != nil {
 			return err
 		}
@@ -384,6 +470,73 @@ func (r *fileResponse) Render(w http.ResponseWriter) error {
 	return nil
 }
 
+// renderTar writes the files as a tar stream.
+func (r *fileResponse) renderTar(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Transfer-Encoding", "chunked")
+
+	tw := tar.NewWriter(w)
+
+	for _, entry := range r.files {
+		hdr := &tar.Header{
+			Name:    entry.Filename,
+			Mode:    0644,
+			ModTime: entry.modTime(nil),
+		}
+
+		var rd io.Reader
+		if entry.Path != "" {
+			fd, err := os.Open(entry.Path)
+			if err != nil {
+				return err
+			}
+			defer fd.Close()
+
+			fi, err := fd.Stat()
+			if err != nil {
+				return err
+			}
+
+			hdr.Size = fi.Size()
+			hdr.ModTime = entry.modTime(fi)
+			rd = fd
+		} else {
+			hdr.Size = int64(len(entry.Buffer))
+			rd = bytes.NewReader(entry.Buffer)
+		}
+
+		err := tw.WriteHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(tw, rd)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := tw.Close()
+	if err != nil {
+		return err
+	}
+
+	if r.removeAfterServe {
+		for _, entry := range r.files {
+			if entry.Path == "" {
+				continue
+			}
+
+			err := os.Remove(entry.Path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (r *fileResponse) String() string {
 	return fmt.Sprintf("%d files", len(r.files))
 }
@@ -391,14 +544,25 @@ func (r *fileResponse) String() string {
 type forwardedResponse struct {
 	client  lxd.InstanceServer
 	request *http.Request
+	timeout time.Duration
 }
 
 // ForwardedResponse takes a request directed to a node and forwards it to
 // another node, writing back the response it gegs.
+// There is no limit on how long the other node can take to respond, as some requests (such as waiting for an
+// operation) only get a response once a long running task completes.
 func ForwardedResponse(client lxd.InstanceServer, request *http.Request) Response {
+	return ForwardedResponseTimeout(client, request, 0)
+}
+
+// ForwardedResponseTimeout is like ForwardedResponse, but fails with a 504 error if the other node doesn't start
+// responding within the given timeout. Once the response has started, it can take as long as needed to complete.
+// A zero timeout disables the limit.
+func ForwardedResponseTimeout(client lxd.InstanceServer, request *http.Request, timeout time.Duration) Response {
 	return &forwardedResponse{
 		client:  client,
 		request: request,
+		timeout: timeout,
 	}
 }
 
@@ -408,8 +572,13 @@ func (r *forwardedResponse) Render(w http.ResponseWriter) error {
 		return err
 	}
 
+	// Derive the forwarded request's context from the incoming one, so that it is cancelled if the client
+	// goes away.
+	ctx, cancel := context.WithCancel(r.request.Context())
+	defer cancel()
+
 	url := fmt.Sprintf("%s%s", info.Addresses[0], r.request.URL.RequestURI())
-	forwarded, err := http.NewRequest(r.request.Method, url, r.request.Body)
+	forwarded, err := http.NewRequestWithContext(ctx, r.request.Method, url, r.request.Body)
 	if err != nil {
 		return err
 	}
@@ -423,10 +592,30 @@ func (r *forwardedResponse) Render(w http.ResponseWriter) error {
 		return err
 	}
 
+	// Cancel the forwarded request if the other node doesn't start responding in time.
+	var timer *time.Timer
+	if r.timeout > 0 {
+		timer = time.AfterFunc(r.timeout, cancel)
+	}
+
 	response, err := httpClient.Do(forwarded)
+
+	// Once the other node is responding, let the transfer take as long as it needs.
+	timedOut := timer != nil && !timer.Stop()
+
+	if timedOut {
+		// The request context has been cancelled, so even a response received just in time is unusable.
+		if err == nil {
+			response.Body.Close()
+		}
+
+		return ErrorResponse(http.StatusGatewayTimeout, fmt.Sprintf("Timed out waiting for a response from %q", info.Addresses[0])).Render(w)
+	}
+
 	if err != nil {
 		return err
 	}
+	defer response.Body.Close()
 
 	for key := range response.Header {
 		w.Header().Set(key, response.Header.Get(key))
@@ -26,7 +26,7 @@ func SmartError(err error) Response {
 	}
 
 	if statusCode, found := api.StatusErrorMatch(err); found {
-		return &errorResponse{statusCode, err.Error()}
+		return &errorResponse{code: statusCode, msg: err.Error()}
 	}
 
 	for httpStatusCode, checkErrs := range httpResponseErrors {
@@ -34,15 +34,15 @@ func SmartError(err error) Response {
 			if errors.Is(err, checkErr) || pkgErrors.Cause(err) == checkErr {
 				if err != checkErr {
 					// If the error has been wrapped return the top-level error message.
-					return &errorResponse{httpStatusCode, err.Error()}
+					return &errorResponse{code: httpStatusCode, msg: err.Error()}
 				}
 
 				// If the error hasn't been wrapped, replace the error message with the generic
 				// HTTP status text.
-				return &errorResponse{httpStatusCode, http.StatusText(httpStatusCode)}
+				return &errorResponse{code: httpStatusCode, msg: http.StatusText(httpStatusCode)}
 			}
 		}
 	}
 
-	return &errorResponse{http.StatusInternalServerError, err.Error()}
+	return &errorResponse{code: http.StatusInternalServerError, msg: err.Error()}
 }
@@ -776,8 +776,10 @@ func storagePoolVolumeTypeCustomBackupExportGet(d *Daemon, r *http.Request) resp
 		return response.SmartError(err)
 	}
 
+	backupPath := shared.VarPath("backups", "custom", poolName, project.StorageVolume(projectName, fullName))
 	ent := response.FileResponseEntry{
-		Path: shared.VarPath("backups", "custom", poolName, project.StorageVolume(projectName, fullName)),
+		Path:   backupPath,
+		SHA256: backup.ReadDigest(backupPath),
 	}
 
 	d.State().Events.SendLifecycle(projectName, lifecycle.StorageVolumeBackupRetrieved.Event(poolName, volumeTypeName, volumeName, projectName, request.CreateRequestor(r), nil))
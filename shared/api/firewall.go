@@ -0,0 +1,38 @@
+package api
+
+// FirewallTest represents the result of a firewall driver self-test
+//
+// swagger:model
+//
+// API extension: firewall_self_test
+type FirewallTest struct {
+	// Firewall driver that was tested
+	// Example: nftables
+	Driver string `json:"driver" yaml:"driver"`
+
+	// Whether all operations succeeded
+	// Example: true
+	Success bool `json:"success" yaml:"success"`
+
+	// List of operations performed by the test
+	Operations []FirewallTestOperation `json:"operations" yaml:"operations"`
+}
+
+// FirewallTestOperation represents a single operation performed by a firewall driver self-test
+//
+// swagger:model
+//
+// API extension: firewall_self_test
+type FirewallTestOperation struct {
+	// Name of the operation
+	// Example: snat
+	Name string `json:"name" yaml:"name"`
+
+	// Whether the operation succeeded
+	// Example: true
+	Success bool `json:"success" yaml:"success"`
+
+	// Error returned by the operation (if any)
+	// Example: Failed adding outbound NAT rules
+	Error string `json:"error" yaml:"error"`
+}
@@ -132,6 +132,48 @@ type NetworkLease struct {
 	//
 	// API extension: network_leases_location
 	Location string `json:"location" yaml:"location"`
+
+	// Whether a static lease is in use, by its MAC address holding a DHCPv4 lease or its address being leased over
+	// DHCPv6 (only set for static leases)
+	// Example: true
+	//
+	// API extension: network_leases_static_active
+	Active bool `json:"active" yaml:"active"`
+}
+
+// NetworkExternalSubnet represents usage of an external subnet by a network, instance NIC or network forward
+//
+// swagger:model
+//
+// API extension: network_external_subnets
+type NetworkExternalSubnet struct {
+	// The external subnet
+	// Example: 198.51.100.0/24
+	Subnet string `json:"subnet" yaml:"subnet"`
+
+	// Project of the network using the subnet
+	// Example: default
+	NetworkProject string `json:"network_project" yaml:"network_project"`
+
+	// Name of the network using the subnet
+	// Example: lxdbr0
+	NetworkName string `json:"network_name" yaml:"network_name"`
+
+	// Whether the subnet is used for the network's SNAT address
+	// Example: false
+	NetworkSNAT bool `json:"network_snat" yaml:"network_snat"`
+
+	// Project of the instance using the subnet (if used by an instance NIC)
+	// Example: default
+	InstanceProject string `json:"instance_project" yaml:"instance_project"`
+
+	// Name of the instance using the subnet (if used by an instance NIC)
+	// Example: c1
+	InstanceName string `json:"instance_name" yaml:"instance_name"`
+
+	// Name of the instance NIC using the subnet (if used by an instance NIC)
+	// Example: eth0
+	InstanceDevice string `json:"instance_device" yaml:"instance_device"`
 }
 
 // NetworkState represents the network state
@@ -174,6 +216,32 @@ type NetworkState struct {
 	//
 	// API extension: network_state_vlan
 	VLAN *NetworkStateVLAN `json:"vlan" yaml:"vlan"`
+
+	// Firewall rule counters (only for managed networks using ACLs or forwards)
+	//
+	// API extension: network_state_firewall_counters
+	Firewall *NetworkStateFirewall `json:"firewall" yaml:"firewall"`
+
+	// Cluster DNS forwarder state (only for clustered bridge networks using it)
+	//
+	// API extension: network_state_forkdns
+	Forkdns *NetworkStateForkdns `json:"forkdns" yaml:"forkdns"`
+
+	// Health of the network subsystems (only for managed bridge networks)
+	//
+	// API extension: network_state_health
+	Health *NetworkStateHealth `json:"health" yaml:"health"`
+}
+
+// NetworkStatePost represents the fields required to drain or undrain a network on a cluster member
+//
+// swagger:model
+//
+// API extension: network_state_drain
+type NetworkStatePost struct {
+	// The action to be performed. Valid actions are "drain" and "undrain".
+	// Example: drain
+	Action string `json:"action" yaml:"action"`
 }
 
 // NetworkStateAddress represents a network address
@@ -218,6 +286,59 @@ type NetworkStateCounters struct {
 	PacketsSent int64 `json:"packets_sent" yaml:"packets_sent"`
 }
 
+// NetworkStateFirewall represents the firewall rule counters of a network
+//
+// swagger:model
+//
+// API extension: network_state_firewall_counters
+type NetworkStateFirewall struct {
+	// Number of packets matched by ACL rules
+	// Example: 1182515
+	ACLPackets int64 `json:"acl_packets" yaml:"acl_packets"`
+
+	// Number of bytes matched by ACL rules
+	// Example: 250542118
+	ACLBytes int64 `json:"acl_bytes" yaml:"acl_bytes"`
+
+	// Number of packets matched by address forward rules
+	// Example: 1024
+	ForwardPackets int64 `json:"forward_packets" yaml:"forward_packets"`
+
+	// Number of bytes matched by address forward rules
+	// Example: 65536
+	ForwardBytes int64 `json:"forward_bytes" yaml:"forward_bytes"`
+}
+
+// NetworkStateForkdns represents the state of the cluster DNS forwarder of a network
+//
+// swagger:model
+//
+// API extension: network_state_forkdns
+type NetworkStateForkdns struct {
+	// Whether the forwarder process is running
+	// Example: true
+	Running bool `json:"running" yaml:"running"`
+
+	// Addresses of the cluster members DNS queries are forwarded to
+	// Example: ["10.0.0.2:1053", "10.0.0.3:1053"]
+	Servers []string `json:"servers" yaml:"servers"`
+}
+
+// NetworkStateHealth represents the health of the subsystems of a network
+//
+// swagger:model
+//
+// API extension: network_state_health
+type NetworkStateHealth struct {
+	// Whether all of the checked subsystems are healthy
+	// Example: false
+	Healthy bool `json:"healthy" yaml:"healthy"`
+
+	// Problem found with each checked subsystem (empty if healthy)
+	// Example: {"interface": "", "addresses": "", "dnsmasq": "The dnsmasq process isn't running"}
+	Subsystems map[string]string `json:"subsystems" yaml:"subsystems"`
+}
+
 // NetworkStateBond represents bond specific state
 //
 // swagger:model
@@ -282,6 +403,30 @@ type NetworkStateBridge struct {
 	// List of devices that are in the bridge
 	// Example: ["eth0", "eth1"]
 	UpperDevices []string `json:"upper_devices" yaml:"upper_devices"`
+
+	// VLAN memberships of the bridge and its ports, keyed by interface name (only for managed native bridges)
+	//
+	// API extension: network_state_bridge_vlans
+	VLANs map[string][]NetworkStateBridgeVLAN `json:"vlans" yaml:"vlans"`
+}
+
+// NetworkStateBridgeVLAN represents the membership of a bridge port in a VLAN
+//
+// swagger:model
+//
+// API extension: network_state_bridge_vlans
+type NetworkStateBridgeVLAN struct {
+	// VLAN ID
+	// Example: 100
+	VID uint64 `json:"vid" yaml:"vid"`
+
+	// Whether the VLAN is used for untagged ingress traffic on the port
+	// Example: true
+	PVID bool `json:"pvid" yaml:"pvid"`
+
+	// Whether egress traffic of the VLAN leaves the port untagged
+	// Example: true
+	Untagged bool `json:"untagged" yaml:"untagged"`
 }
 
 // NetworkStateVLAN represents VLAN specific state
@@ -48,6 +48,12 @@ type NetworkACLRule struct {
 	// State of the rule
 	// Example: enabled
 	State string `json:"state" yaml:"state"`
+
+	// Cron expression(s) of the minutes during which the rule is active (always active if empty)
+	// Example: * 22-23 * * 6
+	//
+	// API extension: network_acl_rule_schedule
+	Schedule string `json:"schedule,omitempty" yaml:"schedule,omitempty"`
 }
 
 // Normalise normalises the fields in the rule so that they are comparable with ones stored.
@@ -58,6 +64,7 @@ func (r *NetworkACLRule) Normalise() {
 	r.ICMPCode = strings.TrimSpace(r.ICMPCode)
 	r.Description = strings.TrimSpace(r.Description)
 	r.State = strings.TrimSpace(r.State)
+	r.Schedule = strings.TrimSpace(r.Schedule)
 
 	// Remove space from Source subject list.
 	subjects := strings.Split(r.Source, ",")
@@ -149,3 +156,56 @@ type NetworkACLsPost struct {
 	NetworkACLPost `yaml:",inline"`
 	NetworkACLPut  `yaml:",inline"`
 }
+
+// NetworkACLEvaluatePost represents a packet to evaluate against a network's ACLs
+//
+// swagger:model
+//
+// API extension: network_acl_evaluate
+type NetworkACLEvaluatePost struct {
+	// Direction of the packet relative to the instance ("ingress" or "egress")
+	// Example: ingress
+	Direction string `json:"direction" yaml:"direction"`
+
+	// Source address
+	// Example: 192.0.2.1
+	Source string `json:"source" yaml:"source"`
+
+	// Destination address
+	// Example: 10.0.0.2
+	Destination string `json:"destination" yaml:"destination"`
+
+	// Protocol ("tcp", "udp", "icmp4" or "icmp6")
+	// Example: tcp
+	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+
+	// Source port (for the tcp and udp protocols)
+	// Example: 1234
+	SourcePort uint64 `json:"source_port,omitempty" yaml:"source_port,omitempty"`
+
+	// Destination port (for the tcp and udp protocols)
+	// Example: 22
+	DestinationPort uint64 `json:"destination_port,omitempty" yaml:"destination_port,omitempty"`
+}
+
+// NetworkACLEvaluation represents the result of evaluating a packet against a network's ACLs
+//
+// swagger:model
+//
+// API extension: network_acl_evaluate
+type NetworkACLEvaluation struct {
+	// Whether the packet is allowed
+	// Example: true
+	Allowed bool `json:"allowed" yaml:"allowed"`
+
+	// Action of the matched rule
+	// Example: allow
+	Action string `json:"action" yaml:"action"`
+
+	// Name of the ACL containing the matched rule (empty if a default rule matched)
+	// Example: web
+	ACL string `json:"acl" yaml:"acl"`
+
+	// Matched rule (nil if a default rule matched)
+	Rule *NetworkACLRule `json:"rule" yaml:"rule"`
+}
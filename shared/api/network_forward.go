@@ -27,7 +27,7 @@ type NetworkForwardPort struct {
 	// Example: 80,81,8080-8090
 	TargetPort string `json:"target_port" yaml:"target_port"`
 
-	// TargetAddress to forward ListenPorts to
+	// TargetAddress to forward ListenPorts to (comma delimited list of failover targets in order of preference)
 	// Example: 198.51.100.2
 	TargetAddress string `json:"target_address" yaml:"target_address"`
 }
@@ -36,15 +36,21 @@ type NetworkForwardPort struct {
 func (p *NetworkForwardPort) Normalise() {
 	p.Description = strings.TrimSpace(p.Description)
 	p.Protocol = strings.TrimSpace(p.Protocol)
-	p.TargetAddress = strings.TrimSpace(p.TargetAddress)
 
-	ip := net.ParseIP(p.TargetAddress)
-	if ip != nil {
-		p.TargetAddress = ip.String() // Replace with canonical form if specified.
+	// Replace target addresses with canonical form if specified.
+	subjects := strings.Split(p.TargetAddress, ",")
+	for i, s := range subjects {
+		subjects[i] = strings.TrimSpace(s)
+
+		ip := net.ParseIP(subjects[i])
+		if ip != nil {
+			subjects[i] = ip.String()
+		}
 	}
+	p.TargetAddress = strings.Join(subjects, ",")
 
 	// Remove space from ListenPort list.
-	subjects := strings.Split(p.ListenPort, ",")
+	subjects = strings.Split(p.ListenPort, ",")
 	for i, s := range subjects {
 		subjects[i] = strings.TrimSpace(s)
 	}
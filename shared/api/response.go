@@ -19,6 +19,10 @@ type ResponseRaw struct {
 	Code  int    `json:"error_code" yaml:"error_code"`
 	Error string `json:"error" yaml:"error"`
 
+	// Machine-readable identifier of the error (e.g. "network_in_use"), if any
+	// API extension: error_id
+	ErrorID string `json:"error_id,omitempty" yaml:"error_id,omitempty"`
+
 	Metadata interface{} `json:"metadata" yaml:"metadata"`
 }
 
@@ -37,6 +41,10 @@ type Response struct {
 	Code  int    `json:"error_code" yaml:"error_code"`
 	Error string `json:"error" yaml:"error"`
 
+	// Machine-readable identifier of the error (e.g. "network_in_use"), if any
+	// API extension: error_id
+	ErrorID string `json:"error_id,omitempty" yaml:"error_id,omitempty"`
+
 	// Valid for Sync and Error responses
 	Metadata json.RawMessage `json:"metadata" yaml:"metadata"`
 }
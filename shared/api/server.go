@@ -108,6 +108,11 @@ type ServerEnvironment struct {
 
 	// List of supported storage drivers
 	StorageSupportedDrivers []ServerStorageDriverInfo `json:"storage_supported_drivers" yaml:"storage_supported_drivers"`
+
+	// List of supported network drivers and their capabilities
+	//
+	// API extension: network_driver_capabilities
+	NetworkSupportedDrivers []ServerNetworkDriverInfo `json:"network_supported_drivers" yaml:"network_supported_drivers"`
 }
 
 // ServerStorageDriverInfo represents the read-only info about a storage driver
@@ -135,6 +140,49 @@ type ServerStorageDriverInfo struct {
 	Remote bool
 }
 
+// ServerNetworkDriverInfo represents the read-only info about a network driver
+//
+// swagger:model
+//
+// API extension: network_driver_capabilities
+type ServerNetworkDriverInfo struct {
+	// Name of the driver
+	// Example: bridge
+	Name string `json:"name" yaml:"name"`
+
+	// Whether the driver can be used in projects with their own networks
+	// Example: false
+	Projects bool `json:"projects" yaml:"projects"`
+
+	// Whether the driver supports network address forwards
+	// Example: true
+	AddressForwards bool `json:"address_forwards" yaml:"address_forwards"`
+
+	// Whether the driver supports network peering
+	// Example: false
+	Peering bool `json:"peering" yaml:"peering"`
+
+	// Whether the driver supports network ACLs
+	// Example: true
+	ACLs bool `json:"acls" yaml:"acls"`
+
+	// Whether the driver supports advertising its subnets and forwards via BGP
+	// Example: true
+	BGP bool `json:"bgp" yaml:"bgp"`
+
+	// Whether the driver supports the fan overlay mode
+	// Example: true
+	Fan bool `json:"fan" yaml:"fan"`
+
+	// Whether the driver supports tunnels to remote hosts
+	// Example: true
+	Tunnels bool `json:"tunnels" yaml:"tunnels"`
+
+	// List of config keys supported by the driver
+	// Example: ["bridge.mtu", "ipv4.address", "tunnel.NAME.protocol"]
+	ConfigKeys []string `json:"config_keys" yaml:"config_keys"`
+}
+
 // ServerPut represents the modifiable fields of a LXD server configuration
 //
 // swagger:model
@@ -304,6 +304,72 @@ var APIExtensions = []string{
 	"qemu_metrics",
 	"gpu_mig_uuid",
 	"event_project",
+	"network_state_firewall_counters",
+	"network_dhcp_max_leases_per_mac",
+	"network_dhcp_limit",
+	"network_dns_port",
+	"network_dns_gateway_record",
+	"network_dns_dnssec",
+	"network_ipv6_address_auto_stable",
+	"network_ipv4_routing_global",
+	"network_ipv6_routing_global",
+	"network_security_apparmor",
+	"network_fan_underlay_interface",
+	"network_tunnel_mtu",
+	"network_tunnel_geneve",
+	"network_ipv6_ra_dns",
+	"network_security_firewall",
+	"network_bridge_port_isolation",
+	"network_zones_dns_queries",
+	"network_zones_dns_notify",
+	"metrics_dns",
+	"network_zones_peer_subnets",
+	"network_zones_peer_keys",
+	"dns_upstreams",
+	"nic_routed_host_table_rules",
+	"nic_routed_vm_network_config",
+	"nic_routed_rp_filter",
+	"file_response_sha256",
+	"error_id",
+	"metrics_network_dhcp",
+	"instance_nic_bridged_dns_name",
+	"network_bridge_ovn_ranges_dhcp_exclusion",
+	"network_bridge_stp",
+	"network_bridge_ageing_time",
+	"network_bridge_group_fwd_mask",
+	"network_bridge_ipv6_dad",
+	"network_bridge_address_anycast",
+	"network_bridge_acl_project",
+	"network_acl_rule_schedule",
+	"network_bridge_igmp_snooping",
+	"network_bridge_multicast_querier",
+	"network_bridge_dns_leasefile",
+	"network_state_forkdns",
+	"network_bridge_dns_forkdns_exclude",
+	"network_forkdns_metrics",
+	"network_bridge_hwaddr_seed",
+	"network_bridge_vlan_filtering",
+	"network_bridge_pvid",
+	"network_state_bridge_vlans",
+	"network_bridge_dns_mode_external",
+	"network_bridge_dns_logging",
+	"network_bridge_dns_ttl",
+	"network_effective_config",
+	"network_leases_eui64",
+	"network_leases_instance",
+	"network_leases_static_active",
+	"network_forward_target_list",
+	"firewall_self_test",
+	"network_driver_capabilities",
+	"network_bgp_communities",
+	"network_bgp_graceful_shutdown",
+	"network_forward_bgp_advertise",
+	"network_state_drain",
+	"network_state_health",
+	"network_external_subnets",
+	"network_acl_log_bridge",
+	"network_acl_evaluate",
+	"network_leases_reservations",
 }
 
 // APIExtensionsCount returns the number of available API extensions.